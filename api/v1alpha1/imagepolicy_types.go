@@ -0,0 +1,205 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Verifier is the type of the image signature verifier
+// +kubebuilder:validation:Enum=notary;cosign;notation
+type Verifier string
+
+const (
+	VerifierNotary   Verifier = "notary"
+	VerifierCosign   Verifier = "cosign"
+	VerifierNotation Verifier = "notation"
+)
+
+const (
+	// ConditionTypeReady reports whether the policy can be used to verify images
+	ConditionTypeReady = "Ready"
+
+	ConditionReasonValid   = "Valid"
+	ConditionReasonInvalid = "Invalid"
+	// ConditionReasonIgnored is used when another policy in the namespace takes precedence
+	ConditionReasonIgnored = "Ignored"
+)
+
+// ImagePolicySpec defines how images of pods are verified in namespaces with the user validation
+type ImagePolicySpec struct {
+	// Verifiers verify image signatures, notary is used if not set
+	// +optional
+	// +listType=set
+	Verifiers []Verifier `json:"verifiers,omitempty"`
+
+	// VerificationMode defines how results of multiple verifiers are combined
+	// +kubebuilder:validation:Enum=anyOf;allOf
+	// +kubebuilder:default=anyOf
+	// +optional
+	VerificationMode string `json:"verificationMode,omitempty"`
+
+	// Notary configures the notary verifier
+	// +optional
+	Notary *NotaryVerifier `json:"notary,omitempty"`
+
+	// Cosign configures the cosign verifier
+	// +optional
+	Cosign *CosignVerifier `json:"cosign,omitempty"`
+
+	// Notation configures the notation verifier
+	// +optional
+	Notation *NotationVerifier `json:"notation,omitempty"`
+
+	// AllowedRegistries contains patterns of images which are not verified, patterns starting with `!` exclude images
+	// +optional
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+
+	// StrictMode rejects pods with images which can't be verified, because the verifier isn't available
+	// +kubebuilder:default=true
+	// +optional
+	StrictMode *bool `json:"strictMode,omitempty"`
+
+	// EnforcementMode defines if pods with invalid images are rejected or admitted with warnings
+	// +kubebuilder:validation:Enum=enforce;warn
+	// +kubebuilder:default=enforce
+	// +optional
+	EnforcementMode string `json:"enforcementMode,omitempty"`
+
+	// Exceptions select pods which are not verified
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Exceptions []ImagePolicyException `json:"exceptions,omitempty"`
+}
+
+type NotaryVerifier struct {
+	// URL of the notary server
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// Timeout of requests to the notary server
+	// +kubebuilder:default="30s"
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+type CosignVerifier struct {
+	// PublicKeys contains PEM encoded public keys, the image is valid if it's signed with any of them
+	// +kubebuilder:validation:MinItems=1
+	PublicKeys []string `json:"publicKeys"`
+}
+
+type NotationVerifier struct {
+	// SecretName is the name of the secret with the trust policy and trust stores
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+
+	// SecretNamespace is the namespace of the secret, it can be set only in the ClusterImagePolicy,
+	// the namespace of the ImagePolicy or of the verified pod is used if not set
+	// +optional
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+}
+
+type ImagePolicyException struct {
+	// Name identifies the exception
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// PodSelector selects pods which are not verified
+	// +kubebuilder:validation:XValidation:rule="(has(self.matchLabels) && size(self.matchLabels) > 0) || (has(self.matchExpressions) && size(self.matchExpressions) > 0)",message="podSelector must not be empty, it would select all pods"
+	PodSelector metav1.LabelSelector `json:"podSelector"`
+}
+
+// ImagePolicyStatus reports whether the policy can be used
+type ImagePolicyStatus struct {
+	// ObservedGeneration is the generation of the policy which was checked
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions of the policy
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=imgpol
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+//+kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].reason"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ImagePolicy configures the user validation of images in its namespace,
+// if there is more than one policy in the namespace, the first one by name is used
+type ImagePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// the namespaced policy can't read secrets of other namespaces
+	// +kubebuilder:validation:XValidation:rule="!has(self.notation) || !has(self.notation.secretNamespace)",message="notation.secretNamespace can be set only in ClusterImagePolicy"
+	Spec   ImagePolicySpec   `json:"spec,omitempty"`
+	Status ImagePolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ImagePolicyList contains a list of ImagePolicy
+type ImagePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImagePolicy `json:"items"`
+}
+
+type ClusterImagePolicySpec struct {
+	// NamespaceSelector selects namespaces to which the policy applies, all namespaces with the user validation if not set
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	ImagePolicySpec `json:",inline"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,shortName=cimgpol
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+//+kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].reason"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ClusterImagePolicy configures the user validation of images in selected namespaces without an ImagePolicy,
+// if more than one policy selects the namespace, the first one by name is used
+type ClusterImagePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterImagePolicySpec `json:"spec,omitempty"`
+	Status ImagePolicyStatus      `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterImagePolicyList contains a list of ClusterImagePolicy
+type ClusterImagePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterImagePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImagePolicy{}, &ImagePolicyList{}, &ClusterImagePolicy{}, &ClusterImagePolicyList{})
+}
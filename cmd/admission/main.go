@@ -2,14 +2,23 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"slices"
 
+	"github.com/kyma-project/warden/api/v1alpha1"
+	"github.com/kyma-project/warden/internal/credentialprovider"
 	"github.com/kyma-project/warden/internal/env"
+	"github.com/kyma-project/warden/internal/helpers"
 	"github.com/kyma-project/warden/internal/logging"
+	"github.com/kyma-project/warden/internal/logging/tracing"
 	"github.com/kyma-project/warden/internal/validate"
 	"github.com/kyma-project/warden/internal/webhook"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
 	"go.uber.org/zap/zapcore"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -21,6 +30,8 @@ import (
 	"github.com/kyma-project/warden/internal/webhook/certs"
 	"go.uber.org/zap"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -39,6 +50,9 @@ var (
 func init() {
 	_ = admissionregistrationv1.AddToScheme(scheme)
 	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	_ = batchv1.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -72,14 +86,20 @@ func main() {
 	}
 	logger := l.WithContext()
 
-	if err := config.Watch(configPath, logger.Named("config watcher")); err != nil {
-		setupLog.Error(err, "while setup file watcher")
-		os.Exit(2)
-	}
-
 	logrZap := zapr.NewLogger(logger.Desugar())
 	ctrl.SetLogger(logrZap)
 
+	shutdownTracing, err := tracing.SetupTracerProvider(context.Background(), "warden-admission", tracing.Config{
+		Exporter:      appConfig.Tracing.Exporter,
+		Endpoint:      appConfig.Tracing.Endpoint,
+		Insecure:      appConfig.Tracing.Insecure,
+		SamplingRatio: appConfig.Tracing.SamplingRatio,
+	})
+	if err != nil {
+		setupLog.Error(err, "while configuring tracing")
+		os.Exit(1)
+	}
+
 	deployName := env.Get("ADMISSION_DEPLOYMENT_NAME")
 	addOwnerRef, err := env.GetBool("ADDMISSION_ADD_CERT_OWNER_REF")
 	if err != nil {
@@ -87,25 +107,67 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := certs.SetupCertSecret(
-		context.Background(),
-		appConfig.Admission.SecretName,
-		appConfig.Admission.SystemNamespace,
-		appConfig.Admission.ServiceName,
-		deployName,
-		addOwnerRef,
-		logger); err != nil {
-		logger.Error("failed to setup certificates and webhook secret", err.Error())
+	certConfig := certs.Config{
+		Mode:                appConfig.Admission.Certificate.Mode,
+		KeyAlgorithm:        appConfig.Admission.Certificate.KeyAlgorithm,
+		CAValidity:          appConfig.Admission.Certificate.CAValidity,
+		CARotationThreshold: appConfig.Admission.Certificate.CARotationThreshold,
+		Validity:            appConfig.Admission.Certificate.Validity,
+		RotationThreshold:   appConfig.Admission.Certificate.RotationThreshold,
+	}
+	if err := certConfig.Validate(); err != nil {
+		logger.Error("invalid certificate config ", err.Error())
 		os.Exit(1)
 	}
 
-	if err := certs.SaveToDirectory(
+	webhooksConfig := appConfig.Admission.Webhooks
+	registration := webhook.RegistrationConfig{
+		HandlerTimeout:    appConfig.Admission.Timeout,
+		FailurePolicy:     admissionregistrationv1.FailurePolicyType(webhooksConfig.FailurePolicy),
+		NamespaceSelector: webhooksConfig.NamespaceSelector.LabelSelector(),
+		ObjectSelector:    webhooksConfig.ObjectSelector.LabelSelector(),
+		Defaulting: webhook.WebhookSettings{
+			Timeout:     webhooksConfig.Defaulting.Timeout,
+			MatchPolicy: admissionregistrationv1.MatchPolicyType(webhooksConfig.Defaulting.MatchPolicy),
+		},
+		Validation: webhook.WebhookSettings{
+			Timeout:     webhooksConfig.Validation.Timeout,
+			MatchPolicy: admissionregistrationv1.MatchPolicyType(webhooksConfig.Validation.MatchPolicy),
+		},
+		WorkloadValidation: webhook.WebhookSettings{
+			Timeout:     webhooksConfig.WorkloadValidation.Timeout,
+			MatchPolicy: admissionregistrationv1.MatchPolicyType(webhooksConfig.WorkloadValidation.MatchPolicy),
+		},
+	}
+	if err := registration.Validate(); err != nil {
+		logger.Error("invalid webhooks config ", err.Error())
+		os.Exit(1)
+	}
+
+	if certConfig.Mode == certs.ModeGenerated {
+		if err := certs.SetupCertSecret(
+			context.Background(),
+			appConfig.Admission.SecretName,
+			appConfig.Admission.SystemNamespace,
+			appConfig.Admission.ServiceName,
+			deployName,
+			addOwnerRef,
+			certConfig,
+			webhook.UpdateCABundle,
+			logger); err != nil {
+			logger.Error("failed to setup certificates and webhook secret", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	certificateStore := certs.NewCertificateStore()
+	if err := certs.LoadCertificate(
 		context.Background(),
 		appConfig.Admission.SecretName,
 		appConfig.Admission.SystemNamespace,
-		certs.DefaultCertDir,
+		certificateStore,
 		logger); err != nil {
-		logger.Error("failed to save certificate from secret", err.Error())
+		logger.Error("failed to load certificate from secret", err.Error())
 		os.Exit(1)
 	}
 
@@ -117,9 +179,8 @@ func main() {
 		Logger:                 logrZap,
 		HealthProbeBindAddress: ":8090",
 		WebhookServer: ctrlwebhook.NewServer(ctrlwebhook.Options{
-			CertName: certs.CertFile,
-			KeyName:  certs.KeyFile,
-			Port:     appConfig.Admission.Port,
+			Port:    appConfig.Admission.Port,
+			TLSOpts: []func(*tls.Config){certificateStore.ConfigureTLS},
 		}),
 		Cache: cache.Options{
 			ByObject: map[client.Object]cache.ByObject{
@@ -146,37 +207,136 @@ func main() {
 		appConfig.Admission.SecretName,
 		deployName,
 		addOwnerRef,
+		certConfig,
+		registration,
+		certificateStore,
 		logger); err != nil {
 		logger.Error("failed to setup webhook resource controller ", err.Error())
 		os.Exit(5)
 	}
 
-	validatorSvc := validate.NewValidatorSvcFactory().NewValidatorSvc(
-		appConfig.Notary.URL, appConfig.Notary.AllowedRegistries, appConfig.Notary.Timeout)
+	var validationCache *validate.ValidationCache
+	if appConfig.Cache.Enabled {
+		validationCache = validate.NewValidationCache(validate.CacheConfig{
+			ValidTTL:   appConfig.Cache.ValidTTL,
+			InvalidTTL: appConfig.Cache.InvalidTTL,
+			MaxEntries: appConfig.Cache.MaxEntries,
+		})
+	}
+
+	// breakers are shared by system and user validators, so all validations of the notary server open the same breaker
+	circuitBreakers := validate.NewCircuitBreakers(validate.CircuitBreakerConfig{
+		FailureThreshold: appConfig.Notary.CircuitBreaker.FailureThreshold,
+		OpenTimeout:      appConfig.Notary.CircuitBreaker.OpenTimeout,
+		SystemBackend:    appConfig.Notary.URL,
+	})
+
+	notationConfig := validate.NotationConfig{}
+	if slices.Contains(helpers.ParseVerifiers(appConfig.Verification.Verifier), pkg.VerifierNotation) {
+		notationConfig, err = validate.LoadNotationConfig(
+			appConfig.Verification.Notation.TrustPolicyPath,
+			appConfig.Verification.Notation.TrustStorePath)
+		if err != nil {
+			logger.Error("unable to load notation configuration ", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var credentialProvider validate.CredentialProvider
+	if appConfig.CredentialProviders.ConfigPath != "" {
+		credentialProviderConfig, err := credentialprovider.LoadConfig(appConfig.CredentialProviders.ConfigPath)
+		if err != nil {
+			logger.Error("unable to load credential provider configuration ", err.Error())
+			os.Exit(1)
+		}
+		credentialProvider, err = credentialprovider.New(credentialProviderConfig, appConfig.CredentialProviders.BinDir)
+		if err != nil {
+			logger.Error("unable to setup credential providers ", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	validatorSvc := validate.NewReloadableValidator(
+		validate.NewValidatorSvcFactory(validationCache, credentialProvider, circuitBreakers),
+		validate.ValidatorSvcConfig{
+			Verifier:          appConfig.Verification.Verifier,
+			VerificationMode:  appConfig.Verification.Mode,
+			NotaryURL:         appConfig.Notary.URL,
+			NotaryTimeout:     appConfig.Notary.Timeout,
+			AllowedRegistries: appConfig.Notary.AllowedRegistries,
+			CosignPublicKeys:  appConfig.Verification.Cosign.PublicKeys,
+			NotationConfig:    notationConfig,
+		})
 
 	logger.Info("setting up webhook server")
 	// webhook server setup
 	whs := mgr.GetWebhookServer()
 	decoder := ctrladmission.NewDecoder(mgr.GetScheme())
-	whs.Register(admission.ValidationPath, &ctrlwebhook.Admission{
-		Handler: admission.NewValidationWebhook(logger.With("webhook", "validation"), &decoder),
-	})
+	recorder := mgr.GetEventRecorderFor("warden-admission")
+	whs.Register(admission.ValidationPath, withTracing(&ctrlwebhook.Admission{
+		Handler: admission.NewValidationWebhook(logger.With("webhook", "validation"), &decoder, recorder),
+	}))
 
 	predefinedUserAllowedRegistries := validate.ParseAllowedRegistries(appConfig.Notary.PredefinedUserAllowedRegistries)
-	whs.Register(admission.DefaultingPath, &ctrlwebhook.Admission{
-		Handler: admission.NewDefaultingWebhook(mgr.GetClient(),
-			mgr.GetAPIReader(),
-			validatorSvc, validate.NewValidatorSvcFactory(predefinedUserAllowedRegistries...),
-			appConfig.Admission.Timeout, appConfig.Admission.StrictMode,
-			&decoder, logger.With("webhook", "defaulting")),
-	})
+	userValidatorSvcFactory := validate.NewValidatorSvcFactory(validationCache, credentialProvider, circuitBreakers, predefinedUserAllowedRegistries...)
+	defaultingWebhook := admission.NewDefaultingWebhook(mgr.GetClient(),
+		mgr.GetAPIReader(),
+		validatorSvc, userValidatorSvcFactory,
+		appConfig.Admission.Timeout, appConfig.Admission.StrictMode,
+		&decoder, recorder, logger.With("webhook", "defaulting"))
+	whs.Register(admission.DefaultingPath, withTracing(&ctrlwebhook.Admission{
+		Handler: defaultingWebhook,
+	}))
+	workloadWebhook := admission.NewWorkloadWebhook(mgr.GetClient(),
+		mgr.GetAPIReader(),
+		validatorSvc, userValidatorSvcFactory,
+		appConfig.Admission.Timeout, appConfig.Admission.StrictMode,
+		&decoder, logger.With("webhook", "workloads"))
+	whs.Register(admission.WorkloadValidationPath, withTracing(&ctrlwebhook.Admission{
+		Handler: workloadWebhook,
+	}))
+
+	reloadConfig := func(next *config.Config) error {
+		level, err := zapcore.ParseLevel(next.Logging.Level)
+		if err != nil {
+			return errors.Wrap(err, "invalid logger level")
+		}
+		// webhooks aren't registered again, so the admission timeout must fit in their registered timeouts
+		if err := registration.ValidateHandlerTimeout(next.Admission.Timeout); err != nil {
+			return err
+		}
+
+		atomic.SetLevel(level)
+		validatorConfig := validatorSvc.Config()
+		validatorConfig.NotaryURL = next.Notary.URL
+		validatorConfig.NotaryTimeout = next.Notary.Timeout
+		validatorConfig.AllowedRegistries = next.Notary.AllowedRegistries
+		validatorSvc.Reload(validatorConfig)
+		circuitBreakers.SetSystemBackend(next.Notary.URL)
+		settings := admission.Settings{Timeout: next.Admission.Timeout, StrictMode: next.Admission.StrictMode}
+		defaultingWebhook.UpdateSettings(settings)
+		workloadWebhook.UpdateSettings(settings)
+		return nil
+	}
+	if err := config.Watch(configPath, appConfig, reloadConfig, logger.Named("config watcher")); err != nil {
+		logger.Error("while setup file watcher ", err.Error())
+		os.Exit(2)
+	}
 
 	logger.Info("starting the controller-manager")
 
 	// start the server manager
 	err = mgr.Start(ctrl.SetupSignalHandler())
+	if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+		logger.Error("failed to flush traces ", shutdownErr.Error())
+	}
 	if err != nil {
 		logger.Error(err, "failed to start controller-manager")
 		os.Exit(1)
 	}
 }
+
+// withTracing continues the trace of the API server request in the webhook handler
+func withTracing(webhook *ctrlwebhook.Admission) http.Handler {
+	return tracing.NewTracingMiddleware(webhook.ServeHTTP)
+}
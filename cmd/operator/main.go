@@ -17,20 +17,30 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"slices"
 
 	"github.com/go-logr/zapr"
+	"github.com/kyma-project/warden/internal/helpers"
 	"github.com/kyma-project/warden/internal/logging"
+	"github.com/kyma-project/warden/internal/logging/tracing"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/kyma-project/warden/api/v1alpha1"
 	"github.com/kyma-project/warden/internal/config"
 	"github.com/kyma-project/warden/internal/controllers"
+	"github.com/kyma-project/warden/internal/controllers/imagepolicy"
 	"github.com/kyma-project/warden/internal/controllers/namespace"
+	"github.com/kyma-project/warden/internal/credentialprovider"
+	"github.com/kyma-project/warden/internal/metrics"
 	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -57,6 +67,7 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 }
 
 func main() {
@@ -88,14 +99,21 @@ func main() {
 		os.Exit(10)
 	}
 	logger := l.WithContext()
-	if err := config.Watch(configPath, logger.Named("config watcher")); err != nil {
-		setupLog.Error(err, "while setup file watcher")
-		os.Exit(2)
-	}
 
 	logrZap := zapr.NewLogger(logger.Desugar())
 	ctrl.SetLogger(logrZap)
 
+	shutdownTracing, err := tracing.SetupTracerProvider(context.Background(), "warden-operator", tracing.Config{
+		Exporter:      appConfig.Tracing.Exporter,
+		Endpoint:      appConfig.Tracing.Endpoint,
+		Insecure:      appConfig.Tracing.Insecure,
+		SamplingRatio: appConfig.Tracing.SamplingRatio,
+	})
+	if err != nil {
+		setupLog.Error(err, "while configuring tracing")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: ctrlmetrics.Options{
@@ -120,22 +138,94 @@ func main() {
 		os.Exit(1)
 	}
 
-	repoFactory := validate.NotaryRepoFactory{Timeout: appConfig.Notary.Timeout}
-	allowedRegistries := validate.ParseAllowedRegistries(appConfig.Notary.AllowedRegistries)
+	if err := metrics.RegisterPodsCollector(mgr.GetClient()); err != nil {
+		logger.Error(err, "unable to register pods metrics")
+		os.Exit(1)
+	}
+
 	predefinedUserAllowedRegistries := validate.ParseAllowedRegistries(appConfig.Notary.PredefinedUserAllowedRegistries)
 
-	notaryConfig := &validate.ServiceConfig{NotaryConfig: validate.NotaryConfig{Url: appConfig.Notary.URL}, AllowedRegistries: allowedRegistries}
+	var validationCache *validate.ValidationCache
+	if appConfig.Cache.Enabled {
+		validationCache = validate.NewValidationCache(validate.CacheConfig{
+			ValidTTL:   appConfig.Cache.ValidTTL,
+			InvalidTTL: appConfig.Cache.InvalidTTL,
+			MaxEntries: appConfig.Cache.MaxEntries,
+		})
+	}
+
+	// breakers are shared by system and user validators, so all validations of the notary server open the same breaker
+	circuitBreakers := validate.NewCircuitBreakers(validate.CircuitBreakerConfig{
+		FailureThreshold: appConfig.Notary.CircuitBreaker.FailureThreshold,
+		OpenTimeout:      appConfig.Notary.CircuitBreaker.OpenTimeout,
+		SystemBackend:    appConfig.Notary.URL,
+	})
+
+	notationConfig := validate.NotationConfig{}
+	if slices.Contains(helpers.ParseVerifiers(appConfig.Verification.Verifier), pkg.VerifierNotation) {
+		notationConfig, err = validate.LoadNotationConfig(
+			appConfig.Verification.Notation.TrustPolicyPath,
+			appConfig.Verification.Notation.TrustStorePath)
+		if err != nil {
+			logger.Error(err, "unable to load notation configuration")
+			os.Exit(1)
+		}
+	}
+
+	var credentialProvider validate.CredentialProvider
+	if appConfig.CredentialProviders.ConfigPath != "" {
+		credentialProviderConfig, err := credentialprovider.LoadConfig(appConfig.CredentialProviders.ConfigPath)
+		if err != nil {
+			logger.Error(err, "unable to load credential provider configuration")
+			os.Exit(1)
+		}
+		credentialProvider, err = credentialprovider.New(credentialProviderConfig, appConfig.CredentialProviders.BinDir)
+		if err != nil {
+			logger.Error(err, "unable to setup credential providers")
+			os.Exit(1)
+		}
+	}
+
+	podValidator := validate.NewReloadableValidator(
+		validate.NewValidatorSvcFactory(validationCache, credentialProvider, circuitBreakers),
+		validate.ValidatorSvcConfig{
+			Verifier:          appConfig.Verification.Verifier,
+			VerificationMode:  appConfig.Verification.Mode,
+			NotaryURL:         appConfig.Notary.URL,
+			NotaryTimeout:     appConfig.Notary.Timeout,
+			AllowedRegistries: appConfig.Notary.AllowedRegistries,
+			CosignPublicKeys:  appConfig.Verification.Cosign.PublicKeys,
+			NotationConfig:    notationConfig,
+		})
+
+	reloadConfig := func(next *config.Config) error {
+		level, err := zapcore.ParseLevel(next.Logging.Level)
+		if err != nil {
+			return errors.Wrap(err, "invalid logger level")
+		}
 
-	imageValidator := validate.NewImageValidator(notaryConfig, repoFactory)
-	podValidator := validate.NewPodValidator(imageValidator)
+		atomic.SetLevel(level)
+		validatorConfig := podValidator.Config()
+		validatorConfig.NotaryURL = next.Notary.URL
+		validatorConfig.NotaryTimeout = next.Notary.Timeout
+		validatorConfig.AllowedRegistries = next.Notary.AllowedRegistries
+		podValidator.Reload(validatorConfig)
+		circuitBreakers.SetSystemBackend(next.Notary.URL)
+		return nil
+	}
+	if err := config.Watch(configPath, appConfig, reloadConfig, logger.Named("config watcher")); err != nil {
+		logger.Error(err, "while setup file watcher")
+		os.Exit(2)
+	}
 
 	if err = (controllers.NewPodReconciler(
 		mgr.GetClient(),
 		mgr.GetAPIReader(),
 		mgr.GetScheme(),
 		podValidator,
-		validate.NewValidatorSvcFactory(predefinedUserAllowedRegistries...),
+		validate.NewValidatorSvcFactory(validationCache, credentialProvider, circuitBreakers, predefinedUserAllowedRegistries...),
 		controllers.PodReconcilerConfig{RequeueAfter: appConfig.Operator.PodReconcilerRequeueAfter},
+		mgr.GetEventRecorderFor("warden-operator"),
 		logger.Named("pod-controller"),
 	)).SetupWithManager(mgr); err != nil {
 		logger.Error(err, "unable to create controller", "controller", "Pod")
@@ -152,6 +242,22 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = (&imagepolicy.ImagePolicyReconciler{
+		Client: mgr.GetClient(),
+		Log:    logger.Named("imagepolicy-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		logger.Error(err, "unable to create controller", "controller", "ImagePolicy")
+		os.Exit(1)
+	}
+
+	if err = (&imagepolicy.ClusterImagePolicyReconciler{
+		Client: mgr.GetClient(),
+		Log:    logger.Named("clusterimagepolicy-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		logger.Error(err, "unable to create controller", "controller", "ClusterImagePolicy")
+		os.Exit(1)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		logger.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -162,7 +268,11 @@ func main() {
 	}
 
 	logger.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+		logger.Error(shutdownErr, "failed to flush traces")
+	}
+	if err != nil {
 		logger.Error(err, "problem running manager")
 		os.Exit(1)
 	}
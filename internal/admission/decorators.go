@@ -38,6 +38,15 @@ func HandlerWithTimeMeasure(handler Handler) Handler {
 
 type TimeoutHandler func(ctx context.Context, err error, req admission.Request) admission.Response
 
+// TimeoutHandlerBudget bounds requests of timeout handlers, they're sent after the admission timeout expired,
+// so they must not wait longer than the API server waits for the webhook response
+const TimeoutHandlerBudget = time.Second
+
+// timeoutHandlerContext returns the context for requests of the timeout handler, it keeps values of the expired context
+func timeoutHandlerContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), TimeoutHandlerBudget)
+}
+
 func HandleWithTimeout(timeout time.Duration, handler Handler, timeoutHandler TimeoutHandler) Handler {
 	return func(ctx context.Context, req admission.Request) admission.Response {
 		ctxTimeout, cancel := context.WithTimeout(ctx, timeout)
@@ -9,20 +9,28 @@ import (
 	"time"
 
 	"github.com/kyma-project/warden/internal/annotations"
+	"github.com/kyma-project/warden/internal/events"
 	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/logging/tracing"
 	"github.com/kyma-project/warden/internal/validate"
 	"github.com/kyma-project/warden/pkg"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 const (
 	DefaultingPath = "/defaulting/pods"
+
+	// EphemeralContainersSubResource is used by `kubectl debug` to add containers to the running pod
+	EphemeralContainersSubResource = "ephemeralcontainers"
 )
 
 const PodType = "Pod"
@@ -30,37 +38,48 @@ const PodType = "Pod"
 type DefaultingWebHook struct {
 	systemValidator          validate.PodValidator
 	userValidationSvcFactory validate.ValidatorSvcFactory
-	timeout                  time.Duration
+	settings                 *reloadableSettings
 	client                   k8sclient.Client
 	reader                   k8sclient.Reader
 	decoder                  *admission.Decoder
+	recorder                 record.EventRecorder
 	baseLogger               *zap.SugaredLogger
-	strictMode               bool
 }
 
 func NewDefaultingWebhook(client k8sclient.Client, reader k8sclient.Reader,
 	systemValidator validate.PodValidator, userValidationSvcFactory validate.ValidatorSvcFactory,
 	timeout time.Duration, strictMode bool,
-	decoder *admission.Decoder, logger *zap.SugaredLogger) *DefaultingWebHook {
+	decoder *admission.Decoder, recorder record.EventRecorder, logger *zap.SugaredLogger) *DefaultingWebHook {
 	return &DefaultingWebHook{
 		client:                   client,
 		reader:                   reader,
 		systemValidator:          systemValidator,
 		userValidationSvcFactory: userValidationSvcFactory,
 		baseLogger:               logger,
-		timeout:                  timeout,
-		strictMode:               strictMode,
+		settings:                 newReloadableSettings(Settings{Timeout: timeout, StrictMode: strictMode}),
 		decoder:                  decoder,
+		recorder:                 recorder,
 	}
 }
 
+// UpdateSettings applies settings to requests received after the update
+func (w *DefaultingWebHook) UpdateSettings(settings Settings) {
+	w.settings.update(settings)
+}
+
 func (w *DefaultingWebHook) Handle(ctx context.Context, req admission.Request) admission.Response {
 	return HandleWithLogger(w.baseLogger,
 		HandlerWithTimeMeasure(
-			HandleWithTimeout(w.timeout, w.handle, w.handleTimeout)))(ctx, req)
+			HandleWithTimeout(w.settings.load().Timeout, w.handle, w.handleTimeout)))(ctx, req)
 }
 
-func (w *DefaultingWebHook) handle(ctx context.Context, req admission.Request) admission.Response {
+func (w *DefaultingWebHook) handle(ctx context.Context, req admission.Request) (resp admission.Response) {
+	ctx, span := tracing.StartSpan(ctx, "DefaultingWebHook.handle",
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("warden.admission.operation", string(req.Operation)),
+		attribute.String("warden.admission.subresource", req.SubResource))
+	defer func() { endAdmissionSpan(span, resp) }()
+
 	if req.Kind.Kind != PodType {
 		return admission.Errored(http.StatusBadRequest,
 			errors.Errorf("Invalid request kind:%s, expected:%s", req.Kind.Kind, PodType))
@@ -79,18 +98,22 @@ func (w *DefaultingWebHook) handle(ctx context.Context, req admission.Request) a
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
+	if req.SubResource == EphemeralContainersSubResource {
+		return w.handleEphemeralContainers(ctx, req, pod, ns)
+	}
+
 	if !isValidationNeeded(ctx, pod, ns, req.Operation) {
 		result := cleanAnnotationIfNeeded(ctx, pod, ns, req)
 		return result
 	}
 
-	validator := w.systemValidator
-	if validate.IsUserValidationForNS(ns) {
-		var err error
-		validator, err = validate.NewUserValidationSvc(ns, w.userValidationSvcFactory)
-		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
-		}
+	policy, err := validate.GetUserImagePolicy(ctx, w.reader, ns)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	validator, err := w.getValidator(ctx, ns, policy)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
 	imagePullCredentials, err := helpers.GetRemotePullCredentials(ctx, w.reader, pod)
@@ -105,10 +128,129 @@ func (w *DefaultingWebHook) handle(ctx context.Context, req admission.Request) a
 	if result.Status == validate.NoAction {
 		return admission.Allowed("validation is not enabled for pod")
 	}
-	res := w.createResponse(ctx, req, result, pod, ns, logger)
+	w.recordValidationResult(req, pod, result)
+	res := w.createResponse(ctx, req, result, pod, ns, policy, logger)
 	return res
 }
 
+// handleEphemeralContainers allows or denies the request directly, because the pod labels and annotations
+// can't be changed through the ephemeralcontainers subresource, so the validation webhook can't reject it later
+func (w *DefaultingWebHook) handleEphemeralContainers(ctx context.Context, req admission.Request, pod *corev1.Pod, ns *corev1.Namespace) admission.Response {
+	if enabled := validate.IsValidationEnabledForNS(ns); !enabled {
+		return admission.Allowed("validation is not needed for pod")
+	}
+
+	policy, err := validate.GetUserImagePolicy(ctx, w.reader, ns)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	validator, err := w.getValidator(ctx, ns, policy)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	// containers of the running pod have been already validated
+	ephemeralPod := pod.DeepCopy()
+	ephemeralPod.Spec.InitContainers = nil
+	ephemeralPod.Spec.Containers = nil
+
+	imagePullCredentials, err := helpers.GetRemotePullCredentials(ctx, w.reader, ephemeralPod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	result, err := validator.ValidatePod(ctx, ephemeralPod, ns, imagePullCredentials)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	helpers.LoggerFromCtx(ctx).Infow("pod ephemeral containers were validated", "result", result)
+	w.recordValidationResult(req, pod, result)
+	return w.createEphemeralContainersResponse(result, ns, policy)
+}
+
+func (w *DefaultingWebHook) createEphemeralContainersResponse(result validate.ValidationResult, ns *corev1.Namespace, policy *validate.UserImagePolicy) admission.Response {
+	strictMode, err := w.isStrictMode(ns, policy)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	warnMode := isWarnModeForNS(ns, policy)
+
+	switch result.Status {
+	case validate.Invalid:
+		return denyOrWarn(warnMode, fmt.Sprintf("Pod ephemeral container images %s validation failed", strings.Join(result.InvalidImages, ", ")))
+	case validate.ServiceUnavailable:
+		if strictMode {
+			return denyOrWarn(warnMode, "Pod ephemeral container images couldn't be validated")
+		}
+		return admission.Allowed("pod ephemeral container images couldn't be validated")
+	default:
+		return admission.Allowed("pod ephemeral container images are valid")
+	}
+}
+
+// endAdmissionSpan ends the span of the admission request, only errored responses mark the span as failed,
+// because rejecting the pod is the expected outcome of the validation
+func endAdmissionSpan(span trace.Span, resp admission.Response) {
+	span.SetAttributes(attribute.Bool("warden.admission.allowed", resp.Allowed))
+	var err error
+	if resp.Result != nil && resp.Result.Code >= http.StatusInternalServerError {
+		err = errors.New(resp.Result.Message)
+	}
+	tracing.EndSpan(span, err)
+}
+
+// recordValidationResult records events of failed images, events aren't recorded for dry-run requests,
+// because the pod is never created
+func (w *DefaultingWebHook) recordValidationResult(req admission.Request, pod *corev1.Pod, result validate.ValidationResult) {
+	if isDryRun(req) {
+		return
+	}
+	events.RecordValidationResult(w.recorder, pod, result)
+}
+
+func (w *DefaultingWebHook) getValidator(ctx context.Context, ns *corev1.Namespace, policy *validate.UserImagePolicy) (validate.PodValidator, error) {
+	return getValidatorForNS(ctx, w.reader, ns, policy, w.systemValidator, w.userValidationSvcFactory)
+}
+
+func (w *DefaultingWebHook) isStrictMode(ns *corev1.Namespace, policy *validate.UserImagePolicy) (bool, error) {
+	return isStrictModeForNS(ns, policy, w.settings.load().StrictMode)
+}
+
+// getValidatorForNS returns the system validator or the user validator configured by the policy or the namespace annotations,
+// the policy is resolved once per request by the caller
+func getValidatorForNS(ctx context.Context, reader k8sclient.Reader, ns *corev1.Namespace, policy *validate.UserImagePolicy,
+	systemValidator validate.PodValidator, userValidationSvcFactory validate.ValidatorSvcFactory) (validate.PodValidator, error) {
+	if validate.IsUserValidationForNS(ns) {
+		return validate.NewUserValidationSvcForPolicy(ctx, reader, ns, policy, userValidationSvcFactory)
+	}
+	return systemValidator, nil
+}
+
+func isStrictModeForNS(ns *corev1.Namespace, policy *validate.UserImagePolicy, systemStrictMode bool) (bool, error) {
+	if validate.IsUserValidationForNS(ns) {
+		return validate.GetUserValidationStrictMode(ns, policy)
+	}
+	return systemStrictMode, nil
+}
+
+// isWarnModeForNS checks if pods with invalid images are admitted with warnings instead of being rejected
+func isWarnModeForNS(ns *corev1.Namespace, policy *validate.UserImagePolicy) bool {
+	return validate.GetEnforcementMode(ns, policy) == pkg.EnforcementModeWarn
+}
+
+// denyOrWarn denies the request, or admits it with the warning in the warn enforcement mode
+func denyOrWarn(warnMode bool, msg string) admission.Response {
+	if warnMode {
+		return admission.Allowed("").WithWarnings(msg)
+	}
+	return admission.Denied(msg)
+}
+
+func isDryRun(req admission.Request) bool {
+	return req.DryRun != nil && *req.DryRun
+}
+
 func cleanAnnotationIfNeeded(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, req admission.Request) admission.Response {
 	if enabled := validate.IsValidationEnabledForNS(ns); !enabled {
 		return admission.Allowed("validation is not needed for pod")
@@ -123,13 +265,17 @@ func cleanAnnotationIfNeeded(ctx context.Context, pod *corev1.Pod, ns *corev1.Na
 	return admission.Allowed("validation is not needed for pod")
 }
 
-func (w DefaultingWebHook) handleTimeout(ctx context.Context, timeoutErr error, req admission.Request) admission.Response {
+func (w *DefaultingWebHook) handleTimeout(ctx context.Context, timeoutErr error, req admission.Request) admission.Response {
+	// the request context is already expired, but the namespace configuration still has to be read
+	ctx, cancel := timeoutHandlerContext(ctx)
+	defer cancel()
+
 	pod := &corev1.Pod{}
 	if err := (*w.decoder).Decode(req, pod); err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	msg := fmt.Sprintf("request exceeded desired timeout: %s, reason: %s", w.timeout.String(), timeoutErr.Error())
+	msg := fmt.Sprintf("request exceeded desired timeout: %s, reason: %s", w.settings.load().Timeout.String(), timeoutErr.Error())
 	logger := helpers.LoggerFromCtx(ctx)
 	logger.Info(msg)
 
@@ -138,25 +284,36 @@ func (w DefaultingWebHook) handleTimeout(ctx context.Context, timeoutErr error,
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	res := w.createResponse(ctx, req, validate.ValidationResult{Status: validate.ServiceUnavailable}, pod, ns, logger)
+	policy, err := validate.GetUserImagePolicy(ctx, w.reader, ns)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	result := validate.ValidationResult{Status: validate.ServiceUnavailable}
+	w.recordValidationResult(req, pod, result)
+
+	if req.SubResource == EphemeralContainersSubResource {
+		res := w.createEphemeralContainersResponse(result, ns, policy)
+		res.Result.Message = msg
+		return res
+	}
+
+	res := w.createResponse(ctx, req, result, pod, ns, policy, logger)
 	res.Result = &metav1.Status{Message: msg}
 	return res
 }
 
 func (w *DefaultingWebHook) createResponse(ctx context.Context,
 	req admission.Request, result validate.ValidationResult,
-	pod *corev1.Pod, ns *corev1.Namespace, logger *zap.SugaredLogger) admission.Response {
+	pod *corev1.Pod, ns *corev1.Namespace, policy *validate.UserImagePolicy, logger *zap.SugaredLogger) admission.Response {
 
-	strictMode := w.strictMode
-	if validate.IsUserValidationForNS(ns) {
-		var err error
-		strictMode, err = helpers.GetUserValidationStrictMode(ns)
-		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
-		}
+	strictMode, err := w.isStrictMode(ns, policy)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
 	}
+	warnMode := isWarnModeForNS(ns, policy)
 
-	markedPod := markPod(ctx, result, pod, strictMode)
+	markedPod := markPod(ctx, result, pod, strictMode, warnMode)
 	fBytes, err := json.Marshal(markedPod)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
@@ -205,8 +362,12 @@ func getPodValidationLabelValue(pod *corev1.Pod) string {
 	return validationLabelValue
 }
 
-func markPod(ctx context.Context, result validate.ValidationResult, pod *corev1.Pod, strictMode bool) *corev1.Pod {
+func markPod(ctx context.Context, result validate.ValidationResult, pod *corev1.Pod, strictMode, warnMode bool) *corev1.Pod {
 	label, annotation := podMarkersForValidationResult(result.Status, strictMode)
+	if warnMode && annotation == annotations.ValidationReject {
+		// the validation webhook admits the pod and returns the warning instead of rejecting it
+		annotation = annotations.ValidationWarn
+	}
 	helpers.LoggerFromCtx(ctx).Infof("pod was labeled: `%s` and annotated: `%s`", label, annotation)
 	if label == "" && annotation == "" {
 		return pod
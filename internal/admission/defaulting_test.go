@@ -13,6 +13,7 @@ import (
 	"github.com/kyma-project/warden/internal/helpers"
 	"k8s.io/utils/ptr"
 
+	"github.com/kyma-project/warden/api/v1alpha1"
 	"github.com/kyma-project/warden/internal/annotations"
 	"github.com/kyma-project/warden/internal/test_helpers"
 	"github.com/kyma-project/warden/internal/validate"
@@ -27,7 +28,10 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
@@ -68,7 +72,7 @@ func TestTimeout(t *testing.T) {
 			Return(validate.ValidationResult{Status: validate.Valid}, nil).Once()
 		defer validationSvc.AssertExpectations(t)
 		webhook := NewDefaultingWebhook(client, client,
-			validationSvc, nil, timeout, StrictModeOff, &decoder, logger.Sugar())
+			validationSvc, nil, timeout, StrictModeOff, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
@@ -86,7 +90,7 @@ func TestTimeout(t *testing.T) {
 			Return(validate.ValidationResult{Status: validate.Valid}, nil).Once()
 		defer validationSvc.AssertExpectations(t)
 		webhook := NewDefaultingWebhook(client, client,
-			validationSvc, nil, timeout, StrictModeOff, &decoder, logger.Sugar())
+			validationSvc, nil, timeout, StrictModeOff, &decoder, &record.FakeRecorder{}, logger.Sugar())
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
 
@@ -105,7 +109,7 @@ func TestTimeout(t *testing.T) {
 			Return(validate.ValidationResult{Status: validate.Valid}, nil).Once()
 		defer validationSvc.AssertExpectations(t)
 		webhook := NewDefaultingWebhook(client, client,
-			validationSvc, nil, timeout, StrictModeOn, &decoder, logger.Sugar())
+			validationSvc, nil, timeout, StrictModeOn, &decoder, &record.FakeRecorder{}, logger.Sugar())
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
 
@@ -126,10 +130,10 @@ func TestTimeout(t *testing.T) {
 		srv := httptest.NewServer(h)
 		defer srv.Close()
 
-		validateImage := validate.NewImageValidator(&validate.ServiceConfig{NotaryConfig: validate.NotaryConfig{Url: srv.URL}}, validate.NotaryRepoFactory{})
+		validateImage := validate.NewImageValidator(&validate.ServiceConfig{NotaryConfig: validate.NotaryConfig{Url: srv.URL}}, validate.NewNotaryRepoFactory(0))
 		validationSvc := validate.NewPodValidator(validateImage)
 		webhook := NewDefaultingWebhook(client, client,
-			validationSvc, nil, timeout, StrictModeOff, &decoder, logger.Sugar())
+			validationSvc, nil, timeout, StrictModeOff, &decoder, &record.FakeRecorder{}, logger.Sugar())
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
 
@@ -165,7 +169,7 @@ func TestFlow_OutputStatuses_ForPodValidationResult(t *testing.T) {
 		req := newRequestFix(t, pod, admissionv1.Create)
 		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
 		webhook := NewDefaultingWebhook(client, client,
-			mockPodValidator, nil, timeout, false, &decoder, logger.Sugar())
+			mockPodValidator, nil, timeout, false, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
@@ -190,7 +194,7 @@ func TestFlow_OutputStatuses_ForPodValidationResult(t *testing.T) {
 		req := newRequestFix(t, pod, admissionv1.Create)
 		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
 		webhook := NewDefaultingWebhook(client, client,
-			mockPodValidator, nil, timeout, false, &decoder, logger.Sugar())
+			mockPodValidator, nil, timeout, false, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
@@ -211,7 +215,7 @@ func TestFlow_OutputStatuses_ForPodValidationResult(t *testing.T) {
 		req := newRequestFix(t, pod, admissionv1.Update)
 		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
 		webhook := NewDefaultingWebhook(client, client,
-			nil, nil, timeout, false, &decoder, logger.Sugar())
+			nil, nil, timeout, false, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
@@ -234,8 +238,9 @@ func TestFlow_OutputStatuses_ForPodValidationResult(t *testing.T) {
 		pod := newPodFix(nsName, nil)
 		req := newRequestFix(t, pod, admissionv1.Create)
 		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
+		recorder := record.NewFakeRecorder(10)
 		webhook := NewDefaultingWebhook(client, client,
-			mockPodValidator, nil, timeout, false, &decoder, logger.Sugar())
+			mockPodValidator, nil, timeout, false, &decoder, recorder, logger.Sugar())
 
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
@@ -245,6 +250,59 @@ func TestFlow_OutputStatuses_ForPodValidationResult(t *testing.T) {
 		require.NotNil(t, res)
 		require.True(t, res.AdmissionResponse.Allowed)
 		require.ElementsMatch(t, withAddRejectAndImagesAnnotation(patchWithAddLabel(pkg.ValidationStatusFailed)), res.Patches)
+		require.Len(t, recorder.Events, 1)
+		require.Equal(t, "Warning ImageSignatureInvalid Image test:test validation failed: notary validation error: validation failed", <-recorder.Events)
+	})
+
+	t.Run("when invalid image in dry-run request should not record event", func(t *testing.T) {
+		//GIVEN
+		ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName,
+			Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled}}}
+		mockImageValidator := mocks.ImageValidatorService{}
+		mockImageValidator.Mock.On("Validate", mock.Anything, "test:test", mock.Anything).
+			Return(pkg.NewValidationFailedErr(errors.New("validation failed")))
+		mockPodValidator := validate.NewPodValidator(&mockImageValidator)
+
+		pod := newPodFix(nsName, nil)
+		req := newRequestFix(t, pod, admissionv1.Create)
+		req.DryRun = ptr.To(true)
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
+		recorder := record.NewFakeRecorder(10)
+		webhook := NewDefaultingWebhook(client, client,
+			mockPodValidator, nil, timeout, false, &decoder, recorder, logger.Sugar())
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.NotNil(t, res)
+		require.ElementsMatch(t, withAddRejectAndImagesAnnotation(patchWithAddLabel(pkg.ValidationStatusFailed)), res.Patches)
+		require.Empty(t, recorder.Events)
+	})
+
+	t.Run("when invalid image in namespace with warn validation should return failed and annotation warn", func(t *testing.T) {
+		//GIVEN
+		ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName,
+			Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationWarn}}}
+		mockImageValidator := mocks.ImageValidatorService{}
+		mockImageValidator.Mock.On("Validate", mock.Anything, "test:test", mock.Anything).
+			Return(pkg.NewValidationFailedErr(errors.New("validation failed")))
+		mockPodValidator := validate.NewPodValidator(&mockImageValidator)
+
+		pod := newPodFix(nsName, nil)
+		req := newRequestFix(t, pod, admissionv1.Create)
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
+		webhook := NewDefaultingWebhook(client, client,
+			mockPodValidator, nil, timeout, false, &decoder, &record.FakeRecorder{}, logger.Sugar())
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		mockImageValidator.AssertNumberOfCalls(t, "Validate", 1)
+		require.NotNil(t, res)
+		require.True(t, res.AdmissionResponse.Allowed)
+		require.ElementsMatch(t, withAddWarnAndImagesAnnotation(patchWithAddLabel(pkg.ValidationStatusFailed)), res.Patches)
 	})
 
 	t.Run("when service unavailable and strict mode on should return pending and annotation reject", func(t *testing.T) {
@@ -260,7 +318,7 @@ func TestFlow_OutputStatuses_ForPodValidationResult(t *testing.T) {
 		req := newRequestFix(t, pod, admissionv1.Create)
 		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
 		webhook := NewDefaultingWebhook(client, client,
-			mockPodValidator, nil, timeout, StrictModeOn, &decoder, logger.Sugar())
+			mockPodValidator, nil, timeout, StrictModeOn, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
@@ -285,7 +343,7 @@ func TestFlow_OutputStatuses_ForPodValidationResult(t *testing.T) {
 		req := newRequestFix(t, pod, admissionv1.Create)
 		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
 		webhook := NewDefaultingWebhook(client, client,
-			mockPodValidator, nil, timeout, StrictModeOff, &decoder, logger.Sugar())
+			mockPodValidator, nil, timeout, StrictModeOff, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
@@ -317,7 +375,7 @@ func TestFlow_OutputStatuses_ForPodValidationResult(t *testing.T) {
 		defer userValidator.AssertExpectations(t)
 
 		userValidatorFactory := mocks.NewValidatorSvcFactory(t)
-		userValidatorFactory.On("NewValidatorSvc", mock.Anything, mock.Anything, mock.Anything).
+		userValidatorFactory.On("NewValidatorSvc", mock.Anything).
 			Return(userValidator).Once()
 		defer userValidatorFactory.AssertExpectations(t)
 
@@ -325,7 +383,7 @@ func TestFlow_OutputStatuses_ForPodValidationResult(t *testing.T) {
 		req := newRequestFix(t, pod, admissionv1.Create)
 		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
 		webhook := NewDefaultingWebhook(client, client,
-			systemValidator, userValidatorFactory, timeout, StrictModeOff, &decoder, logger.Sugar())
+			systemValidator, userValidatorFactory, timeout, StrictModeOff, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
@@ -357,7 +415,7 @@ func TestFlow_OutputStatuses_ForPodValidationResult(t *testing.T) {
 		defer userValidator.AssertExpectations(t)
 
 		userValidatorFactory := mocks.NewValidatorSvcFactory(t)
-		userValidatorFactory.On("NewValidatorSvc", mock.Anything, mock.Anything, mock.Anything).
+		userValidatorFactory.On("NewValidatorSvc", mock.Anything).
 			Return(userValidator).Once()
 		defer userValidatorFactory.AssertExpectations(t)
 
@@ -365,7 +423,7 @@ func TestFlow_OutputStatuses_ForPodValidationResult(t *testing.T) {
 		req := newRequestFix(t, pod, admissionv1.Create)
 		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
 		webhook := NewDefaultingWebhook(client, client,
-			systemValidator, userValidatorFactory, timeout, StrictModeOn, &decoder, logger.Sugar())
+			systemValidator, userValidatorFactory, timeout, StrictModeOn, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
@@ -502,7 +560,7 @@ func TestFlow_SomeInputStatuses_ShouldCallPodValidation(t *testing.T) {
 			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
 			timeout := time.Second
 			webhook := NewDefaultingWebhook(client, client,
-				mockPodValidator, nil, timeout, false, &decoder, logger.Sugar())
+				mockPodValidator, nil, timeout, false, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 			//WHEN
 			res := webhook.Handle(context.TODO(), req)
@@ -566,7 +624,7 @@ func TestFlow_NamespaceLabelsValidation(t *testing.T) {
 			validationSvc.AssertNotCalled(t, "ValidatePod")
 			defer validationSvc.AssertExpectations(t)
 			webhook := NewDefaultingWebhook(client, client,
-				validationSvc, nil, timeout, StrictModeOff, &decoder, logger.Sugar())
+				validationSvc, nil, timeout, StrictModeOff, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 			//WHEN
 			res := webhook.Handle(context.TODO(), req)
@@ -637,12 +695,12 @@ func TestFlow_NamespaceLabelsValidation(t *testing.T) {
 			defer userValidator.AssertExpectations(t)
 
 			userValidatorFactory := mocks.NewValidatorSvcFactory(t)
-			userValidatorFactory.On("NewValidatorSvc", mock.Anything, mock.Anything, mock.Anything).
+			userValidatorFactory.On("NewValidatorSvc", mock.Anything).
 				Return(userValidator).Maybe()
 			defer userValidatorFactory.AssertExpectations(t)
 
 			webhook := NewDefaultingWebhook(client, client,
-				systemValidator, userValidatorFactory, timeout, StrictModeOff, &decoder, logger.Sugar())
+				systemValidator, userValidatorFactory, timeout, StrictModeOff, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 			//WHEN
 			res := webhook.Handle(context.TODO(), req)
@@ -697,7 +755,7 @@ func TestFlow_UseSystemOrUserValidator(t *testing.T) {
 		defer userValidatorFactory.AssertExpectations(t)
 
 		webhook := NewDefaultingWebhook(client, client,
-			validationSvc, userValidatorFactory, timeout, StrictModeOff, &decoder, logger.Sugar())
+			validationSvc, userValidatorFactory, timeout, StrictModeOff, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
@@ -743,12 +801,12 @@ func TestFlow_UseSystemOrUserValidator(t *testing.T) {
 		defer userValidator.AssertExpectations(t)
 
 		userValidatorFactory := mocks.NewValidatorSvcFactory(t)
-		userValidatorFactory.On("NewValidatorSvc", mock.Anything, mock.Anything, mock.Anything).
+		userValidatorFactory.On("NewValidatorSvc", mock.Anything).
 			Return(userValidator).Once()
 		defer userValidatorFactory.AssertExpectations(t)
 
 		webhook := NewDefaultingWebhook(client, client,
-			systemValidator, userValidatorFactory, timeout, StrictModeOff, &decoder, logger.Sugar())
+			systemValidator, userValidatorFactory, timeout, StrictModeOff, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 		//WHEN
 		res := webhook.Handle(context.TODO(), req)
@@ -758,6 +816,172 @@ func TestFlow_UseSystemOrUserValidator(t *testing.T) {
 		require.Nil(t, res.Result)
 		assert.True(t, res.Allowed)
 	})
+
+	t.Run("Image policy is resolved once per request", func(t *testing.T) {
+		//GIVEN
+		policyScheme := runtime.NewScheme()
+		require.NoError(t, corev1.AddToScheme(policyScheme))
+		require.NoError(t, v1alpha1.AddToScheme(policyScheme))
+		namespaceLabels := map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationUser}
+		ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNs, Labels: namespaceLabels}}
+		policy := v1alpha1.ImagePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: testNs},
+			Spec: v1alpha1.ImagePolicySpec{
+				Notary:     &v1alpha1.NotaryVerifier{URL: "https://notary"},
+				StrictMode: ptr.To(true),
+			},
+		}
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: testNs},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Image: "test:test"}}},
+		}
+
+		raw, err := json.Marshal(pod)
+		require.NoError(t, err)
+
+		req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:   metav1.GroupVersionKind{Kind: PodType, Version: corev1.SchemeGroupVersion.Version},
+			Object: runtime.RawExtension{Raw: raw},
+		}}
+		lists := 0
+		client := fake.NewClientBuilder().WithScheme(policyScheme).WithObjects(&ns, &policy).
+			WithInterceptorFuncs(interceptor.Funcs{
+				List: func(ctx context.Context, client k8sclient.WithWatch, list k8sclient.ObjectList, opts ...k8sclient.ListOption) error {
+					lists++
+					return client.List(ctx, list, opts...)
+				},
+			}).Build()
+
+		userValidator := mocks.NewPodValidator(t)
+		userValidator.On("ValidatePod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(validate.ValidationResult{Status: validate.ServiceUnavailable}, nil).Once()
+		userValidatorFactory := mocks.NewValidatorSvcFactory(t)
+		userValidatorFactory.On("NewValidatorSvc", mock.Anything).
+			Return(userValidator).Once()
+
+		webhook := NewDefaultingWebhook(client, client,
+			mocks.NewPodValidator(t), userValidatorFactory, timeout, StrictModeOff, &decoder, &record.FakeRecorder{}, logger.Sugar())
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.True(t, res.Allowed)
+		// the policy of the namespace is found with the first list and it's used for the strict mode
+		require.Equal(t, 1, lists)
+		require.ElementsMatch(t, withAddRejectAnnotation(patchWithAddLabel(pkg.ValidationStatusPending)), res.Patches)
+	})
+}
+
+func TestFlow_EphemeralContainers(t *testing.T) {
+	//GIVEN
+	logger := zap.NewNop()
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	decoder := admission.NewDecoder(scheme)
+	timeout := time.Millisecond * 100
+
+	testNs := "test-namespace"
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNs,
+		Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled}}}
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: testNs,
+			Labels: map[string]string{pkg.PodValidationLabel: pkg.ValidationStatusSuccess}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Image: "test:test"}},
+			EphemeralContainers: []corev1.EphemeralContainer{
+				{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Image: "debug:test"}},
+			},
+		},
+	}
+
+	raw, err := json.Marshal(pod)
+	require.NoError(t, err)
+
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Kind:        metav1.GroupVersionKind{Kind: PodType, Version: corev1.SchemeGroupVersion.Version},
+		SubResource: EphemeralContainersSubResource,
+		Operation:   admissionv1.Update,
+		Object:      runtime.RawExtension{Raw: raw},
+	}}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
+
+	onlyEphemeralContainers := mock.MatchedBy(func(p *corev1.Pod) bool {
+		return len(p.Spec.Containers) == 0 && len(p.Spec.EphemeralContainers) == 1
+	})
+
+	tests := []struct {
+		name            string
+		strictMode      bool
+		result          validate.ValidationResult
+		expectedAllowed bool
+		expectedMessage string
+	}{
+		{
+			name:            "valid ephemeral container is allowed",
+			result:          validate.ValidationResult{Status: validate.Valid},
+			expectedAllowed: true,
+		},
+		{
+			name:            "invalid ephemeral container is denied",
+			result:          validate.ValidationResult{Status: validate.Invalid, InvalidImages: []string{"debug:test"}},
+			expectedAllowed: false,
+			expectedMessage: "Pod ephemeral container images debug:test validation failed",
+		},
+		{
+			name:            "unavailable validation is allowed with strict mode off",
+			strictMode:      StrictModeOff,
+			result:          validate.ValidationResult{Status: validate.ServiceUnavailable, InvalidImages: []string{"debug:test"}},
+			expectedAllowed: true,
+		},
+		{
+			name:            "unavailable validation is denied with strict mode on",
+			strictMode:      StrictModeOn,
+			result:          validate.ValidationResult{Status: validate.ServiceUnavailable, InvalidImages: []string{"debug:test"}},
+			expectedAllowed: false,
+			expectedMessage: "Pod ephemeral container images couldn't be validated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			validationSvc := mocks.NewPodValidator(t)
+			validationSvc.On("ValidatePod", mock.Anything, onlyEphemeralContainers, mock.Anything, mock.Anything).
+				Return(tt.result, nil).Once()
+			webhook := NewDefaultingWebhook(client, client,
+				validationSvc, nil, timeout, tt.strictMode, &decoder, &record.FakeRecorder{}, logger.Sugar())
+
+			//WHEN
+			res := webhook.Handle(context.TODO(), req)
+
+			//THEN
+			require.NotNil(t, res)
+			assert.Equal(t, tt.expectedAllowed, res.Allowed)
+			assert.Empty(t, res.Patches)
+			require.NotNil(t, res.Result)
+			assert.Contains(t, res.Result.Message, tt.expectedMessage)
+		})
+	}
+
+	t.Run("updated strict mode is applied to next requests", func(t *testing.T) {
+		//GIVEN
+		validationSvc := mocks.NewPodValidator(t)
+		validationSvc.On("ValidatePod", mock.Anything, onlyEphemeralContainers, mock.Anything, mock.Anything).
+			Return(validate.ValidationResult{Status: validate.ServiceUnavailable, InvalidImages: []string{"debug:test"}}, nil).Twice()
+		webhook := NewDefaultingWebhook(client, client,
+			validationSvc, nil, timeout, StrictModeOff, &decoder, &record.FakeRecorder{}, logger.Sugar())
+		require.True(t, webhook.Handle(context.TODO(), req).Allowed)
+
+		//WHEN
+		webhook.UpdateSettings(Settings{Timeout: timeout, StrictMode: StrictModeOn})
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.NotNil(t, res)
+		assert.False(t, res.Allowed)
+		require.NotNil(t, res.Result)
+		assert.Contains(t, res.Result.Message, "Pod ephemeral container images couldn't be validated")
+	})
 }
 
 func TestFlow_UserValidatorGetValuesFromNamespaceAnnotations(t *testing.T) {
@@ -856,20 +1080,18 @@ func TestFlow_UserValidatorGetValuesFromNamespaceAnnotations(t *testing.T) {
 
 			// user validator factory should be called with proper data
 			userValidatorFactory := mocks.NewValidatorSvcFactory(t)
-			userValidatorFactory.On("NewValidatorSvc", mock.Anything, mock.Anything, mock.Anything).
+			userValidatorFactory.On("NewValidatorSvc", mock.Anything).
 				Return(userValidator).
 				Run(func(args mock.Arguments) {
-					argNotaryURL := args.Get(0)
-					argAllowedRegistries := args.Get(1)
-					argNotaryTimeout := args.Get(2)
-					require.Equal(t, expectedNotaryURL, argNotaryURL)
-					require.Equal(t, expectedAllowedRegistries, argAllowedRegistries)
-					require.Equal(t, expectedNotaryTimeout, argNotaryTimeout)
+					argConfig := args.Get(0).(validate.ValidatorSvcConfig)
+					require.Equal(t, expectedNotaryURL, argConfig.NotaryURL)
+					require.Equal(t, expectedAllowedRegistries, argConfig.AllowedRegistries)
+					require.Equal(t, expectedNotaryTimeout, argConfig.NotaryTimeout)
 				}).Maybe()
 			defer userValidatorFactory.AssertExpectations(t)
 
 			webhook := NewDefaultingWebhook(client, client,
-				systemValidator, userValidatorFactory, timeout, StrictModeOff, &decoder, logger.Sugar())
+				systemValidator, userValidatorFactory, timeout, StrictModeOff, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 			//WHEN
 			res := webhook.Handle(context.TODO(), req)
@@ -888,6 +1110,40 @@ func TestFlow_UserValidatorGetValuesFromNamespaceAnnotations(t *testing.T) {
 	}
 }
 
+func TestHandleTimeoutBoundsRequests(t *testing.T) {
+	//GIVEN
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	decoder := admission.NewDecoder(scheme)
+	raw, err := json.Marshal(corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "test-namespace"}})
+	require.NoError(t, err)
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Kind:   metav1.GroupVersionKind{Kind: PodType, Version: corev1.SchemeGroupVersion.Version},
+		Object: runtime.RawExtension{Raw: raw},
+	}}
+	// the namespace request hangs until it's cancelled
+	client := fake.NewClientBuilder().WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, _ k8sclient.WithWatch, _ k8sclient.ObjectKey, _ k8sclient.Object, _ ...k8sclient.GetOption) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		}).Build()
+	webhook := NewDefaultingWebhook(client, client,
+		mocks.NewPodValidator(t), mocks.NewValidatorSvcFactory(t), time.Millisecond*100, StrictModeOff, &decoder, &record.FakeRecorder{}, zap.NewNop().Sugar())
+	expiredCtx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	start := time.Now()
+
+	//WHEN
+	res := webhook.handleTimeout(expiredCtx, context.DeadlineExceeded, req)
+
+	//THEN
+	require.False(t, res.Allowed)
+	require.Equal(t, int32(http.StatusInternalServerError), res.Result.Code)
+	require.Less(t, time.Since(start), 2*TimeoutHandlerBudget)
+}
+
 func TestHandleTimeout(t *testing.T) {
 	//GIVEN
 	logger := zap.NewNop()
@@ -988,7 +1244,7 @@ func TestHandleTimeout(t *testing.T) {
 			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
 
 			webhook := NewDefaultingWebhook(client, client,
-				nil, nil, timeout, tt.systemStrictMode, &decoder, logger.Sugar())
+				nil, nil, timeout, tt.systemStrictMode, &decoder, &record.FakeRecorder{}, logger.Sugar())
 
 			//WHEN
 			res := webhook.handleTimeout(ctxLogger, errors.New(""), req)
@@ -1077,6 +1333,17 @@ func withAddRejectAnnotation(patch []jsonpatch.JsonPatchOperation) []jsonpatch.J
 	})
 }
 
+func withAddWarnAndImagesAnnotation(patch []jsonpatch.JsonPatchOperation) []jsonpatch.JsonPatchOperation {
+	return append(patch, jsonpatch.JsonPatchOperation{
+		Operation: "add",
+		Path:      "/metadata/annotations",
+		Value: map[string]interface{}{
+			"pods.warden.kyma-project.io/invalid-images":  "test:test",
+			"pods.warden.kyma-project.io/validate-reject": "warn",
+		},
+	})
+}
+
 func withAddRejectAndImagesAnnotation(patch []jsonpatch.JsonPatchOperation) []jsonpatch.JsonPatchOperation {
 	return append(patch, jsonpatch.JsonPatchOperation{
 		Operation: "add",
@@ -0,0 +1,33 @@
+package admission
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Settings of the system validation which are applied to requests without restarting webhooks
+type Settings struct {
+	// Timeout after which the webhook responds without the validation result
+	Timeout time.Duration
+	// StrictMode rejects pods which couldn't be validated
+	StrictMode bool
+}
+
+// reloadableSettings are replaced as a whole, so partially updated settings are never read
+type reloadableSettings struct {
+	current atomic.Pointer[Settings]
+}
+
+func newReloadableSettings(settings Settings) *reloadableSettings {
+	s := &reloadableSettings{}
+	s.update(settings)
+	return s
+}
+
+func (s *reloadableSettings) update(settings Settings) {
+	s.current.Store(&settings)
+}
+
+func (s *reloadableSettings) load() Settings {
+	return *s.current.Load()
+}
@@ -6,11 +6,13 @@ import (
 	"net/http"
 
 	"github.com/kyma-project/warden/internal/annotations"
+	"github.com/kyma-project/warden/internal/events"
 	"github.com/kyma-project/warden/internal/helpers"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
@@ -20,13 +22,15 @@ const (
 
 type ValidationWebhook struct {
 	decoder    *admission.Decoder
+	recorder   record.EventRecorder
 	baseLogger *zap.SugaredLogger
 }
 
-func NewValidationWebhook(logger *zap.SugaredLogger, decoder *admission.Decoder) *ValidationWebhook {
+func NewValidationWebhook(logger *zap.SugaredLogger, decoder *admission.Decoder, recorder record.EventRecorder) *ValidationWebhook {
 	return &ValidationWebhook{
 		baseLogger: logger,
 		decoder:    decoder,
+		recorder:   recorder,
 	}
 }
 
@@ -46,6 +50,12 @@ func (w *ValidationWebhook) handle(ctx context.Context, req admission.Request) a
 			errors.Errorf("Invalid request kind: %s, expected: %s", req.Kind.Kind, PodType))
 	}
 
+	if req.SubResource == EphemeralContainersSubResource {
+		// the defaulting webhook denies invalid ephemeral containers directly,
+		// the reject annotation can't be set through the ephemeralcontainers subresource
+		return admission.Allowed("ephemeral containers are validated by the defaulting webhook")
+	}
+
 	pod := &corev1.Pod{}
 	if err := (*w.decoder).Decode(req, pod); err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
@@ -55,14 +65,30 @@ func (w *ValidationWebhook) handle(ctx context.Context, req admission.Request) a
 		return admission.Allowed("nothing to do")
 	}
 
-	if pod.Annotations[annotations.PodValidationRejectAnnotation] != annotations.ValidationReject {
+	switch pod.Annotations[annotations.PodValidationRejectAnnotation] {
+	case annotations.ValidationReject:
+		logger.Info("Pod images validation failed")
+		w.recordPodRejected(req, pod, false)
+		return admission.Denied(validationFailedMessage(pod))
+	case annotations.ValidationWarn:
+		logger.Info("Pod images validation failed, pod is admitted with warning")
+		w.recordPodRejected(req, pod, true)
+		return admission.Allowed("").WithWarnings(validationFailedMessage(pod))
+	default:
 		return admission.Allowed("nothing to do")
 	}
+}
 
-	logger.Info("Pod images validation failed")
-	if _, ok := pod.Annotations[annotations.InvalidImagesAnnotation]; ok {
-		return admission.Denied(fmt.Sprintf("Pod images %s validation failed", pod.Annotations[annotations.InvalidImagesAnnotation]))
+func (w *ValidationWebhook) recordPodRejected(req admission.Request, pod *corev1.Pod, warnMode bool) {
+	if isDryRun(req) {
+		return
 	}
+	events.RecordPodRejected(w.recorder, pod, warnMode, validationFailedMessage(pod))
+}
 
-	return admission.Denied("Pod images validation failed")
+func validationFailedMessage(pod *corev1.Pod) string {
+	if _, ok := pod.Annotations[annotations.InvalidImagesAnnotation]; ok {
+		return fmt.Sprintf("Pod images %s validation failed", pod.Annotations[annotations.InvalidImagesAnnotation])
+	}
+	return "Pod images validation failed"
 }
@@ -18,13 +18,14 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestValidationWebhook(t *testing.T) {
 	scheme := runtime.NewScheme()
 	decoder := admission.NewDecoder(scheme)
 	log := test_helpers.NewTestZapLogger(t).Sugar()
-	webhook := NewValidationWebhook(log, &decoder)
+	webhook := NewValidationWebhook(log, &decoder, &record.FakeRecorder{})
 
 	testCases := []struct {
 		name            string
@@ -87,13 +88,89 @@ func TestValidationWebhook(t *testing.T) {
 			assert.Contains(t, resp.Result.Message, tc.expectedMessage)
 		})
 	}
+
+	t.Run("Pod should be allowed with warning", func(t *testing.T) {
+		//GIVE
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-pod",
+				Annotations: map[string]string{
+					annotations.PodValidationRejectAnnotation: annotations.ValidationWarn,
+					annotations.InvalidImagesAnnotation:       "test:test",
+				}},
+		}
+		rawPod, err := json.Marshal(pod)
+		require.NoError(t, err)
+		req := admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:   metav1.GroupVersionKind{Kind: PodType, Version: corev1.SchemeGroupVersion.Version},
+				Object: runtime.RawExtension{Raw: rawPod},
+			}}
+		recorder := record.NewFakeRecorder(10)
+		webhook := NewValidationWebhook(log, &decoder, recorder)
+
+		//WHEN
+		resp := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.True(t, resp.Allowed)
+		require.Equal(t, []string{"Pod images test:test validation failed"}, resp.Warnings)
+		require.Len(t, recorder.Events, 1)
+		require.Equal(t, "Warning PodAdmittedWithWarning Pod images test:test validation failed", <-recorder.Events)
+	})
+
+	t.Run("Pod rejection is recorded", func(t *testing.T) {
+		//GIVE
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-pod",
+				Annotations: map[string]string{
+					annotations.PodValidationRejectAnnotation: annotations.ValidationReject,
+					annotations.InvalidImagesAnnotation:       "test:test",
+				}},
+		}
+		rawPod, err := json.Marshal(pod)
+		require.NoError(t, err)
+		req := admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:   metav1.GroupVersionKind{Kind: PodType, Version: corev1.SchemeGroupVersion.Version},
+				Object: runtime.RawExtension{Raw: rawPod},
+			}}
+		recorder := record.NewFakeRecorder(10)
+		webhook := NewValidationWebhook(log, &decoder, recorder)
+
+		//WHEN
+		resp := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.False(t, resp.Allowed)
+		require.Len(t, recorder.Events, 1)
+		require.Equal(t, "Warning PodRejected Pod images test:test validation failed", <-recorder.Events)
+	})
+
+	t.Run("Ephemeral containers are allowed", func(t *testing.T) {
+		//GIVE
+		req := admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:        metav1.GroupVersionKind{Kind: PodType, Version: corev1.SchemeGroupVersion.Version},
+				SubResource: EphemeralContainersSubResource,
+				Operation:   admissionv1.Update,
+			}}
+
+		//WHEN
+		resp := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.NotNil(t, resp)
+		require.NotNil(t, resp.Result)
+		assert.Equal(t, int32(http.StatusOK), resp.Result.Code)
+		assert.Contains(t, resp.Result.Message, "validated by the defaulting webhook")
+	})
 }
 
 func TestValidationWebhook_Errors(t *testing.T) {
 	scheme := runtime.NewScheme()
 	decoder := admission.NewDecoder(scheme)
 	log := test_helpers.NewTestZapLogger(t).Sugar()
-	webhook := NewValidationWebhook(log, &decoder)
+	webhook := NewValidationWebhook(log, &decoder, &record.FakeRecorder{})
 	testCases := []struct {
 		name            string
 		req             admission.Request
@@ -0,0 +1,232 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	WorkloadValidationPath = "/validation/workloads"
+)
+
+const (
+	DeploymentType  = "Deployment"
+	StatefulSetType = "StatefulSet"
+	DaemonSetType   = "DaemonSet"
+	JobType         = "Job"
+	CronJobType     = "CronJob"
+)
+
+// WorkloadWebhook validates images of workload pod templates, so the workload with invalid images
+// is rejected when it's applied instead of failing later on pods creation
+type WorkloadWebhook struct {
+	systemValidator          validate.PodValidator
+	userValidationSvcFactory validate.ValidatorSvcFactory
+	settings                 *reloadableSettings
+	client                   k8sclient.Client
+	reader                   k8sclient.Reader
+	decoder                  *admission.Decoder
+	baseLogger               *zap.SugaredLogger
+}
+
+func NewWorkloadWebhook(client k8sclient.Client, reader k8sclient.Reader,
+	systemValidator validate.PodValidator, userValidationSvcFactory validate.ValidatorSvcFactory,
+	timeout time.Duration, strictMode bool,
+	decoder *admission.Decoder, logger *zap.SugaredLogger) *WorkloadWebhook {
+	return &WorkloadWebhook{
+		client:                   client,
+		reader:                   reader,
+		systemValidator:          systemValidator,
+		userValidationSvcFactory: userValidationSvcFactory,
+		baseLogger:               logger,
+		settings:                 newReloadableSettings(Settings{Timeout: timeout, StrictMode: strictMode}),
+		decoder:                  decoder,
+	}
+}
+
+// UpdateSettings applies settings to requests received after the update
+func (w *WorkloadWebhook) UpdateSettings(settings Settings) {
+	w.settings.update(settings)
+}
+
+func (w *WorkloadWebhook) Handle(ctx context.Context, req admission.Request) admission.Response {
+	return HandleWithLogger(w.baseLogger,
+		HandlerWithTimeMeasure(
+			HandleWithTimeout(w.settings.load().Timeout, w.handle, w.handleTimeout)))(ctx, req)
+}
+
+func (w *WorkloadWebhook) handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation == admissionv1.Delete {
+		return admission.Allowed("")
+	}
+
+	template, err := w.decodePodTemplate(req.Kind.Kind, req.Object)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if req.Operation == admissionv1.Update {
+		oldTemplate, err := w.decodePodTemplate(req.Kind.Kind, req.OldObject)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if !areTemplateImagesChanged(oldTemplate, template) {
+			return admission.Allowed("workload images are not changed")
+		}
+	}
+
+	ns := &corev1.Namespace{}
+	if err := w.client.Get(ctx, k8sclient.ObjectKey{Name: req.Namespace}, ns); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if enabled := validate.IsValidationEnabledForNS(ns); !enabled {
+		return admission.Allowed("validation is not needed for workload")
+	}
+
+	policy, err := validate.GetUserImagePolicy(ctx, w.reader, ns)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	validator, err := getValidatorForNS(ctx, w.reader, ns, policy, w.systemValidator, w.userValidationSvcFactory)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	pod := podFromTemplate(req.Name, req.Namespace, template)
+	imagePullCredentials, err := helpers.GetRemotePullCredentials(ctx, w.reader, pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	result, err := validator.ValidatePod(ctx, pod, ns, imagePullCredentials)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	helpers.LoggerFromCtx(ctx).Infow("workload was validated", "kind", req.Kind.Kind, "result", result)
+	return w.createResponse(req.Kind.Kind, result, ns, policy)
+}
+
+func (w *WorkloadWebhook) handleTimeout(ctx context.Context, timeoutErr error, req admission.Request) admission.Response {
+	// the request context is already expired, but the namespace configuration still has to be read
+	ctx, cancel := timeoutHandlerContext(ctx)
+	defer cancel()
+
+	msg := fmt.Sprintf("request exceeded desired timeout: %s, reason: %s", w.settings.load().Timeout.String(), timeoutErr.Error())
+	helpers.LoggerFromCtx(ctx).Info(msg)
+
+	ns := &corev1.Namespace{}
+	if err := w.client.Get(ctx, k8sclient.ObjectKey{Name: req.Namespace}, ns); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	policy, err := validate.GetUserImagePolicy(ctx, w.reader, ns)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	res := w.createResponse(req.Kind.Kind, validate.ValidationResult{Status: validate.ServiceUnavailable}, ns, policy)
+	res.Result.Message = msg
+	return res
+}
+
+func (w *WorkloadWebhook) createResponse(kind string, result validate.ValidationResult, ns *corev1.Namespace, policy *validate.UserImagePolicy) admission.Response {
+	strictMode, err := isStrictModeForNS(ns, policy, w.settings.load().StrictMode)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	warnMode := isWarnModeForNS(ns, policy)
+
+	switch result.Status {
+	case validate.Invalid:
+		return denyOrWarn(warnMode, fmt.Sprintf("%s images %s validation failed", kind, strings.Join(result.InvalidImages, ", ")))
+	case validate.ServiceUnavailable:
+		msg := fmt.Sprintf("%s images couldn't be validated", kind)
+		if strictMode {
+			return denyOrWarn(warnMode, msg)
+		}
+		return admission.Allowed("").WithWarnings(msg)
+	default:
+		return admission.Allowed("workload images are valid")
+	}
+}
+
+func (w *WorkloadWebhook) decodePodTemplate(kind string, raw runtime.RawExtension) (*corev1.PodTemplateSpec, error) {
+	switch kind {
+	case DeploymentType:
+		deployment := &appsv1.Deployment{}
+		if err := (*w.decoder).DecodeRaw(raw, deployment); err != nil {
+			return nil, err
+		}
+		return &deployment.Spec.Template, nil
+	case StatefulSetType:
+		statefulSet := &appsv1.StatefulSet{}
+		if err := (*w.decoder).DecodeRaw(raw, statefulSet); err != nil {
+			return nil, err
+		}
+		return &statefulSet.Spec.Template, nil
+	case DaemonSetType:
+		daemonSet := &appsv1.DaemonSet{}
+		if err := (*w.decoder).DecodeRaw(raw, daemonSet); err != nil {
+			return nil, err
+		}
+		return &daemonSet.Spec.Template, nil
+	case JobType:
+		job := &batchv1.Job{}
+		if err := (*w.decoder).DecodeRaw(raw, job); err != nil {
+			return nil, err
+		}
+		return &job.Spec.Template, nil
+	case CronJobType:
+		cronJob := &batchv1.CronJob{}
+		if err := (*w.decoder).DecodeRaw(raw, cronJob); err != nil {
+			return nil, err
+		}
+		return &cronJob.Spec.JobTemplate.Spec.Template, nil
+	}
+	return nil, errors.Errorf("Invalid request kind: %s, expected one of: %s", kind,
+		strings.Join([]string{DeploymentType, StatefulSetType, DaemonSetType, JobType, CronJobType}, ", "))
+}
+
+// podFromTemplate creates the pod which would be created by the workload, so it can be validated by the PodValidator
+func podFromTemplate(name, namespace string, template *corev1.PodTemplateSpec) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      template.Labels,
+			Annotations: template.Annotations,
+		},
+		Spec: template.Spec,
+	}
+}
+
+func areTemplateImagesChanged(oldTemplate, newTemplate *corev1.PodTemplateSpec) bool {
+	return !slices.Equal(getTemplateImages(oldTemplate), getTemplateImages(newTemplate))
+}
+
+func getTemplateImages(template *corev1.PodTemplateSpec) []string {
+	var images []string
+	for _, container := range append(template.Spec.InitContainers, template.Spec.Containers...) {
+		images = append(images, container.Image)
+	}
+	slices.Sort(images)
+	return slices.Compact(images)
+}
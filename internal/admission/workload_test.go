@@ -0,0 +1,213 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestWorkloadWebhook(t *testing.T) {
+	//GIVEN
+	logger := zap.NewNop()
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, appsv1.AddToScheme(scheme))
+	require.NoError(t, batchv1.AddToScheme(scheme))
+	decoder := admission.NewDecoder(scheme)
+	timeout := time.Millisecond * 100
+
+	testNs := "test-namespace"
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNs,
+		Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled}}}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
+
+	template := corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+		InitContainers: []corev1.Container{{Image: "init:test"}},
+		Containers:     []corev1.Container{{Image: "test:test"}},
+	}}
+	templateImages := mock.MatchedBy(func(p *corev1.Pod) bool {
+		return p.Namespace == testNs && len(p.Spec.InitContainers) == 1 && len(p.Spec.Containers) == 1
+	})
+
+	deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: template}}
+	cronJob := &batchv1.CronJob{Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{
+		Spec: batchv1.JobSpec{Template: template}}}}
+
+	tests := []struct {
+		name            string
+		kind            string
+		object          runtime.Object
+		strictMode      bool
+		result          validate.ValidationResult
+		expectedAllowed bool
+		expectedMessage string
+		expectedWarning string
+	}{
+		{
+			name:            "valid deployment is allowed",
+			kind:            DeploymentType,
+			object:          deployment,
+			result:          validate.ValidationResult{Status: validate.Valid},
+			expectedAllowed: true,
+		},
+		{
+			name:            "invalid deployment is denied",
+			kind:            DeploymentType,
+			object:          deployment,
+			result:          validate.ValidationResult{Status: validate.Invalid, InvalidImages: []string{"test:test"}},
+			expectedAllowed: false,
+			expectedMessage: "Deployment images test:test validation failed",
+		},
+		{
+			name:            "invalid cronjob is denied",
+			kind:            CronJobType,
+			object:          cronJob,
+			result:          validate.ValidationResult{Status: validate.Invalid, InvalidImages: []string{"init:test"}},
+			expectedAllowed: false,
+			expectedMessage: "CronJob images init:test validation failed",
+		},
+		{
+			name:            "unavailable validation is allowed with warning with strict mode off",
+			kind:            DeploymentType,
+			object:          deployment,
+			strictMode:      StrictModeOff,
+			result:          validate.ValidationResult{Status: validate.ServiceUnavailable, InvalidImages: []string{"test:test"}},
+			expectedAllowed: true,
+			expectedWarning: "Deployment images couldn't be validated",
+		},
+		{
+			name:            "unavailable validation is denied with strict mode on",
+			kind:            DeploymentType,
+			object:          deployment,
+			strictMode:      StrictModeOn,
+			result:          validate.ValidationResult{Status: validate.ServiceUnavailable, InvalidImages: []string{"test:test"}},
+			expectedAllowed: false,
+			expectedMessage: "Deployment images couldn't be validated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			raw, err := json.Marshal(tt.object)
+			require.NoError(t, err)
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Kind: tt.kind},
+				Namespace: testNs,
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+			}}
+
+			validationSvc := mocks.NewPodValidator(t)
+			validationSvc.On("ValidatePod", mock.Anything, templateImages, mock.Anything, mock.Anything).
+				Return(tt.result, nil).Once()
+			webhook := NewWorkloadWebhook(client, client,
+				validationSvc, nil, timeout, tt.strictMode, &decoder, logger.Sugar())
+
+			//WHEN
+			res := webhook.Handle(context.TODO(), req)
+
+			//THEN
+			require.NotNil(t, res)
+			assert.Equal(t, tt.expectedAllowed, res.Allowed)
+			require.NotNil(t, res.Result)
+			assert.Contains(t, res.Result.Message, tt.expectedMessage)
+			if tt.expectedWarning != "" {
+				assert.Contains(t, res.Warnings, tt.expectedWarning)
+			}
+		})
+	}
+
+	t.Run("invalid deployment in namespace with warn validation is allowed with warning", func(t *testing.T) {
+		//GIVEN
+		warnNs := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "warn-namespace",
+			Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationWarn}}}
+		warnClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&warnNs).Build()
+		raw, err := json.Marshal(deployment)
+		require.NoError(t, err)
+		req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Kind: DeploymentType},
+			Namespace: warnNs.Name,
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+
+		validationSvc := mocks.NewPodValidator(t)
+		validationSvc.On("ValidatePod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(validate.ValidationResult{Status: validate.Invalid, InvalidImages: []string{"test:test"}}, nil).Once()
+		webhook := NewWorkloadWebhook(warnClient, warnClient,
+			validationSvc, nil, timeout, StrictModeOn, &decoder, logger.Sugar())
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.True(t, res.Allowed)
+		require.Equal(t, []string{"Deployment images test:test validation failed"}, res.Warnings)
+	})
+
+	t.Run("update without changed images is not validated", func(t *testing.T) {
+		//GIVEN
+		raw, err := json.Marshal(deployment)
+		require.NoError(t, err)
+		scaled := deployment.DeepCopy()
+		scaled.Spec.Replicas = ptr.To[int32](3)
+		scaledRaw, err := json.Marshal(scaled)
+		require.NoError(t, err)
+		req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Kind: DeploymentType},
+			Namespace: testNs,
+			Operation: admissionv1.Update,
+			Object:    runtime.RawExtension{Raw: scaledRaw},
+			OldObject: runtime.RawExtension{Raw: raw},
+		}}
+
+		validationSvc := mocks.NewPodValidator(t)
+		webhook := NewWorkloadWebhook(client, client,
+			validationSvc, nil, timeout, StrictModeOn, &decoder, logger.Sugar())
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		assert.True(t, res.Allowed)
+		validationSvc.AssertNotCalled(t, "ValidatePod", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unsupported kind is errored", func(t *testing.T) {
+		//GIVEN
+		req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Kind: "ReplicationController"},
+			Namespace: testNs,
+			Operation: admissionv1.Create,
+		}}
+		webhook := NewWorkloadWebhook(client, client,
+			nil, nil, timeout, StrictModeOff, &decoder, logger.Sugar())
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		assert.False(t, res.Allowed)
+		require.NotNil(t, res.Result)
+		assert.Equal(t, int32(http.StatusBadRequest), res.Result.Code)
+	})
+}
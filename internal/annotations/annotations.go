@@ -5,4 +5,5 @@ const (
 	PodValidationRejectAnnotation = "pods.warden.kyma-project.io/validate-reject"
 	InvalidImagesAnnotation       = "pods.warden.kyma-project.io/invalid-images"
 	ValidationReject              = "reject"
+	ValidationWarn                = "warn" // used instead of ValidationReject when the pod is admitted with warnings
 )
@@ -1,18 +1,60 @@
 package config
 
 import (
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
 	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type notary struct {
-	URL                             string        `yaml:"URL"`
-	Timeout                         time.Duration `yaml:"timeout"`
-	AllowedRegistries               string        `yaml:"allowedRegistries"`
-	PredefinedUserAllowedRegistries string        `yaml:"predefinedUserAllowedRegistries"`
+	URL                             string         `yaml:"URL"`
+	Timeout                         time.Duration  `yaml:"timeout"`
+	AllowedRegistries               string         `yaml:"allowedRegistries"`
+	PredefinedUserAllowedRegistries string         `yaml:"predefinedUserAllowedRegistries"`
+	CircuitBreaker                  circuitBreaker `yaml:"circuitBreaker"`
+}
+
+type circuitBreaker struct {
+	FailureThreshold int           `yaml:"failureThreshold"`
+	OpenTimeout      time.Duration `yaml:"openTimeout"`
+}
+
+type verification struct {
+	Verifier string   `yaml:"verifier"`
+	Mode     string   `yaml:"mode"`
+	Cosign   cosign   `yaml:"cosign"`
+	Notation notation `yaml:"notation"`
+}
+
+type cosign struct {
+	PublicKeys string `yaml:"publicKeys"`
+}
+
+type notation struct {
+	TrustPolicyPath string `yaml:"trustPolicyPath"`
+	TrustStorePath  string `yaml:"trustStorePath"`
+}
+
+// credentialProviders configures kubelet credential provider plugins used to read private images
+type credentialProviders struct {
+	ConfigPath string `yaml:"configPath"`
+	BinDir     string `yaml:"binDir"`
+}
+
+type cache struct {
+	Enabled    bool          `yaml:"enabled"`
+	ValidTTL   time.Duration `yaml:"validTTL"`
+	InvalidTTL time.Duration `yaml:"invalidTTL"`
+	MaxEntries int           `yaml:"maxEntries"`
 }
 
 type admission struct {
@@ -22,6 +64,60 @@ type admission struct {
 	Timeout         time.Duration `yaml:"timeout"`
 	Port            int           `yaml:"port"`
 	StrictMode      bool          `yaml:"strictMode"`
+	Certificate     certificate   `yaml:"certificate"`
+	Webhooks        webhooks      `yaml:"webhooks"`
+}
+
+// webhooks configures the registration of admission webhooks in the API server
+type webhooks struct {
+	FailurePolicy      string         `yaml:"failurePolicy"`
+	NamespaceSelector  *labelSelector `yaml:"namespaceSelector"`
+	ObjectSelector     *labelSelector `yaml:"objectSelector"`
+	Defaulting         webhook        `yaml:"defaulting"`
+	Validation         webhook        `yaml:"validation"`
+	WorkloadValidation webhook        `yaml:"workloadValidation"`
+}
+
+type webhook struct {
+	Timeout     time.Duration `yaml:"timeout"`
+	MatchPolicy string        `yaml:"matchPolicy"`
+}
+
+type labelSelector struct {
+	MatchLabels      map[string]string          `yaml:"matchLabels"`
+	MatchExpressions []labelSelectorRequirement `yaml:"matchExpressions"`
+}
+
+type labelSelectorRequirement struct {
+	Key      string   `yaml:"key"`
+	Operator string   `yaml:"operator"`
+	Values   []string `yaml:"values"`
+}
+
+// LabelSelector converts the selector, it returns nil if the selector isn't configured
+func (s *labelSelector) LabelSelector() *metav1.LabelSelector {
+	if s == nil {
+		return nil
+	}
+	selector := &metav1.LabelSelector{MatchLabels: s.MatchLabels}
+	for _, requirement := range s.MatchExpressions {
+		selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      requirement.Key,
+			Operator: metav1.LabelSelectorOperator(requirement.Operator),
+			Values:   requirement.Values,
+		})
+	}
+	return selector
+}
+
+// certificate configures the CA and the serving certificate generated for the admission webhook
+type certificate struct {
+	Mode                string        `yaml:"mode"`
+	KeyAlgorithm        string        `yaml:"keyAlgorithm"`
+	CAValidity          time.Duration `yaml:"caValidity"`
+	CARotationThreshold time.Duration `yaml:"caRotationThreshold"`
+	Validity            time.Duration `yaml:"validity"`
+	RotationThreshold   time.Duration `yaml:"rotationThreshold"`
 }
 
 type operator struct {
@@ -31,11 +127,15 @@ type operator struct {
 	PodReconcilerRequeueAfter time.Duration `yaml:"podReconcilerRequeueAfter"`
 }
 
-type config struct {
-	Notary    notary    `yaml:"notary"`
-	Admission admission `yaml:"admission"`
-	Operator  operator  `yaml:"operator"`
-	Logging   logging   `yaml:"logging"`
+type Config struct {
+	Notary              notary              `yaml:"notary"`
+	Verification        verification        `yaml:"verification"`
+	CredentialProviders credentialProviders `yaml:"credentialProviders"`
+	Cache               cache               `yaml:"cache"`
+	Admission           admission           `yaml:"admission"`
+	Operator            operator            `yaml:"operator"`
+	Logging             logging             `yaml:"logging"`
+	Tracing             tracing             `yaml:"tracing"`
 }
 
 type logging struct {
@@ -43,7 +143,14 @@ type logging struct {
 	Format string `yaml:"format"`
 }
 
-func Load(path string) (*config, error) {
+type tracing struct {
+	Exporter      string  `yaml:"exporter"`
+	Endpoint      string  `yaml:"endpoint"`
+	Insecure      bool    `yaml:"insecure"`
+	SamplingRatio float64 `yaml:"samplingRatio"`
+}
+
+func Load(path string) (*Config, error) {
 	config := defaultConfig()
 
 	sanitizedPath, err := filepath.Abs(path)
@@ -56,15 +163,62 @@ func Load(path string) (*config, error) {
 		return nil, err
 	}
 
-	err = yaml.Unmarshal(yamlFile, config)
-	return config, err
+	if err := yaml.Unmarshal(yamlFile, config); err != nil {
+		return config, err
+	}
+	if err := validate(config); err != nil {
+		return nil, errors.Wrap(err, "invalid config")
+	}
+	return config, nil
 }
 
-func defaultConfig() *config {
-	return &config{
+// validate checks settings which are applied at startup and on reload by every binary,
+// binaries can reject the reloaded config for their own settings in the reload function
+func validate(config *Config) error {
+	if _, err := zapcore.ParseLevel(config.Logging.Level); err != nil {
+		return errors.Wrap(err, "invalid logger level")
+	}
+	if !config.usesNotary() {
+		return nil
+	}
+	notaryURL, err := url.Parse(config.Notary.URL)
+	if err != nil {
+		return errors.Wrap(err, "invalid notary URL")
+	}
+	if notaryURL.Scheme != "https" || notaryURL.Host == "" {
+		return errors.Errorf("notary URL %q must be an absolute https URL", config.Notary.URL)
+	}
+	if config.Notary.Timeout <= 0 {
+		return errors.Errorf("notary timeout must be positive, got %s", config.Notary.Timeout)
+	}
+	return nil
+}
+
+// usesNotary checks if notary is the configured verifier, it's the default if no verifier is configured
+func (c *Config) usesNotary() bool {
+	verifiers := helpers.ParseVerifiers(c.Verification.Verifier)
+	return len(verifiers) == 0 || slices.Contains(verifiers, pkg.VerifierNotary)
+}
+
+func defaultConfig() *Config {
+	return &Config{
 		Notary: notary{
 			URL:     "https://signing-dev.repositories.cloud.sap",
 			Timeout: time.Second * 30,
+			CircuitBreaker: circuitBreaker{
+				FailureThreshold: 5,
+				OpenTimeout:      time.Second * 30,
+			},
+		},
+		Verification: verification{
+			Verifier: pkg.VerifierNotary,
+			Mode:     pkg.VerificationModeAnyOf,
+		},
+		Cache: cache{
+			Enabled:    true,
+			ValidTTL:   time.Minute * 5,
+			InvalidTTL: time.Minute,
+			MaxEntries: 10000,
 		},
 		Admission: admission{
 			SystemNamespace: "default",
@@ -73,6 +227,27 @@ func defaultConfig() *config {
 			Port:            8443,
 			Timeout:         time.Second * 2,
 			StrictMode:      false,
+			Certificate: certificate{
+				Mode:                "generated",
+				KeyAlgorithm:        "RSA",
+				CAValidity:          time.Hour * 24 * 3650,
+				CARotationThreshold: time.Hour * 24 * 365,
+				Validity:            time.Hour * 24 * 90,
+				RotationThreshold:   time.Hour * 24 * 10,
+			},
+			Webhooks: webhooks{
+				FailurePolicy: "Ignore",
+				Defaulting: webhook{
+					MatchPolicy: "Exact",
+				},
+				Validation: webhook{
+					Timeout:     time.Second,
+					MatchPolicy: "Exact",
+				},
+				WorkloadValidation: webhook{
+					MatchPolicy: "Equivalent",
+				},
+			},
 		},
 		Operator: operator{
 			MetricsBindAddress:        ":8080",
@@ -84,5 +259,9 @@ func defaultConfig() *config {
 			Level:  "info",
 			Format: "text",
 		},
+		Tracing: tracing{
+			Exporter:      "none",
+			SamplingRatio: 1,
+		},
 	}
 }
@@ -4,14 +4,22 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
 	testURL                             = "https://signing-dev.repositories.cloud.sap"
 	testAllowedRegistries               = "test1,\ntest2,\ntest3"
 	testPredefinedUserAllowedRegistries = "user1,\nuser2"
+	testVerifier                        = "cosign"
+	testVerificationMode                = "allOf"
+	testCosignPublicKeys                = "-----BEGIN PUBLIC KEY-----\ntest\n-----END PUBLIC KEY-----"
+	testNotationTrustPolicyPath         = "/notation/trustpolicy.json"
+	testNotationTrustStorePath          = "/notation/truststore"
+	testTracingEndpoint                 = "otel-collector.kyma-system:4318"
 )
 
 func TestLoad(t *testing.T) {
@@ -25,6 +33,42 @@ func TestLoad(t *testing.T) {
 		require.Equal(t, testAllowedRegistries, cfg.Notary.AllowedRegistries)
 		require.Equal(t, testPredefinedUserAllowedRegistries, cfg.Notary.PredefinedUserAllowedRegistries)
 		require.Equal(t, testURL, cfg.Notary.URL)
+		require.Equal(t, 3, cfg.Notary.CircuitBreaker.FailureThreshold)
+		require.Equal(t, time.Minute, cfg.Notary.CircuitBreaker.OpenTimeout)
+		require.Equal(t, testVerifier, cfg.Verification.Verifier)
+		require.Equal(t, testVerificationMode, cfg.Verification.Mode)
+		require.Equal(t, testCosignPublicKeys, cfg.Verification.Cosign.PublicKeys)
+		require.Equal(t, testNotationTrustPolicyPath, cfg.Verification.Notation.TrustPolicyPath)
+		require.Equal(t, testNotationTrustStorePath, cfg.Verification.Notation.TrustStorePath)
+		require.Equal(t, "/etc/warden/credential-providers.yaml", cfg.CredentialProviders.ConfigPath)
+		require.Equal(t, "/usr/libexec/credential-providers", cfg.CredentialProviders.BinDir)
+		require.False(t, cfg.Cache.Enabled)
+		require.Equal(t, 10*time.Minute, cfg.Cache.ValidTTL)
+		require.Equal(t, time.Minute, cfg.Cache.InvalidTTL)
+		require.Equal(t, 100, cfg.Cache.MaxEntries)
+		require.Equal(t, "external", cfg.Admission.Certificate.Mode)
+		require.Equal(t, "ECDSA", cfg.Admission.Certificate.KeyAlgorithm)
+		require.Equal(t, 30*24*time.Hour, cfg.Admission.Certificate.Validity)
+		require.Equal(t, 7*24*time.Hour, cfg.Admission.Certificate.RotationThreshold)
+		require.Equal(t, 3650*24*time.Hour, cfg.Admission.Certificate.CAValidity)
+		require.Equal(t, 365*24*time.Hour, cfg.Admission.Certificate.CARotationThreshold)
+		require.Equal(t, "Fail", cfg.Admission.Webhooks.FailurePolicy)
+		require.Equal(t, &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "kubernetes.io/metadata.name", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kube-system"}},
+			},
+		}, cfg.Admission.Webhooks.NamespaceSelector.LabelSelector())
+		require.Equal(t, &metav1.LabelSelector{
+			MatchLabels: map[string]string{"warden.kyma-project.io/validate": "true"},
+		}, cfg.Admission.Webhooks.ObjectSelector.LabelSelector())
+		require.Equal(t, 15*time.Second, cfg.Admission.Webhooks.Defaulting.Timeout)
+		require.Equal(t, "Exact", cfg.Admission.Webhooks.Defaulting.MatchPolicy)
+		require.Equal(t, time.Second, cfg.Admission.Webhooks.Validation.Timeout)
+		require.Equal(t, "Equivalent", cfg.Admission.Webhooks.WorkloadValidation.MatchPolicy)
+		require.Equal(t, "otlp", cfg.Tracing.Exporter)
+		require.Equal(t, testTracingEndpoint, cfg.Tracing.Endpoint)
+		require.True(t, cfg.Tracing.Insecure)
+		require.Equal(t, float64(1), cfg.Tracing.SamplingRatio)
 	})
 
 	t.Run("Load test config from relative path", func(t *testing.T) {
@@ -50,4 +94,35 @@ func TestLoad(t *testing.T) {
 		require.Error(t, err)
 		require.Nil(t, cfg)
 	})
+
+	t.Run("Invalid notary URL error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("notary:\n  URL: http://notary.io\n"), 0600))
+
+		cfg, err := Load(path)
+		require.EqualError(t, err, `invalid config: notary URL "http://notary.io" must be an absolute https URL`)
+		require.Nil(t, cfg)
+	})
+
+	t.Run("Notary settings are not validated without the notary verifier", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("notary:\n  URL: \"\"\nverification:\n  verifier: notation\n"), 0600))
+
+		cfg, err := Load(path)
+		require.NoError(t, err)
+		require.Equal(t, "notation", cfg.Verification.Verifier)
+	})
+}
+
+func TestLabelSelector(t *testing.T) {
+	t.Run("not configured selector", func(t *testing.T) {
+		//GIVEN
+		var selector *labelSelector
+
+		//WHEN
+		converted := selector.LabelSelector()
+
+		//THEN
+		require.Nil(t, converted)
+	})
 }
@@ -1,18 +1,26 @@
 package config
 
 import (
+	"os"
+	"path"
+	"reflect"
+
 	"github.com/fsnotify/fsnotify"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
-	"os"
-	"path"
 )
 
-func Watch(filePath string, log *zap.SugaredLogger) error {
+// ReloadFunc applies the reloaded config, it returns an error if the config is invalid and nothing was applied
+type ReloadFunc func(config *Config) error
+
+// Watch reloads the config file when its content changes and calls reload with the new config,
+// the current config is kept if the file can't be loaded, its reloadable settings are invalid or reload rejects it
+func Watch(filePath string, current *Config, reload ReloadFunc, log *zap.SugaredLogger) error {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return errors.Wrap(err, "while creating file watcher")
 	}
+	startup := current
 	go func() {
 		defer func() {
 			watcher.Close()
@@ -21,13 +29,17 @@ func Watch(filePath string, log *zap.SugaredLogger) error {
 		for {
 			log.Debug("check file event")
 			select {
-			case event := <-watcher.Events:
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
 				log.Debugf("event name: %s, op: %s", event.Name, event.Op)
-				log.Info("Config changed, restarting")
-				os.Exit(0)
-			case watchErr := <-watcher.Errors:
+				current = reloadConfig(filePath, startup, current, reload, log)
+			case watchErr, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
 				log.Error(watchErr.Error())
-				os.Exit(1)
 			}
 		}
 	}()
@@ -40,3 +52,44 @@ func Watch(filePath string, log *zap.SugaredLogger) error {
 	}
 	return nil
 }
+
+// reloadConfig returns the reloaded config or the current config if the config file didn't change or it's invalid,
+// events of other files in the directory and intermediate events of the ConfigMap update don't change the config
+func reloadConfig(filePath string, startup, current *Config, reload ReloadFunc, log *zap.SugaredLogger) *Config {
+	// the file truncated before it's written again would be loaded as the default config
+	if info, err := os.Stat(filePath); err == nil && info.Size() == 0 {
+		log.Debug("config file is empty, keeping the current config")
+		return current
+	}
+	next, err := Load(filePath)
+	if err != nil {
+		log.Errorf("failed to load config, keeping the current config: %s", err.Error())
+		return current
+	}
+	if reflect.DeepEqual(current, next) {
+		return current
+	}
+	if err := reload(next); err != nil {
+		log.Errorf("invalid config, keeping the current config: %s", err.Error())
+		return current
+	}
+	log.Info("config reloaded")
+	if requiresRestart(startup, next) {
+		log.Warn("config contains changes which are applied after restart")
+	}
+	return next
+}
+
+// requiresRestart checks if configs differ in settings which are applied only at startup
+func requiresRestart(startup, next *Config) bool {
+	startupCopy, nextCopy := *startup, *next
+	for _, config := range []*Config{&startupCopy, &nextCopy} {
+		config.Logging.Level = ""
+		config.Notary.URL = ""
+		config.Notary.Timeout = 0
+		config.Notary.AllowedRegistries = ""
+		config.Admission.StrictMode = false
+		config.Admission.Timeout = 0
+	}
+	return !reflect.DeepEqual(startupCopy, nextCopy)
+}
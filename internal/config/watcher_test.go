@@ -0,0 +1,260 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testConfig = `
+logging:
+  level: info
+notary:
+  URL: https://notary.io
+`
+	testReloadedConfig = `
+logging:
+  level: debug
+notary:
+  URL: https://reloaded-notary.io
+`
+)
+
+func Test_reloadConfig(t *testing.T) {
+	log := zap.NewNop().Sugar()
+	current := loadTestConfig(t, testConfig)
+
+	t.Run("apply changed config", func(t *testing.T) {
+		//GIVEN
+		path := writeTestConfig(t, t.TempDir(), testReloadedConfig)
+		var reloaded *Config
+
+		//WHEN
+		next := reloadConfig(path, current, current, func(config *Config) error {
+			reloaded = config
+			return nil
+		}, log)
+
+		//THEN
+		require.Same(t, reloaded, next)
+		require.Equal(t, "debug", next.Logging.Level)
+		require.Equal(t, "https://reloaded-notary.io", next.Notary.URL)
+	})
+
+	t.Run("skip unchanged config", func(t *testing.T) {
+		//GIVEN
+		path := writeTestConfig(t, t.TempDir(), testConfig)
+
+		//WHEN
+		next := reloadConfig(path, current, current, func(config *Config) error {
+			require.Fail(t, "unchanged config must not be reloaded")
+			return nil
+		}, log)
+
+		//THEN
+		require.Same(t, current, next)
+	})
+
+	t.Run("keep current config if file can't be loaded", func(t *testing.T) {
+		//GIVEN
+		path := writeTestConfig(t, t.TempDir(), "logging: [")
+
+		//WHEN
+		next := reloadConfig(path, current, current, func(config *Config) error {
+			require.Fail(t, "invalid config must not be reloaded")
+			return nil
+		}, log)
+
+		//THEN
+		require.Same(t, current, next)
+	})
+
+	t.Run("keep current config if file is empty", func(t *testing.T) {
+		//GIVEN
+		path := writeTestConfig(t, t.TempDir(), "")
+
+		//WHEN
+		next := reloadConfig(path, current, current, func(config *Config) error {
+			require.Fail(t, "empty config must not be reloaded")
+			return nil
+		}, log)
+
+		//THEN
+		require.Same(t, current, next)
+	})
+
+	t.Run("keep current config if it's rejected", func(t *testing.T) {
+		//GIVEN
+		path := writeTestConfig(t, t.TempDir(), testReloadedConfig)
+
+		//WHEN
+		next := reloadConfig(path, current, current, func(config *Config) error {
+			return errors.New("invalid logger level")
+		}, log)
+
+		//THEN
+		require.Same(t, current, next)
+	})
+}
+
+func Test_reloadConfigRejectsInvalidConfig(t *testing.T) {
+	log := zap.NewNop().Sugar()
+	current := loadTestConfig(t, testConfig)
+
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name:    "invalid logger level",
+			config:  "logging:\n  level: verbose\nnotary:\n  URL: https://notary.io\n",
+			wantErr: "invalid logger level",
+		},
+		{
+			name:    "empty notary URL",
+			config:  "logging:\n  level: info\nnotary:\n  URL: \"\"\n",
+			wantErr: `notary URL "" must be an absolute https URL`,
+		},
+		{
+			name:    "unparsable notary URL",
+			config:  "logging:\n  level: info\nnotary:\n  URL: \"https://notary.io/%zz\"\n",
+			wantErr: "invalid notary URL",
+		},
+		{
+			name:    "http notary URL",
+			config:  "logging:\n  level: info\nnotary:\n  URL: http://notary.io\n",
+			wantErr: `notary URL "http://notary.io" must be an absolute https URL`,
+		},
+		{
+			name:    "zero notary timeout",
+			config:  "logging:\n  level: info\nnotary:\n  URL: https://notary.io\n  timeout: 0s\n",
+			wantErr: "notary timeout must be positive, got 0s",
+		},
+		{
+			name:    "negative notary timeout",
+			config:  "logging:\n  level: info\nnotary:\n  URL: https://notary.io\n  timeout: -1s\n",
+			wantErr: "notary timeout must be positive, got -1s",
+		},
+		{
+			name:    "empty notary URL with notary among verifiers",
+			config:  "logging:\n  level: info\nnotary:\n  URL: \"\"\nverification:\n  verifier: cosign,notary\n",
+			wantErr: `notary URL "" must be an absolute https URL`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			path := writeTestConfig(t, t.TempDir(), tt.config)
+
+			//WHEN
+			_, err := Load(path)
+			reloaded := reloadConfig(path, current, current, func(config *Config) error {
+				require.Fail(t, "invalid config must not be reloaded")
+				return nil
+			}, log)
+
+			//THEN
+			require.ErrorContains(t, err, tt.wantErr)
+			require.Same(t, current, reloaded)
+		})
+	}
+}
+
+func Test_reloadConfigIgnoresNotarySettingsOfOtherVerifiers(t *testing.T) {
+	//GIVEN
+	log := zap.NewNop().Sugar()
+	current := loadTestConfig(t, testConfig)
+	config := "logging:\n  level: info\nnotary:\n  URL: \"\"\n  timeout: 0s\nverification:\n  verifier: cosign\n"
+	path := writeTestConfig(t, t.TempDir(), config)
+	reloadedConfigs := 0
+
+	//WHEN
+	reloaded := reloadConfig(path, current, current, func(config *Config) error {
+		reloadedConfigs++
+		return nil
+	}, log)
+
+	//THEN
+	require.Equal(t, 1, reloadedConfigs)
+	require.Equal(t, "cosign", reloaded.Verification.Verifier)
+	require.Empty(t, reloaded.Notary.URL)
+}
+
+func Test_requiresRestart(t *testing.T) {
+	startup := loadTestConfig(t, testConfig)
+
+	t.Run("reloadable settings changed", func(t *testing.T) {
+		//GIVEN
+		next := loadTestConfig(t, testReloadedConfig)
+		next.Notary.Timeout = time.Minute
+		next.Notary.AllowedRegistries = "registry.io"
+		next.Admission.Timeout = time.Second * 5
+		next.Admission.StrictMode = true
+
+		//WHEN
+		restart := requiresRestart(startup, next)
+
+		//THEN
+		require.False(t, restart)
+	})
+
+	t.Run("startup settings changed", func(t *testing.T) {
+		//GIVEN
+		next := loadTestConfig(t, testConfig)
+		next.Admission.Port = 9443
+
+		//WHEN
+		restart := requiresRestart(startup, next)
+
+		//THEN
+		require.True(t, restart)
+	})
+}
+
+func TestWatch(t *testing.T) {
+	//GIVEN
+	dir := t.TempDir()
+	path := writeTestConfig(t, dir, testConfig)
+	current := loadTestConfig(t, testConfig)
+
+	var mutex sync.Mutex
+	var reloaded []*Config
+	require.NoError(t, Watch(path, current, func(config *Config) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		reloaded = append(reloaded, config)
+		return nil
+	}, zap.NewNop().Sugar()))
+
+	//WHEN
+	writeTestConfig(t, dir, testConfig)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.yaml"), []byte("other"), 0600))
+	writeTestConfig(t, dir, testReloadedConfig)
+
+	//THEN
+	require.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(reloaded) == 1 && reloaded[0].Notary.URL == "https://reloaded-notary.io"
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func writeTestConfig(t *testing.T, dir, content string) string {
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func loadTestConfig(t *testing.T, content string) *Config {
+	config, err := Load(writeTestConfig(t, t.TempDir(), content))
+	require.NoError(t, err)
+	return config
+}
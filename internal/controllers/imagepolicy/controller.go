@@ -0,0 +1,209 @@
+package imagepolicy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kyma-project/warden/api/v1alpha1"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ImagePolicyReconciler reports in the status whether the ImagePolicy can be used
+type ImagePolicyReconciler struct {
+	client.Client
+	Log *zap.SugaredLogger
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ImagePolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ImagePolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// created or deleted policy can change which policy in the namespace is used
+		Watches(&v1alpha1.ImagePolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.namespacePolicies),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc:  func(event.UpdateEvent) bool { return false },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+			})).
+		// created, updated or deleted notation secret changes whether policies referencing it can be used
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.secretPolicies)).
+		Complete(r)
+}
+
+//+kubebuilder:rbac:groups=warden.kyma-project.io,resources=imagepolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=warden.kyma-project.io,resources=imagepolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+func (r *ImagePolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.With("req", req)
+
+	var instance v1alpha1.ImagePolicy
+	if err := r.Get(ctx, req.NamespacedName, &instance); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var policies v1alpha1.ImagePolicyList
+	if err := r.List(ctx, &policies, client.InNamespace(instance.Namespace)); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "while fetching list of image policies")
+	}
+
+	var condition metav1.Condition
+	if first := validate.FirstImagePolicy(policies.Items); first != nil && first.Name != instance.Name {
+		condition = ignoredCondition(fmt.Sprintf("ImagePolicy %s is used in the namespace", first.Name))
+	} else {
+		condition = checkCondition(validate.CheckImagePolicy(ctx, r, instance.Namespace, &instance.Spec))
+	}
+
+	if !setStatus(&instance.Status, instance.Generation, condition) {
+		return ctrl.Result{}, nil
+	}
+	logger.With("reason", condition.Reason).Debug("updating image policy status")
+	return ctrl.Result{}, r.Status().Update(ctx, &instance)
+}
+
+func (r *ImagePolicyReconciler) namespacePolicies(ctx context.Context, obj client.Object) []reconcile.Request {
+	var policies v1alpha1.ImagePolicyList
+	if err := r.List(ctx, &policies, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Errorf("unable to fetch image policies: %s", err)
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, policy := range policies.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name},
+		})
+	}
+	return requests
+}
+
+func (r *ImagePolicyReconciler) secretPolicies(ctx context.Context, secret client.Object) []reconcile.Request {
+	var policies v1alpha1.ImagePolicyList
+	if err := r.List(ctx, &policies); err != nil {
+		r.Log.Errorf("unable to fetch image policies: %s", err)
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, policy := range policies.Items {
+		if referencesSecret(&policy.Spec, policy.Namespace, secret) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// ClusterImagePolicyReconciler reports in the status whether the ClusterImagePolicy can be used
+type ClusterImagePolicyReconciler struct {
+	client.Client
+	Log *zap.SugaredLogger
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterImagePolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ClusterImagePolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// created, updated or deleted notation secret changes whether policies referencing it can be used
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.secretPolicies)).
+		Complete(r)
+}
+
+//+kubebuilder:rbac:groups=warden.kyma-project.io,resources=clusterimagepolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=warden.kyma-project.io,resources=clusterimagepolicies/status,verbs=get;update;patch
+
+func (r *ClusterImagePolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.With("req", req)
+
+	var instance v1alpha1.ClusterImagePolicy
+	if err := r.Get(ctx, req.NamespacedName, &instance); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	condition := checkCondition(validate.CheckImagePolicy(ctx, r, "", &instance.Spec.ImagePolicySpec))
+	if !setStatus(&instance.Status, instance.Generation, condition) {
+		return ctrl.Result{}, nil
+	}
+	logger.With("reason", condition.Reason).Debug("updating cluster image policy status")
+	return ctrl.Result{}, r.Status().Update(ctx, &instance)
+}
+
+func (r *ClusterImagePolicyReconciler) secretPolicies(ctx context.Context, secret client.Object) []reconcile.Request {
+	var policies v1alpha1.ClusterImagePolicyList
+	if err := r.List(ctx, &policies); err != nil {
+		r.Log.Errorf("unable to fetch cluster image policies: %s", err)
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, policy := range policies.Items {
+		if referencesSecret(&policy.Spec.ImagePolicySpec, "", secret) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: policy.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// referencesSecret checks if the notation verifier of the policy uses the secret, the namespaced policy
+// references only secrets of its namespace, the namespace of the cluster policy is empty,
+// so it references only secrets with the namespace set
+func referencesSecret(spec *v1alpha1.ImagePolicySpec, policyNamespace string, secret client.Object) bool {
+	if spec.Notation == nil || spec.Notation.SecretName != secret.GetName() {
+		return false
+	}
+	secretNamespace := policyNamespace
+	if secretNamespace == "" {
+		secretNamespace = spec.Notation.SecretNamespace
+	}
+	return secretNamespace == secret.GetNamespace()
+}
+
+func checkCondition(err error) metav1.Condition {
+	if err != nil {
+		return metav1.Condition{
+			Type:    v1alpha1.ConditionTypeReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1alpha1.ConditionReasonInvalid,
+			Message: err.Error(),
+		}
+	}
+	return metav1.Condition{
+		Type:    v1alpha1.ConditionTypeReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  v1alpha1.ConditionReasonValid,
+		Message: "policy is used to verify images",
+	}
+}
+
+func ignoredCondition(message string) metav1.Condition {
+	return metav1.Condition{
+		Type:    v1alpha1.ConditionTypeReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  v1alpha1.ConditionReasonIgnored,
+		Message: message,
+	}
+}
+
+// setStatus returns true if the status was changed and has to be updated
+func setStatus(status *v1alpha1.ImagePolicyStatus, generation int64, condition metav1.Condition) bool {
+	condition.ObservedGeneration = generation
+	changed := meta.SetStatusCondition(&status.Conditions, condition)
+	if status.ObservedGeneration != generation {
+		status.ObservedGeneration = generation
+		changed = true
+	}
+	return changed
+}
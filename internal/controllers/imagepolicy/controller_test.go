@@ -0,0 +1,177 @@
+package imagepolicy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/warden/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestImagePolicyReconcile(t *testing.T) {
+	tests := []struct {
+		name       string
+		policies   []*v1alpha1.ImagePolicy
+		wantStatus metav1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name:       "valid policy",
+			policies:   []*v1alpha1.ImagePolicy{fixImagePolicy("policy", "https://notary")},
+			wantStatus: metav1.ConditionTrue,
+			wantReason: v1alpha1.ConditionReasonValid,
+		},
+		{
+			name:       "policy without notary URL",
+			policies:   []*v1alpha1.ImagePolicy{fixImagePolicy("policy", "")},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: v1alpha1.ConditionReasonInvalid,
+		},
+		{
+			name: "policy with notation secret of other namespace",
+			policies: []*v1alpha1.ImagePolicy{
+				fixNotationImagePolicy("user-ns", "policy", "notation", "kyma-system"),
+			},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: v1alpha1.ConditionReasonInvalid,
+		},
+		{
+			name: "policy ignored because of other policy in namespace",
+			policies: []*v1alpha1.ImagePolicy{
+				fixImagePolicy("policy", "https://notary"),
+				fixImagePolicy("first-policy", "https://notary"),
+			},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: v1alpha1.ConditionReasonIgnored,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			builder := fake.NewClientBuilder().
+				WithScheme(fixScheme(t)).
+				WithStatusSubresource(&v1alpha1.ImagePolicy{})
+			for _, policy := range tt.policies {
+				builder = builder.WithObjects(policy)
+			}
+			k8sClient := builder.Build()
+			r := &ImagePolicyReconciler{Client: k8sClient, Log: zap.NewNop().Sugar()}
+			key := types.NamespacedName{Namespace: "user-ns", Name: "policy"}
+
+			//WHEN
+			_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+
+			//THEN
+			require.NoError(t, err)
+			var policy v1alpha1.ImagePolicy
+			require.NoError(t, k8sClient.Get(context.TODO(), key, &policy))
+			condition := meta.FindStatusCondition(policy.Status.Conditions, v1alpha1.ConditionTypeReady)
+			require.NotNil(t, condition)
+			require.Equal(t, tt.wantStatus, condition.Status)
+			require.Equal(t, tt.wantReason, condition.Reason)
+			require.Equal(t, policy.Generation, policy.Status.ObservedGeneration)
+		})
+	}
+}
+
+func TestClusterImagePolicyReconcile(t *testing.T) {
+	//GIVEN
+	policy := &v1alpha1.ClusterImagePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy"},
+		Spec: v1alpha1.ClusterImagePolicySpec{
+			ImagePolicySpec: v1alpha1.ImagePolicySpec{
+				Verifiers: []v1alpha1.Verifier{v1alpha1.VerifierCosign},
+				Cosign:    &v1alpha1.CosignVerifier{PublicKeys: []string{"not a key"}},
+			},
+		},
+	}
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(fixScheme(t)).
+		WithStatusSubresource(&v1alpha1.ClusterImagePolicy{}).
+		WithObjects(policy).
+		Build()
+	r := &ClusterImagePolicyReconciler{Client: k8sClient, Log: zap.NewNop().Sugar()}
+
+	//WHEN
+	_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(policy)})
+
+	//THEN
+	require.NoError(t, err)
+	require.NoError(t, k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(policy), policy))
+	condition := meta.FindStatusCondition(policy.Status.Conditions, v1alpha1.ConditionTypeReady)
+	require.NotNil(t, condition)
+	require.Equal(t, metav1.ConditionFalse, condition.Status)
+	require.Equal(t, v1alpha1.ConditionReasonInvalid, condition.Reason)
+	require.Contains(t, condition.Message, "invalid cosign public keys")
+}
+
+func TestSecretPolicies(t *testing.T) {
+	//GIVEN
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "user-ns", Name: "notation"}}
+	sameNamespace := fixNotationImagePolicy("user-ns", "same-namespace", "notation", "")
+	otherNamespace := fixNotationImagePolicy("other-ns", "other-namespace", "notation", "user-ns")
+	otherSecret := fixNotationImagePolicy("user-ns", "other-secret", "other", "")
+	secretNotFromNamespace := fixNotationImagePolicy("other-ns", "secret-not-from-namespace", "notation", "")
+	clusterPolicy := &v1alpha1.ClusterImagePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-policy"},
+		Spec:       v1alpha1.ClusterImagePolicySpec{ImagePolicySpec: otherNamespace.Spec},
+	}
+	clusterPolicyWithoutNamespace := &v1alpha1.ClusterImagePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-policy-without-namespace"},
+		Spec:       v1alpha1.ClusterImagePolicySpec{ImagePolicySpec: sameNamespace.Spec},
+	}
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(fixScheme(t)).
+		WithObjects(sameNamespace, otherNamespace, otherSecret, secretNotFromNamespace, clusterPolicy, clusterPolicyWithoutNamespace).
+		Build()
+
+	//WHEN
+	requests := (&ImagePolicyReconciler{Client: k8sClient, Log: zap.NewNop().Sugar()}).secretPolicies(context.TODO(), secret)
+	clusterRequests := (&ClusterImagePolicyReconciler{Client: k8sClient, Log: zap.NewNop().Sugar()}).secretPolicies(context.TODO(), secret)
+
+	//THEN
+	// the namespaced policy can't reference secrets of other namespaces
+	require.ElementsMatch(t, []reconcile.Request{
+		{NamespacedName: client.ObjectKeyFromObject(sameNamespace)},
+	}, requests)
+	require.Equal(t, []reconcile.Request{
+		{NamespacedName: client.ObjectKeyFromObject(clusterPolicy)},
+	}, clusterRequests)
+}
+
+func fixScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+func fixImagePolicy(name, notaryURL string) *v1alpha1.ImagePolicy {
+	return &v1alpha1.ImagePolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "user-ns", Name: name},
+		Spec: v1alpha1.ImagePolicySpec{
+			Notary: &v1alpha1.NotaryVerifier{URL: notaryURL},
+		},
+	}
+}
+
+func fixNotationImagePolicy(namespace, name, secretName, secretNamespace string) *v1alpha1.ImagePolicy {
+	return &v1alpha1.ImagePolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: v1alpha1.ImagePolicySpec{
+			Verifiers: []v1alpha1.Verifier{v1alpha1.VerifierNotation},
+			Notation:  &v1alpha1.NotationVerifier{SecretName: secretName, SecretNamespace: secretNamespace},
+		},
+	}
+}
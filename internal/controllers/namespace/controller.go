@@ -3,8 +3,11 @@ package namespace
 import (
 	"context"
 
+	"github.com/kyma-project/warden/api/v1alpha1"
 	"github.com/kyma-project/warden/internal/validate"
 	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	"github.com/google/uuid"
@@ -26,16 +29,23 @@ type Reconciler struct {
 // SetupWithManager sets up the controller with the Manager.
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Namespace{}).
-		WithEventFilter(predicate.And(
+		For(&corev1.Namespace{}, builder.WithPredicates(
 			wardenPredicate(predicateOps{logger: r.Log}),
 		)).
+		// pods are validated again when the policy used by the namespace is changed
+		Watches(&v1alpha1.ImagePolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.imagePolicyToNamespace),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&v1alpha1.ClusterImagePolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.clusterImagePolicyToNamespaces),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
 
 //+kubebuilder:rbac:groups="",resources=pods,verbs=list;update
 //+kubebuilder:rbac:groups="",resources=namespaces,verbs=watch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list
+//+kubebuilder:rbac:groups=warden.kyma-project.io,resources=imagepolicies;clusterimagepolicies,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -0,0 +1,41 @@
+package namespace
+
+import (
+	"context"
+
+	"github.com/kyma-project/warden/internal/validate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// imagePolicyToNamespace enqueues the namespace of the changed ImagePolicy if it uses the user validation
+func (r *Reconciler) imagePolicyToNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	var ns corev1.Namespace
+	if err := r.Get(ctx, client.ObjectKey{Name: obj.GetNamespace()}, &ns); err != nil {
+		r.Log.With("namespace", obj.GetNamespace()).Debugf("unable to fetch namespace of image policy: %s", err)
+		return nil
+	}
+	if !validate.IsUserValidationForNS(&ns) {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: ns.Name}}}
+}
+
+// clusterImagePolicyToNamespaces enqueues all namespaces with the user validation,
+// because the changed ClusterImagePolicy could select or stop selecting any of them
+func (r *Reconciler) clusterImagePolicyToNamespaces(ctx context.Context, _ client.Object) []reconcile.Request {
+	var namespaces corev1.NamespaceList
+	if err := r.List(ctx, &namespaces); err != nil {
+		r.Log.Errorf("unable to fetch namespaces for cluster image policy: %s", err)
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range namespaces.Items {
+		if validate.IsUserValidationForNS(&namespaces.Items[i]) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: namespaces.Items[i].Name}})
+		}
+	}
+	return requests
+}
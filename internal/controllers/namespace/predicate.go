@@ -80,6 +80,10 @@ func userValidationAnnotationsUpdated(oldAnnotations, newAnnotations, newLabels
 		warden.NamespaceAllowedRegistriesAnnotation,
 		warden.NamespaceNotaryTimeoutAnnotation,
 		warden.NamespaceStrictModeAnnotation,
+		warden.NamespaceVerifierAnnotation,
+		warden.NamespaceCosignPublicKeysAnnotation,
+		warden.NamespaceNotationSecretAnnotation,
+		warden.NamespaceVerificationModeAnnotation,
 	} {
 		oldValue := oldAnnotations[key]
 		newValue := newAnnotations[key]
@@ -22,13 +22,17 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/kyma-project/warden/internal/events"
 	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/logging/tracing"
 
 	"github.com/kyma-project/warden/internal/validate"
 	"github.com/kyma-project/warden/pkg"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -46,19 +50,21 @@ type PodReconciler struct {
 	scheme                   *runtime.Scheme
 	systemValidator          validate.PodValidator
 	userValidationSvcFactory validate.ValidatorSvcFactory
+	recorder                 record.EventRecorder
 	baseLogger               *zap.SugaredLogger
 	PodReconcilerConfig
 }
 
 func NewPodReconciler(client client.Client, reader client.Reader, scheme *runtime.Scheme,
 	validator validate.PodValidator, userValidationSvcFactory validate.ValidatorSvcFactory,
-	reconcileCfg PodReconcilerConfig, logger *zap.SugaredLogger) *PodReconciler {
+	reconcileCfg PodReconcilerConfig, recorder record.EventRecorder, logger *zap.SugaredLogger) *PodReconciler {
 	return &PodReconciler{
 		client:                   client,
 		reader:                   reader,
 		scheme:                   scheme,
 		systemValidator:          validator,
 		userValidationSvcFactory: userValidationSvcFactory,
+		recorder:                 recorder,
 		baseLogger:               logger,
 		PodReconcilerConfig:      reconcileCfg,
 	}
@@ -105,10 +111,18 @@ func (r *PodReconciler) SetupWithManager(mgr ctrl.Manager) error {
 //+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;update
 //+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch
+//+kubebuilder:rbac:groups=warden.kyma-project.io,resources=imagepolicies;clusterimagepolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	ctx, span := tracing.StartSpan(ctx, "PodReconciler.Reconcile",
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("k8s.pod.name", req.Name))
+	defer func() { tracing.EndSpan(span, err) }()
+
 	reqUUID := uuid.New().String()
 	logger := r.baseLogger.With("req", req).With("req-id", reqUUID)
 	ctxLogger := helpers.LoggerToContext(ctx, logger)
@@ -119,11 +133,14 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	result, err := r.checkPod(ctxLogger, &pod)
+	validationResult, err := r.checkPod(ctxLogger, &pod)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	events.RecordValidationResult(r.recorder, &pod, validationResult)
 
+	result := validationResult.Status
+	span.SetAttributes(attribute.String("warden.validation.status", string(result)))
 	shouldRetry := ctrl.Result{RequeueAfter: r.RequeueAfter}
 	switch result {
 	case validate.Valid:
@@ -132,6 +149,9 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	case validate.Invalid:
 		logger.Info("pod validation failed")
 		shouldRetry = ctrl.Result{}
+	case validate.NoAction:
+		logger.Info("pod validation skipped")
+		shouldRetry = ctrl.Result{}
 	}
 	if err := r.labelPod(ctx, pod, result); err != nil {
 		logger.Info("pod labeling failed ", "err", err.Error())
@@ -140,32 +160,34 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	return shouldRetry, nil
 }
 
-func (r *PodReconciler) checkPod(ctx context.Context, pod *corev1.Pod) (validate.ValidationStatus, error) {
+func (r *PodReconciler) checkPod(ctx context.Context, pod *corev1.Pod) (validate.ValidationResult, error) {
+	noAction := validate.ValidationResult{Status: validate.NoAction}
+
 	var ns corev1.Namespace
 	if err := r.client.Get(ctx, client.ObjectKey{Name: pod.Namespace}, &ns); err != nil {
-		return validate.NoAction, err
+		return noAction, err
 	}
 
 	validator := r.systemValidator
 	if validate.IsUserValidationForNS(&ns) {
 		var err error
-		validator, err = validate.NewUserValidationSvc(&ns, r.userValidationSvcFactory)
+		validator, err = validate.NewUserValidationSvc(ctx, r.client, &ns, r.userValidationSvcFactory)
 		if err != nil {
-			return validate.NoAction, err
+			return noAction, err
 		}
 	}
 
 	imagePullCredentials, err := helpers.GetRemotePullCredentials(ctx, r.client, pod)
 	if err != nil {
-		return validate.NoAction, err
+		return noAction, err
 	}
 
 	result, err := validator.ValidatePod(ctx, pod, &ns, imagePullCredentials)
 	if err != nil {
-		return validate.NoAction, err
+		return noAction, err
 	}
 
-	return result.Status, nil
+	return result, nil
 }
 
 func (r *PodReconciler) labelPod(ctx context.Context, pod corev1.Pod, result validate.ValidationStatus) error {
@@ -211,6 +233,9 @@ func getPodImages(pod *corev1.Pod) []string {
 	for _, container := range pod.Spec.Containers {
 		result = append(result, container.Image)
 	}
+	for _, container := range pod.Spec.EphemeralContainers {
+		result = append(result, container.Image)
+	}
 	return result
 }
 
@@ -20,6 +20,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -54,7 +55,7 @@ func Test_PodReconcile(t *testing.T) {
 	testLogger := test_helpers.NewTestZapLogger(t)
 	ctrl := NewPodReconciler(k8sClient, k8sClient, scheme.Scheme, podValidator, nil, PodReconcilerConfig{
 		RequeueAfter: requeueTime,
-	}, testLogger.Sugar())
+	}, &record.FakeRecorder{}, testLogger.Sugar())
 
 	testCases := []struct {
 		name           string
@@ -185,7 +186,7 @@ func Test_PodReconcileForSystemOrUserValidation(t *testing.T) {
 			}
 
 			ctrl := NewPodReconciler(k8sClient, k8sClient, scheme.Scheme, systemPodValidator, userValidatorFactory,
-				PodReconcilerConfig{RequeueAfter: requeueTime}, testLogger.Sugar())
+				PodReconcilerConfig{RequeueAfter: requeueTime}, &record.FakeRecorder{}, testLogger.Sugar())
 
 			//WHEN
 			res, err := ctrl.Reconcile(context.TODO(), req)
@@ -219,7 +220,7 @@ func Test_PodReconcileForSystemOrUserValidation(t *testing.T) {
 		defer userValidator.AssertExpectations(t)
 
 		userValidatorFactory := mocks.NewValidatorSvcFactory(t)
-		userValidatorFactory.On("NewValidatorSvc", mock.Anything, mock.Anything, mock.Anything).
+		userValidatorFactory.On("NewValidatorSvc", mock.Anything).
 			Return(userValidator).Once()
 		defer userValidatorFactory.AssertExpectations(t)
 
@@ -244,7 +245,7 @@ func Test_PodReconcileForSystemOrUserValidation(t *testing.T) {
 		}
 
 		ctrl := NewPodReconciler(k8sClient, k8sClient, scheme.Scheme, systemPodValidator, userValidatorFactory,
-			PodReconcilerConfig{RequeueAfter: requeueTime}, testLogger.Sugar())
+			PodReconcilerConfig{RequeueAfter: requeueTime}, &record.FakeRecorder{}, testLogger.Sugar())
 
 		//WHEN
 		res, err := ctrl.Reconcile(context.TODO(), req)
@@ -316,7 +317,7 @@ func TestReconcile_K8sOperationFails(t *testing.T) {
 
 		ctrl := NewPodReconciler(mockK8Client, mockK8Client, scheme.Scheme, podValidator, nil, PodReconcilerConfig{
 			RequeueAfter: requeueTime,
-		}, testLogger.Sugar())
+		}, &record.FakeRecorder{}, testLogger.Sugar())
 		req := reconcile.Request{NamespacedName: types.NamespacedName{
 			Namespace: validatableNs,
 			Name:      pod.Name},
@@ -445,6 +446,21 @@ func Test_areImagesChanged(t *testing.T) {
 			require.Equal(t, tt.want, got)
 		})
 	}
+
+	t.Run("added ephemeral container image", func(t *testing.T) {
+		//GIVEN
+		oldPod := fixPod(nil, []corev1.Container{{Image: "image-mike", Name: "container-mike"}})
+		newPod := oldPod.DeepCopy()
+		newPod.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+			{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Image: "image-debug", Name: "debugger"}},
+		}
+
+		//WHEN
+		got := areImagesChanged(oldPod, newPod)
+
+		//THEN
+		require.True(t, got)
+	})
 }
 
 func fixPod(initContainers []corev1.Container, containers []corev1.Container) *corev1.Pod {
@@ -0,0 +1,81 @@
+package credentialprovider
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configKind       = "CredentialProviderConfig"
+	requestKind      = "CredentialProviderRequest"
+	responseKind     = "CredentialProviderResponse"
+	apiVersionV1     = "credentialprovider.kubelet.k8s.io/v1"
+	apiVersionBeta1  = "credentialprovider.kubelet.k8s.io/v1beta1"
+	apiVersionAlpha1 = "credentialprovider.kubelet.k8s.io/v1alpha1"
+)
+
+// Config is the kubelet CredentialProviderConfig, so the same file can be used by kubelet and warden
+type Config struct {
+	Kind      string           `yaml:"kind"`
+	Providers []ProviderConfig `yaml:"providers"`
+}
+
+type ProviderConfig struct {
+	// Name is the name of the provider binary in the bin directory
+	Name                 string        `yaml:"name"`
+	MatchImages          []string      `yaml:"matchImages"`
+	DefaultCacheDuration time.Duration `yaml:"defaultCacheDuration"`
+	APIVersion           string        `yaml:"apiVersion"`
+	Args                 []string      `yaml:"args"`
+	Env                  []EnvVar      `yaml:"env"`
+}
+
+type EnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+func LoadConfig(path string) (*Config, error) {
+	sanitizedPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	configFile, err := os.ReadFile(sanitizedPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read credential provider config")
+	}
+
+	config := &Config{}
+	if err := yaml.Unmarshal(configFile, config); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal credential provider config")
+	}
+	return config, validateConfig(config)
+}
+
+func validateConfig(config *Config) error {
+	if config.Kind != configKind {
+		return errors.Errorf("unexpected credential provider config kind: %s", config.Kind)
+	}
+	for _, provider := range config.Providers {
+		if provider.Name == "" {
+			return errors.New("credential provider name is required")
+		}
+		if filepath.Base(provider.Name) != provider.Name {
+			return errors.Errorf("credential provider name %s must not be a path", provider.Name)
+		}
+		if len(provider.MatchImages) == 0 {
+			return errors.Errorf("credential provider %s has no matchImages", provider.Name)
+		}
+		switch provider.APIVersion {
+		case apiVersionV1, apiVersionBeta1, apiVersionAlpha1:
+		default:
+			return errors.Errorf("credential provider %s has unsupported apiVersion: %s", provider.Name, provider.APIVersion)
+		}
+	}
+	return nil
+}
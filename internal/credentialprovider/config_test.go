@@ -0,0 +1,101 @@
+package credentialprovider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("load kubelet credential provider config", func(t *testing.T) {
+		//WHEN
+		config, err := LoadConfig("testData/config.yaml")
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, &Config{
+			Kind: "CredentialProviderConfig",
+			Providers: []ProviderConfig{
+				{
+					Name:                 "ecr-credential-provider",
+					MatchImages:          []string{"*.dkr.ecr.*.amazonaws.com", "*.dkr.ecr.*.amazonaws.com.cn"},
+					DefaultCacheDuration: 12 * time.Hour,
+					APIVersion:           "credentialprovider.kubelet.k8s.io/v1",
+					Args:                 []string{"get-credentials"},
+					Env:                  []EnvVar{{Name: "AWS_PROFILE", Value: "warden"}},
+				},
+			},
+		}, config)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		//WHEN
+		config, err := LoadConfig("testData/missing.yaml")
+
+		//THEN
+		require.ErrorContains(t, err, "failed to read credential provider config")
+		require.Nil(t, config)
+	})
+
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name:    "wrong kind",
+			config:  "kind: KubeletConfiguration",
+			wantErr: "unexpected credential provider config kind: KubeletConfiguration",
+		},
+		{
+			name: "missing name",
+			config: `kind: CredentialProviderConfig
+providers:
+  - matchImages: ["registry.io"]
+    apiVersion: credentialprovider.kubelet.k8s.io/v1`,
+			wantErr: "credential provider name is required",
+		},
+		{
+			name: "name with path",
+			config: `kind: CredentialProviderConfig
+providers:
+  - name: ../bin/provider
+    matchImages: ["registry.io"]
+    apiVersion: credentialprovider.kubelet.k8s.io/v1`,
+			wantErr: "credential provider name ../bin/provider must not be a path",
+		},
+		{
+			name: "missing matchImages",
+			config: `kind: CredentialProviderConfig
+providers:
+  - name: provider
+    apiVersion: credentialprovider.kubelet.k8s.io/v1`,
+			wantErr: "credential provider provider has no matchImages",
+		},
+		{
+			name: "unsupported apiVersion",
+			config: `kind: CredentialProviderConfig
+providers:
+  - name: provider
+    matchImages: ["registry.io"]
+    apiVersion: credentialprovider.kubelet.k8s.io/v2`,
+			wantErr: "credential provider provider has unsupported apiVersion: credentialprovider.kubelet.k8s.io/v2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.config), 0600))
+
+			//WHEN
+			_, err := LoadConfig(path)
+
+			//THEN
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package credentialprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	cacheKeyTypeImage    = "Image"
+	cacheKeyTypeRegistry = "Registry"
+	cacheKeyTypeGlobal   = "Global"
+
+	globalCacheKey = "global"
+)
+
+type request struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Image      string `json:"image"`
+}
+
+type response struct {
+	Kind          string                  `json:"kind"`
+	APIVersion    string                  `json:"apiVersion"`
+	CacheKeyType  string                  `json:"cacheKeyType"`
+	CacheDuration *metav1.Duration        `json:"cacheDuration,omitempty"`
+	Auth          map[string]authResponse `json:"auth"`
+}
+
+type authResponse struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Providers execute kubelet credential provider plugins to get registry credentials of images
+// which can't be read with image pull secrets, e.g. images from ECR, GCR or ACR
+type Providers struct {
+	providers []*provider
+}
+
+type provider struct {
+	ProviderConfig
+	binPath string
+
+	mutex sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	credentials helpers.RegistryCredentials
+	expiresAt   time.Time
+}
+
+// New creates providers from the kubelet credential provider config, binaries are executed from the binDir
+func New(config *Config, binDir string) (*Providers, error) {
+	providers := &Providers{}
+	for _, providerConfig := range config.Providers {
+		binPath := filepath.Join(binDir, providerConfig.Name)
+		if _, err := os.Stat(binPath); err != nil {
+			return nil, errors.Wrapf(err, "credential provider %s binary not found", providerConfig.Name)
+		}
+		providers.providers = append(providers.providers, &provider{
+			ProviderConfig: providerConfig,
+			binPath:        binPath,
+			cache:          map[string]cacheEntry{},
+		})
+	}
+	return providers, nil
+}
+
+// Provide returns credentials of all providers which match the image, errors of providers are logged and skipped,
+// the same way as kubelet pulls the image without credentials of the failed provider
+func (p *Providers) Provide(ctx context.Context, ref name.Reference) helpers.RegistryCredentials {
+	repository := helpers.KubeletRepository(ref)
+
+	credentials := helpers.RegistryCredentials{}
+	for _, provider := range p.providers {
+		if !provider.matches(repository) {
+			continue
+		}
+		providerCredentials, err := provider.provide(ctx, repository)
+		if err != nil {
+			helpers.LoggerFromCtx(ctx).With("provider", provider.Name).
+				Warnf("failed to get credentials from credential provider: %s", err.Error())
+			continue
+		}
+		for key, auths := range providerCredentials {
+			for _, auth := range auths {
+				credentials.Add(key, auth)
+			}
+		}
+	}
+	return credentials
+}
+
+func (p *provider) matches(repository string) bool {
+	for _, matchImage := range p.MatchImages {
+		if helpers.RegistryKeyMatches(matchImage, repository) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *provider) provide(ctx context.Context, repository string) (helpers.RegistryCredentials, error) {
+	if credentials, ok := p.getCached(repository); ok {
+		return credentials, nil
+	}
+
+	resp, err := p.exec(ctx, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := helpers.RegistryCredentials{}
+	for _, key := range slices.Sorted(maps.Keys(resp.Auth)) {
+		credentials.Add(key, cliType.AuthConfig{
+			Username: resp.Auth[key].Username,
+			Password: resp.Auth[key].Password,
+		})
+	}
+
+	cacheDuration := p.DefaultCacheDuration
+	if resp.CacheDuration != nil {
+		cacheDuration = resp.CacheDuration.Duration
+	}
+	if cacheDuration > 0 {
+		p.setCached(cacheKey(resp.CacheKeyType, repository), credentials, cacheDuration)
+	}
+	return credentials, nil
+}
+
+func (p *provider) exec(ctx context.Context, repository string) (*response, error) {
+	req, err := json.Marshal(request{Kind: requestKind, APIVersion: p.APIVersion, Image: repository})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal credential provider request")
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, p.binPath, p.Args...)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = os.Environ()
+	for _, env := range p.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "credential provider failed: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	resp := &response{}
+	if err := json.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal credential provider response")
+	}
+	if resp.Kind != responseKind {
+		return nil, errors.Errorf("unexpected credential provider response kind: %s", resp.Kind)
+	}
+	if resp.APIVersion != p.APIVersion {
+		return nil, errors.Errorf("credential provider response apiVersion %s doesn't match the config apiVersion %s", resp.APIVersion, p.APIVersion)
+	}
+	switch resp.CacheKeyType {
+	case cacheKeyTypeImage, cacheKeyTypeRegistry, cacheKeyTypeGlobal:
+	default:
+		return nil, errors.Errorf("unsupported credential provider cacheKeyType: %s", resp.CacheKeyType)
+	}
+	return resp, nil
+}
+
+// getCached looks for credentials cached for the image, its registry and for all images
+func (p *provider) getCached(repository string) (helpers.RegistryCredentials, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	for _, keyType := range []string{cacheKeyTypeImage, cacheKeyTypeRegistry, cacheKeyTypeGlobal} {
+		key := cacheKey(keyType, repository)
+		entry, ok := p.cache[key]
+		if !ok {
+			continue
+		}
+		if now.After(entry.expiresAt) {
+			delete(p.cache, key)
+			continue
+		}
+		return entry.credentials, true
+	}
+	return nil, false
+}
+
+func (p *provider) setCached(key string, credentials helpers.RegistryCredentials, duration time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.cache[key] = cacheEntry{credentials: credentials, expiresAt: time.Now().Add(duration)}
+}
+
+func cacheKey(keyType, repository string) string {
+	switch keyType {
+	case cacheKeyTypeRegistry:
+		registry, _, _ := strings.Cut(repository, "/")
+		return cacheKeyTypeRegistry + "/" + registry
+	case cacheKeyTypeGlobal:
+		return globalCacheKey
+	default:
+		return cacheKeyTypeImage + "/" + repository
+	}
+}
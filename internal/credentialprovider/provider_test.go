@@ -0,0 +1,234 @@
+package credentialprovider
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeProviderScript saves every request to the REQUESTS_FILE and prints the RESPONSE,
+// so tests can check how many times the provider was executed
+const fakeProviderScript = `#!/bin/sh
+cat >> "$REQUESTS_FILE"
+echo >> "$REQUESTS_FILE"
+if [ -n "$FAIL" ]; then
+  echo "$FAIL" >&2
+  exit 1
+fi
+printf '%s' "$RESPONSE"
+`
+
+const testAPIVersion = "credentialprovider.kubelet.k8s.io/v1"
+
+func TestProviders_Provide(t *testing.T) {
+	ctx := helpers.LoggerToContext(context.Background(), zap.NewNop().Sugar())
+
+	t.Run("provide credentials of matching image", func(t *testing.T) {
+		//GIVEN
+		provider := newFakeProvider(t, ProviderConfig{MatchImages: []string{"*.registry.io", "docker.io/org"}},
+			fixResponse("Image", "", "*.registry.io", "docker.io/org"))
+		providers := newTestProviders(t, provider)
+
+		//WHEN
+		credentials := providers.Provide(ctx, name.MustParseReference("eu.registry.io/app:1.0"))
+		dockerHubCredentials := providers.Provide(ctx, name.MustParseReference("org/app:1.0"))
+
+		//THEN
+		require.Equal(t, helpers.RegistryCredentials{
+			"*.registry.io": {{Username: "user", Password: "*.registry.io-password"}},
+			"docker.io/org": {{Username: "user", Password: "docker.io/org-password"}},
+		}, credentials)
+		require.Equal(t, credentials, dockerHubCredentials)
+		require.Equal(t, []request{
+			{Kind: "CredentialProviderRequest", APIVersion: testAPIVersion, Image: "eu.registry.io/app"},
+			{Kind: "CredentialProviderRequest", APIVersion: testAPIVersion, Image: "docker.io/org/app"},
+		}, provider.requests(t))
+	})
+
+	t.Run("skip provider which doesn't match image", func(t *testing.T) {
+		//GIVEN
+		provider := newFakeProvider(t, ProviderConfig{MatchImages: []string{"*.registry.io"}},
+			fixResponse("Image", "", "*.registry.io"))
+		providers := newTestProviders(t, provider)
+
+		//WHEN
+		credentials := providers.Provide(ctx, name.MustParseReference("registry.io:5000/app:1.0"))
+
+		//THEN
+		require.Empty(t, credentials)
+		require.Empty(t, provider.requests(t))
+	})
+
+	t.Run("cache credentials for the response cacheDuration", func(t *testing.T) {
+		//GIVEN
+		provider := newFakeProvider(t, ProviderConfig{MatchImages: []string{"registry.io"}},
+			fixResponse("Registry", "1m0s", "registry.io"))
+		providers := newTestProviders(t, provider)
+
+		//WHEN
+		first := providers.Provide(ctx, name.MustParseReference("registry.io/app:1.0"))
+		second := providers.Provide(ctx, name.MustParseReference("registry.io/other:1.0"))
+
+		//THEN
+		require.Equal(t, first, second)
+		require.Len(t, provider.requests(t), 1)
+	})
+
+	t.Run("cache credentials by the image", func(t *testing.T) {
+		//GIVEN
+		provider := newFakeProvider(t, ProviderConfig{MatchImages: []string{"registry.io"}},
+			fixResponse("Image", "1m0s", "registry.io"))
+		providers := newTestProviders(t, provider)
+
+		//WHEN
+		providers.Provide(ctx, name.MustParseReference("registry.io/app:1.0"))
+		providers.Provide(ctx, name.MustParseReference("registry.io/app:2.0"))
+		providers.Provide(ctx, name.MustParseReference("registry.io/other:1.0"))
+
+		//THEN
+		require.Len(t, provider.requests(t), 2)
+	})
+
+	t.Run("cache credentials for the defaultCacheDuration", func(t *testing.T) {
+		//GIVEN
+		provider := newFakeProvider(t, ProviderConfig{MatchImages: []string{"registry.io"}, DefaultCacheDuration: time.Minute},
+			fixResponse("Global", "", "registry.io"))
+		providers := newTestProviders(t, provider)
+
+		//WHEN
+		providers.Provide(ctx, name.MustParseReference("registry.io/app:1.0"))
+		providers.Provide(ctx, name.MustParseReference("registry.io/other:1.0"))
+
+		//THEN
+		require.Len(t, provider.requests(t), 1)
+	})
+
+	t.Run("don't cache credentials with zero cacheDuration", func(t *testing.T) {
+		//GIVEN
+		provider := newFakeProvider(t, ProviderConfig{MatchImages: []string{"registry.io"}, DefaultCacheDuration: time.Minute},
+			fixResponse("Global", "0s", "registry.io"))
+		providers := newTestProviders(t, provider)
+
+		//WHEN
+		providers.Provide(ctx, name.MustParseReference("registry.io/app:1.0"))
+		providers.Provide(ctx, name.MustParseReference("registry.io/app:1.0"))
+
+		//THEN
+		require.Len(t, provider.requests(t), 2)
+	})
+
+	t.Run("skip failed provider", func(t *testing.T) {
+		//GIVEN
+		failed := newFakeProvider(t, ProviderConfig{MatchImages: []string{"registry.io"}}, "")
+		failed.env("FAIL", "no credentials")
+		invalid := newFakeProvider(t, ProviderConfig{MatchImages: []string{"registry.io"}}, `{"kind":"Unknown"}`)
+		working := newFakeProvider(t, ProviderConfig{MatchImages: []string{"registry.io"}},
+			fixResponse("Image", "", "registry.io"))
+		providers := newTestProviders(t, failed, invalid, working)
+
+		//WHEN
+		credentials := providers.Provide(ctx, name.MustParseReference("registry.io/app:1.0"))
+
+		//THEN
+		require.Equal(t, helpers.RegistryCredentials{
+			"registry.io": {{Username: "user", Password: "registry.io-password"}},
+		}, credentials)
+		require.Len(t, failed.requests(t), 1)
+		require.Len(t, invalid.requests(t), 1)
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("missing provider binary", func(t *testing.T) {
+		//GIVEN
+		config := &Config{Providers: []ProviderConfig{{Name: "missing"}}}
+
+		//WHEN
+		providers, err := New(config, t.TempDir())
+
+		//THEN
+		require.ErrorContains(t, err, "credential provider missing binary not found")
+		require.Nil(t, providers)
+	})
+}
+
+type fakeProvider struct {
+	config       ProviderConfig
+	binDir       string
+	requestsFile string
+}
+
+func newFakeProvider(t *testing.T, config ProviderConfig, response string) *fakeProvider {
+	binDir := t.TempDir()
+	config.Name = "fake-provider"
+	config.APIVersion = testAPIVersion
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, config.Name), []byte(fakeProviderScript), 0700))
+
+	provider := &fakeProvider{
+		config:       config,
+		binDir:       binDir,
+		requestsFile: filepath.Join(binDir, "requests"),
+	}
+	provider.env("REQUESTS_FILE", provider.requestsFile)
+	provider.env("RESPONSE", response)
+	return provider
+}
+
+func (p *fakeProvider) env(name, value string) {
+	p.config.Env = append(p.config.Env, EnvVar{Name: name, Value: value})
+}
+
+func (p *fakeProvider) requests(t *testing.T) []request {
+	data, err := os.ReadFile(p.requestsFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+
+	var requests []request
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		req := request{}
+		require.NoError(t, json.Unmarshal([]byte(line), &req))
+		requests = append(requests, req)
+	}
+	return requests
+}
+
+// newTestProviders creates providers with binaries in different directories, so every provider has its own script
+func newTestProviders(t *testing.T, fakeProviders ...*fakeProvider) *Providers {
+	providers := &Providers{}
+	for _, fake := range fakeProviders {
+		created, err := New(&Config{Providers: []ProviderConfig{fake.config}}, fake.binDir)
+		require.NoError(t, err)
+		providers.providers = append(providers.providers, created.providers...)
+	}
+	return providers
+}
+
+func fixResponse(cacheKeyType, cacheDuration string, registries ...string) string {
+	auth := map[string]cliType.AuthConfig{}
+	for _, registry := range registries {
+		auth[registry] = cliType.AuthConfig{Username: "user", Password: registry + "-password"}
+	}
+	resp := map[string]interface{}{
+		"kind":         "CredentialProviderResponse",
+		"apiVersion":   testAPIVersion,
+		"cacheKeyType": cacheKeyType,
+		"auth":         auth,
+	}
+	if cacheDuration != "" {
+		resp["cacheDuration"] = cacheDuration
+	}
+	data, _ := json.Marshal(resp)
+	return string(data)
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"github.com/kyma-project/warden/internal/validate"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons of events recorded for validation outcomes
+const (
+	ReasonImageSignatureInvalid  = validate.ReasonImageSignatureInvalid
+	ReasonNotaryUnavailable      = validate.ReasonNotaryUnavailable
+	ReasonImageNotAllowed        = validate.ReasonImageNotAllowed
+	ReasonPodRejected            = "PodRejected"
+	ReasonPodAdmittedWithWarning = "PodAdmittedWithWarning"
+)
+
+// RecordValidationResult records the warning event for every image which failed the validation,
+// messages don't contain the pod name, so the event recorder aggregates events of pods created by the same workload
+func RecordValidationResult(recorder record.EventRecorder, pod *corev1.Pod, result validate.ValidationResult) {
+	if result.Status != validate.Invalid && result.Status != validate.ServiceUnavailable {
+		return
+	}
+	object := involvedObject(pod)
+	if object == nil {
+		return
+	}
+
+	if len(result.InvalidImages) == 0 {
+		recorder.Event(object, corev1.EventTypeWarning, validate.FailureReasonForStatus(result.Status), "Pod images couldn't be validated")
+		return
+	}
+	for _, image := range result.InvalidImages {
+		err := result.ImageErrors[image]
+		if err == nil {
+			recorder.Eventf(object, corev1.EventTypeWarning, validate.FailureReasonForStatus(result.Status), "Image %s validation failed", image)
+			continue
+		}
+		recorder.Eventf(object, corev1.EventTypeWarning, validate.FailureReason(err), "Image %s validation failed: %s", image, err.Error())
+	}
+}
+
+// RecordPodRejected records the event of the pod rejected, or admitted with the warning, by the validation webhook
+func RecordPodRejected(recorder record.EventRecorder, pod *corev1.Pod, warnMode bool, message string) {
+	object := involvedObject(pod)
+	if object == nil {
+		return
+	}
+	reason := ReasonPodRejected
+	if warnMode {
+		reason = ReasonPodAdmittedWithWarning
+	}
+	recorder.Event(object, corev1.EventTypeWarning, reason, message)
+}
+
+// involvedObject returns the pod, or its controller if the pod is being created and doesn't exist yet,
+// e.g. the ReplicaSet, so app teams can find events of pods which were never admitted
+func involvedObject(pod *corev1.Pod) runtime.Object {
+	if pod.UID != "" {
+		return pod
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta: metav1.TypeMeta{APIVersion: owner.APIVersion, Kind: owner.Kind},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: pod.Namespace,
+				Name:      owner.Name,
+				UID:       owner.UID,
+			},
+		}
+	}
+	if pod.Name != "" {
+		return pod
+	}
+	// the pod created with the generated name and without the owner can't be referenced
+	return nil
+}
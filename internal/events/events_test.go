@@ -0,0 +1,137 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/kyma-project/warden/internal/events"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+)
+
+func TestRecordValidationResult(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod", UID: "pod-uid"}}
+
+	tests := []struct {
+		name       string
+		result     validate.ValidationResult
+		wantEvents []string
+	}{
+		{
+			name:   "valid pod",
+			result: validate.ValidationResult{Status: validate.Valid},
+		},
+		{
+			name:   "skipped pod",
+			result: validate.ValidationResult{Status: validate.NoAction},
+		},
+		{
+			name: "event for every invalid image",
+			result: validate.ValidationResult{
+				Status:        validate.Invalid,
+				InvalidImages: []string{"image-a", "image-b", "image-c"},
+				ImageErrors: map[string]error{
+					"image-a": pkg.NewValidationFailedErr(errors.New("image is not signed")),
+					"image-b": pkg.NewUnknownResultErr(errors.New("connection refused")),
+					"image-c": pkg.NewImageNotAllowedErr(errors.New("no notation trust policy applies to image")),
+				},
+			},
+			wantEvents: []string{
+				"Warning ImageSignatureInvalid Image image-a validation failed: notary validation error: image is not signed",
+				"Warning NotaryUnavailable Image image-b validation failed: notary service unknown error: connection refused",
+				"Warning ImageNotAllowed Image image-c validation failed: notary validation error: image is not allowed: no notation trust policy applies to image",
+			},
+		},
+		{
+			name: "invalid image without error",
+			result: validate.ValidationResult{
+				Status:        validate.Invalid,
+				InvalidImages: []string{"image-a"},
+			},
+			wantEvents: []string{"Warning ImageSignatureInvalid Image image-a validation failed"},
+		},
+		{
+			name:       "images not validated",
+			result:     validate.ValidationResult{Status: validate.ServiceUnavailable},
+			wantEvents: []string{"Warning NotaryUnavailable Pod images couldn't be validated"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			recorder := record.NewFakeRecorder(10)
+
+			//WHEN
+			events.RecordValidationResult(recorder, pod, tt.result)
+
+			//THEN
+			close(recorder.Events)
+			var gotEvents []string
+			for event := range recorder.Events {
+				gotEvents = append(gotEvents, event)
+			}
+			require.Equal(t, tt.wantEvents, gotEvents)
+		})
+	}
+}
+
+func TestRecordPodRejected(t *testing.T) {
+	invalidResult := validate.ValidationResult{Status: validate.Invalid, InvalidImages: []string{"image-a"}}
+
+	t.Run("event of created pod is recorded for its controller", func(t *testing.T) {
+		//GIVEN
+		recorder := record.NewFakeRecorder(10)
+		recorder.IncludeObject = true
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace:    "default",
+			GenerateName: "app-7d4b9c-",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "apps/v1",
+				Kind:       "ReplicaSet",
+				Name:       "app-7d4b9c",
+				UID:        "replicaset-uid",
+				Controller: ptr.To(true),
+			}},
+		}}
+
+		//WHEN
+		events.RecordValidationResult(recorder, pod, invalidResult)
+		events.RecordPodRejected(recorder, pod, false, "Pod images image-a validation failed")
+
+		//THEN
+		require.Len(t, recorder.Events, 2)
+		require.Contains(t, <-recorder.Events, "involvedObject{kind=ReplicaSet,apiVersion=apps/v1}")
+		require.Equal(t, "Warning PodRejected Pod images image-a validation failed involvedObject{kind=ReplicaSet,apiVersion=apps/v1}", <-recorder.Events)
+	})
+
+	t.Run("pod admitted with warning", func(t *testing.T) {
+		//GIVEN
+		recorder := record.NewFakeRecorder(10)
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod"}}
+
+		//WHEN
+		events.RecordPodRejected(recorder, pod, true, "Pod images image-a validation failed")
+
+		//THEN
+		require.Len(t, recorder.Events, 1)
+		require.Equal(t, "Warning PodAdmittedWithWarning Pod images image-a validation failed", <-recorder.Events)
+	})
+
+	t.Run("event is not recorded for pod which can't be referenced", func(t *testing.T) {
+		//GIVEN
+		recorder := record.NewFakeRecorder(10)
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", GenerateName: "pod-"}}
+
+		//WHEN
+		events.RecordValidationResult(recorder, pod, invalidResult)
+		events.RecordPodRejected(recorder, pod, false, "Pod images image-a validation failed")
+
+		//THEN
+		require.Empty(t, recorder.Events)
+	})
+}
@@ -4,47 +4,76 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
+	"maps"
+	"slices"
 
 	k8sconfig "github.com/docker/cli/cli/config/configfile"
-	cliType "github.com/docker/cli/cli/config/types"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func GetRemotePullCredentials(ctx context.Context, reader k8sclient.Reader, pod *corev1.Pod) (map[string]cliType.AuthConfig, error) {
-	remoteSecrets := make(map[string]cliType.AuthConfig)
-	for _, imagePullSecret := range pod.Spec.ImagePullSecrets {
+const defaultServiceAccountName = "default"
+
+// GetRemotePullCredentials returns credentials from image pull secrets of the pod, or from image pull secrets
+// of its service account if the pod doesn't have any, the same way as kubelet, missing and invalid secrets are skipped
+func GetRemotePullCredentials(ctx context.Context, reader k8sclient.Reader, pod *corev1.Pod) (RegistryCredentials, error) {
+	imagePullSecrets := pod.Spec.ImagePullSecrets
+	if len(imagePullSecrets) == 0 {
+		serviceAccountSecrets, err := getServiceAccountPullSecrets(ctx, reader, pod)
+		if err != nil {
+			return nil, err
+		}
+		imagePullSecrets = serviceAccountSecrets
+	}
+
+	remoteSecrets := RegistryCredentials{}
+	for _, imagePullSecret := range imagePullSecrets {
 		secret := &corev1.Secret{}
 		var dockerConfig []byte
 		if err := reader.Get(ctx, k8sclient.ObjectKey{Namespace: pod.Namespace, Name: imagePullSecret.Name}, secret); err != nil {
 			if k8sclient.IgnoreNotFound(err) != nil {
 				return nil, errors.Wrap(err, fmt.Sprintf("can't get %s/%s", pod.Namespace, imagePullSecret.Name)) //"failed to get secret")
 			}
-			return remoteSecrets, nil
+			continue
 		}
+		// invalid secrets are skipped the same way as kubelet does, so they don't fail the pod admission
 		if dc, ok := secret.Data[".dockerconfigjson"]; ok {
 			dockerConfig = dc
 		} else if dc, ok := secret.Data["config.json"]; ok {
 			dockerConfig = dc
 		} else {
-			return nil, errors.New("no dockerconfigjson or config.json found in secret")
+			LoggerFromCtx(ctx).Warnf("image pull secret %s/%s skipped: no dockerconfigjson or config.json found in secret", pod.Namespace, imagePullSecret.Name)
+			continue
 		}
 
 		var config k8sconfig.ConfigFile
 		if err := json.Unmarshal(dockerConfig, &config); err != nil {
-			return nil, errors.Wrap(err, "failed to unmarshal dockerconfigjson")
+			LoggerFromCtx(ctx).Warnf("image pull secret %s/%s skipped: failed to unmarshal dockerconfigjson: %s", pod.Namespace, imagePullSecret.Name, err.Error())
+			continue
 		}
-		for authRepo, auth := range config.AuthConfigs {
-			// remove any protocol from authRepo string, and trailing slash
-			authRepoFragments := strings.Split(authRepo, "://")
-			repoURL := authRepoFragments[len(authRepoFragments)-1]
-			repoURL = strings.TrimRight(repoURL, "/")
-
-			// technically, you could get a slice of authConfigs for each repoURL
-			remoteSecrets[repoURL] = auth
+		// docker config entries aren't ordered, so they are added in the stable order
+		for _, authRepo := range slices.Sorted(maps.Keys(config.AuthConfigs)) {
+			remoteSecrets.Add(authRepo, config.AuthConfigs[authRepo])
 		}
 	}
 	return remoteSecrets, nil
 }
+
+// getServiceAccountPullSecrets returns image pull secrets of the pod service account,
+// which may be not merged into the pod by the ServiceAccount admission plugin yet
+func getServiceAccountPullSecrets(ctx context.Context, reader k8sclient.Reader, pod *corev1.Pod) ([]corev1.LocalObjectReference, error) {
+	name := pod.Spec.ServiceAccountName
+	if name == "" {
+		name = defaultServiceAccountName
+	}
+
+	serviceAccount := &corev1.ServiceAccount{}
+	if err := reader.Get(ctx, k8sclient.ObjectKey{Namespace: pod.Namespace, Name: name}, serviceAccount); err != nil {
+		if k8sclient.IgnoreNotFound(err) != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("can't get service account %s/%s", pod.Namespace, name))
+		}
+		return nil, nil
+	}
+	return serviceAccount.ImagePullSecrets, nil
+}
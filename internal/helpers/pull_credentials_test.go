@@ -5,27 +5,31 @@ import (
 	"reflect"
 	"testing"
 
-	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 func Test_GetRemotePullCredentials(t *testing.T) {
 	ctx := context.Background()
 
 	tests := []struct {
-		name    string
-		secrets []*corev1.Secret
-		pod     *corev1.Pod
-		want    map[string]cliType.AuthConfig
-		wantErr bool
+		name            string
+		secrets         []*corev1.Secret
+		serviceAccounts []*corev1.ServiceAccount
+		pod             *corev1.Pod
+		want            RegistryCredentials
+		wantErr         bool
 	}{
 		{
 			name:    "no secrets",
 			secrets: nil,
 			pod:     &corev1.Pod{},
-			want:    map[string]cliType.AuthConfig{},
+			want:    RegistryCredentials{},
 			wantErr: false,
 		},
 		{
@@ -40,11 +44,11 @@ func Test_GetRemotePullCredentials(t *testing.T) {
 					},
 				},
 			},
-			want:    map[string]cliType.AuthConfig{},
+			want:    RegistryCredentials{},
 			wantErr: false,
 		},
 		{
-			name: "incorrect secret",
+			name: "secret without docker config is skipped",
 			secrets: []*corev1.Secret{
 				{
 					ObjectMeta: metav1.ObjectMeta{
@@ -68,11 +72,11 @@ func Test_GetRemotePullCredentials(t *testing.T) {
 					},
 				},
 			},
-			want:    map[string]cliType.AuthConfig{},
-			wantErr: true,
+			want:    RegistryCredentials{},
+			wantErr: false,
 		},
 		{
-			name: "malformed secret",
+			name: "malformed secret is skipped",
 			secrets: []*corev1.Secret{
 				{
 					ObjectMeta: metav1.ObjectMeta{
@@ -96,8 +100,8 @@ func Test_GetRemotePullCredentials(t *testing.T) {
 					},
 				},
 			},
-			want:    map[string]cliType.AuthConfig{},
-			wantErr: true,
+			want:    RegistryCredentials{},
+			wantErr: false,
 		},
 		{
 			name: "secret with .dockerconfigjson",
@@ -124,11 +128,11 @@ func Test_GetRemotePullCredentials(t *testing.T) {
 					},
 				},
 			},
-			want: map[string]cliType.AuthConfig{
-				"registry": {
+			want: RegistryCredentials{
+				"registry": {{
 					Username: "user",
 					Password: "password",
-				},
+				}},
 			},
 			wantErr: false,
 		}, {
@@ -156,11 +160,196 @@ func Test_GetRemotePullCredentials(t *testing.T) {
 					},
 				},
 			},
-			want: map[string]cliType.AuthConfig{
-				"registry": {
+			want: RegistryCredentials{
+				"registry": {{
 					Auth: "username:password",
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name:            "secret of default service account",
+			secrets:         []*corev1.Secret{fixDockerConfigSecret("sa-secret", `{"auths": {"registry": {"username": "sa-user", "password": "sa-password"}}}`)},
+			serviceAccounts: []*corev1.ServiceAccount{fixServiceAccount("default", "sa-secret")},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+				},
+			},
+			want: RegistryCredentials{
+				"registry": {{
+					Username: "sa-user",
+					Password: "sa-password",
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "secret of pod service account",
+			secrets: []*corev1.Secret{fixDockerConfigSecret("sa-secret", `{"auths": {"registry": {"username": "sa-user", "password": "sa-password"}}}`)},
+			serviceAccounts: []*corev1.ServiceAccount{
+				fixServiceAccount("default"),
+				fixServiceAccount("app", "sa-secret"),
+			},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: "app",
+				},
+			},
+			want: RegistryCredentials{
+				"registry": {{
+					Username: "sa-user",
+					Password: "sa-password",
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "service account secrets are ignored if pod has secrets",
+			secrets: []*corev1.Secret{
+				fixDockerConfigSecret("sa-secret", `{"auths": {"registry": {"username": "sa-user", "password": "sa-password"}, "other-registry": {"username": "other-user", "password": "other-password"}}}`),
+				fixDockerConfigSecret("pod-secret", `{"auths": {"registry": {"username": "user", "password": "password"}}}`),
+			},
+			serviceAccounts: []*corev1.ServiceAccount{fixServiceAccount("default", "sa-secret")},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+				},
+				Spec: corev1.PodSpec{
+					ImagePullSecrets: []corev1.LocalObjectReference{
+						{
+							Name: "pod-secret",
+						},
+					},
 				},
 			},
+			want: RegistryCredentials{
+				"registry": {{
+					Username: "user",
+					Password: "password",
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name:            "missing secret of service account",
+			serviceAccounts: []*corev1.ServiceAccount{fixServiceAccount("default", "sa-secret")},
+			secrets:         []*corev1.Secret{fixDockerConfigSecret("pod-secret", `{"auths": {"registry": {"username": "user", "password": "password"}}}`)},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+				},
+				Spec: corev1.PodSpec{
+					ImagePullSecrets: []corev1.LocalObjectReference{
+						{
+							Name: "pod-secret",
+						},
+					},
+				},
+			},
+			want: RegistryCredentials{
+				"registry": {{
+					Username: "user",
+					Password: "password",
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "secret with scheme, API version and missing secret before it",
+			secrets: []*corev1.Secret{
+				fixDockerConfigSecret("secret", `{"auths": {"https://index.docker.io/v1/": {"username": "user", "password": "password"}}}`),
+			},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+				},
+				Spec: corev1.PodSpec{
+					ImagePullSecrets: []corev1.LocalObjectReference{
+						{
+							Name: "missing",
+						},
+						{
+							Name: "secret",
+						},
+					},
+				},
+			},
+			want: RegistryCredentials{
+				"index.docker.io": {{
+					Username: "user",
+					Password: "password",
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "secret without docker config before valid secret",
+			secrets: []*corev1.Secret{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "incorrect",
+						Namespace: "default",
+					},
+					Data: map[string][]byte{
+						"incorrectKey": []byte("someData"),
+					},
+				},
+				fixDockerConfigSecret("secret", `{"auths": {"registry": {"username": "user", "password": "password"}}}`),
+			},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+				},
+				Spec: corev1.PodSpec{
+					ImagePullSecrets: []corev1.LocalObjectReference{
+						{
+							Name: "incorrect",
+						},
+						{
+							Name: "secret",
+						},
+					},
+				},
+			},
+			want: RegistryCredentials{
+				"registry": {{
+					Username: "user",
+					Password: "password",
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "malformed secret before valid secret",
+			secrets: []*corev1.Secret{
+				fixDockerConfigSecret("malformed", "someData"),
+				fixDockerConfigSecret("secret", `{"auths": {"registry": {"username": "user", "password": "password"}}}`),
+			},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+				},
+				Spec: corev1.PodSpec{
+					ImagePullSecrets: []corev1.LocalObjectReference{
+						{
+							Name: "malformed",
+						},
+						{
+							Name: "secret",
+						},
+					},
+				},
+			},
+			want: RegistryCredentials{
+				"registry": {{
+					Username: "user",
+					Password: "password",
+				}},
+			},
 			wantErr: false,
 		},
 	}
@@ -170,6 +359,9 @@ func Test_GetRemotePullCredentials(t *testing.T) {
 			for _, secret := range tt.secrets {
 				clientBuilder.WithObjects(secret)
 			}
+			for _, serviceAccount := range tt.serviceAccounts {
+				clientBuilder.WithObjects(serviceAccount)
+			}
 			client := clientBuilder.Build()
 
 			got, err := GetRemotePullCredentials(ctx, client, tt.pod)
@@ -183,3 +375,56 @@ func Test_GetRemotePullCredentials(t *testing.T) {
 		})
 	}
 }
+
+func Test_GetRemotePullCredentials_SkipServiceAccount(t *testing.T) {
+	//GIVEN
+	client := fake.NewClientBuilder().
+		WithObjects(fixDockerConfigSecret("pod-secret", `{"auths": {"registry": {"username": "user", "password": "password"}}}`)).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, client ctrlclient.WithWatch, key ctrlclient.ObjectKey, obj ctrlclient.Object, opts ...ctrlclient.GetOption) error {
+				if _, ok := obj.(*corev1.ServiceAccount); ok {
+					return errors.New("service account must not be read")
+				}
+				return client.Get(ctx, key, obj, opts...)
+			},
+		}).Build()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: "app",
+			ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "pod-secret"}},
+		},
+	}
+
+	//WHEN
+	got, err := GetRemotePullCredentials(context.Background(), client, pod)
+
+	//THEN
+	require.NoError(t, err)
+	require.Equal(t, RegistryCredentials{"registry": {{Username: "user", Password: "password"}}}, got)
+}
+
+func fixDockerConfigSecret(name, dockerConfig string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			".dockerconfigjson": []byte(dockerConfig),
+		},
+	}
+}
+
+func fixServiceAccount(name string, imagePullSecrets ...string) *corev1.ServiceAccount {
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+	}
+	for _, secret := range imagePullSecrets {
+		serviceAccount.ImagePullSecrets = append(serviceAccount.ImagePullSecrets, corev1.LocalObjectReference{Name: secret})
+	}
+	return serviceAccount
+}
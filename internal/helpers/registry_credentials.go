@@ -0,0 +1,119 @@
+package helpers
+
+import (
+	"maps"
+	"net"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+const (
+	dockerHubRegistry        = "docker.io"
+	dockerHubDefaultRegistry = "index.docker.io"
+)
+
+// RegistryCredentials are image pull credentials by the registry key, which is the registry host with the optional path,
+// e.g. `registry.io`, `registry.io:5000/org` or `*.azurecr.io`. Credentials are matched with images the same way as kubelet does
+type RegistryCredentials map[string][]cliType.AuthConfig
+
+// Add adds credentials of the registry from the docker config, the scheme and the registry API version are ignored,
+// so the Docker Hub alias `https://index.docker.io/v1/` is added as `index.docker.io`
+func (c RegistryCredentials) Add(registry string, auth cliType.AuthConfig) {
+	key, ok := registryCredentialsKey(registry)
+	if !ok {
+		return
+	}
+	c[key] = append(c[key], auth)
+}
+
+// Lookup returns all credentials matching the image repository in the order they should be tried,
+// credentials of more specific keys are returned first, and credentials of registries added earlier come first
+// if their keys are equal
+func (c RegistryCredentials) Lookup(ref name.Reference) []cliType.AuthConfig {
+	repository := KubeletRepository(ref)
+
+	keys := slices.Sorted(maps.Keys(c))
+	slices.Reverse(keys)
+
+	var credentials []cliType.AuthConfig
+	for _, key := range keys {
+		if RegistryKeyMatches(key, repository) {
+			credentials = append(credentials, c[key]...)
+		}
+	}
+	if len(credentials) == 0 && ref.Context().RegistryStr() == name.DefaultRegistry {
+		// credentials added for the legacy Docker Hub address are used for all Docker Hub images
+		credentials = slices.Clone(c[dockerHubDefaultRegistry])
+	}
+	return credentials
+}
+
+func registryCredentialsKey(registry string) (string, bool) {
+	value := registry
+	if !strings.HasPrefix(value, "https://") && !strings.HasPrefix(value, "http://") {
+		value = "https://" + value
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" {
+		return "", false
+	}
+
+	path := parsed.Path
+	if strings.HasPrefix(path, "/v1/") || strings.HasPrefix(path, "/v2/") {
+		path = path[3:]
+	}
+	if path == "" || path == "/" {
+		return parsed.Host, true
+	}
+	return parsed.Host + path, true
+}
+
+// KubeletRepository returns the repository name as it's used by kubelet, which refers to Docker Hub as `docker.io`
+func KubeletRepository(ref name.Reference) string {
+	registry := ref.Context().RegistryStr()
+	if registry == name.DefaultRegistry {
+		registry = dockerHubRegistry
+	}
+	return registry + "/" + ref.Context().RepositoryStr()
+}
+
+// RegistryKeyMatches checks if the key matches the repository, every part of the key host may contain the glob pattern,
+// ports have to be equal and the key path has to be the prefix of the repository path
+func RegistryKeyMatches(key, repository string) bool {
+	keyURL, err := url.Parse("https://" + key)
+	if err != nil {
+		return false
+	}
+	repositoryURL, err := url.Parse("https://" + repository)
+	if err != nil {
+		return false
+	}
+
+	keyHostParts, keyPort := splitRegistryHost(keyURL.Host)
+	repositoryHostParts, repositoryPort := splitRegistryHost(repositoryURL.Host)
+	if keyPort != repositoryPort || len(keyHostParts) != len(repositoryHostParts) {
+		return false
+	}
+	if !strings.HasPrefix(repositoryURL.Path, keyURL.Path) {
+		return false
+	}
+	for i, keyHostPart := range keyHostParts {
+		if matches, err := filepath.Match(keyHostPart, repositoryHostParts[i]); err != nil || !matches {
+			return false
+		}
+	}
+	return true
+}
+
+func splitRegistryHost(host string) ([]string, string) {
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname, port = host, ""
+	}
+	return strings.Split(hostname, "."), port
+}
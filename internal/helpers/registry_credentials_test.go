@@ -0,0 +1,119 @@
+package helpers
+
+import (
+	"testing"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryCredentials_Lookup(t *testing.T) {
+	tests := []struct {
+		name        string
+		registries  []string
+		image       string
+		wantMatches []string
+	}{
+		{
+			name:        "exact registry",
+			registries:  []string{"registry.io"},
+			image:       "registry.io/app:1.0",
+			wantMatches: []string{"registry.io"},
+		},
+		{
+			name:        "registry with scheme and trailing slash",
+			registries:  []string{"https://registry.io/"},
+			image:       "registry.io/org/app:1.0",
+			wantMatches: []string{"https://registry.io/"},
+		},
+		{
+			name:        "different registry",
+			registries:  []string{"registry.io"},
+			image:       "other.io/app:1.0",
+			wantMatches: nil,
+		},
+		{
+			name:        "Docker Hub alias",
+			registries:  []string{"https://index.docker.io/v1/"},
+			image:       "library/nginx:1.25",
+			wantMatches: []string{"https://index.docker.io/v1/"},
+		},
+		{
+			name:        "Docker Hub registry",
+			registries:  []string{"docker.io"},
+			image:       "docker.io/org/app:1.0",
+			wantMatches: []string{"docker.io"},
+		},
+		{
+			name:        "path scoped registry",
+			registries:  []string{"registry.io/org"},
+			image:       "registry.io/org/app:1.0",
+			wantMatches: []string{"registry.io/org"},
+		},
+		{
+			name:        "path scoped registry of different path",
+			registries:  []string{"registry.io/other-org"},
+			image:       "registry.io/org/app:1.0",
+			wantMatches: nil,
+		},
+		{
+			name:        "wildcard host",
+			registries:  []string{"*.azurecr.io"},
+			image:       "myregistry.azurecr.io/app:1.0",
+			wantMatches: []string{"*.azurecr.io"},
+		},
+		{
+			name:        "wildcard doesn't match more host parts",
+			registries:  []string{"*.azurecr.io"},
+			image:       "my.registry.azurecr.io/app:1.0",
+			wantMatches: nil,
+		},
+		{
+			name:        "registry with port",
+			registries:  []string{"registry.io:5000"},
+			image:       "registry.io:5000/app:1.0",
+			wantMatches: []string{"registry.io:5000"},
+		},
+		{
+			name:        "registry with different port",
+			registries:  []string{"registry.io:5000", "registry.io"},
+			image:       "registry.io:5001/app:1.0",
+			wantMatches: nil,
+		},
+		{
+			name:        "more specific credentials are tried first",
+			registries:  []string{"registry.io", "*.io", "registry.io/org", "registry.io/org/app"},
+			image:       "registry.io/org/app:1.0",
+			wantMatches: []string{"registry.io/org/app", "registry.io/org", "registry.io", "*.io"},
+		},
+		{
+			name:        "credentials of the same registry are tried in order",
+			registries:  []string{"registry.io", "https://registry.io"},
+			image:       "registry.io/app:1.0",
+			wantMatches: []string{"registry.io", "https://registry.io"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			credentials := RegistryCredentials{}
+			for _, registry := range tt.registries {
+				// the registry is used as the username, so matched credentials can be identified
+				credentials.Add(registry, cliType.AuthConfig{Username: registry})
+			}
+			ref, err := name.ParseReference(tt.image)
+			require.NoError(t, err)
+
+			//WHEN
+			got := credentials.Lookup(ref)
+
+			//THEN
+			var gotMatches []string
+			for _, auth := range got {
+				gotMatches = append(gotMatches, auth.Username)
+			}
+			require.Equal(t, tt.wantMatches, gotMatches)
+		})
+	}
+}
@@ -1,30 +1,69 @@
 package helpers
 
 import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/kyma-project/warden/pkg"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
-	"strconv"
-	"time"
 )
 
 const (
 	DefaultUserAllowedRegistries   = ""
 	DefaultUserNotaryTimeoutString = "30s"
 	DefaultUserStrictMode          = true
+	DefaultUserVerifier            = pkg.VerifierNotary
+	DefaultUserVerificationMode    = pkg.VerificationModeAnyOf
+
+	verifiersSeparator = ","
 )
 
 type UserValidationNotaryConfig struct {
+	Verifier          string
+	VerificationMode  string
 	NotaryURL         string
 	AllowedRegistries string
 	NotaryTimeout     time.Duration
+	CosignPublicKeys  string
+	NotationSecret    string
 }
 
 func GetUserValidationNotaryConfig(ns *corev1.Namespace) (UserValidationNotaryConfig, error) {
+	userVerifier, okVerifier := ns.GetAnnotations()[pkg.NamespaceVerifierAnnotation]
+	if !okVerifier {
+		userVerifier = DefaultUserVerifier
+	}
+	userVerifiers := ParseVerifiers(userVerifier)
+	if len(userVerifiers) == 0 {
+		return UserValidationNotaryConfig{}, errors.New("verifier is not set")
+	}
+	for _, verifier := range userVerifiers {
+		if !IsSupportedVerifier(verifier) {
+			return UserValidationNotaryConfig{}, errors.Errorf("verifier %s is not supported", verifier)
+		}
+	}
+	userVerificationMode, okVerificationMode := ns.GetAnnotations()[pkg.NamespaceVerificationModeAnnotation]
+	if !okVerificationMode {
+		userVerificationMode = DefaultUserVerificationMode
+	}
+	if userVerificationMode != pkg.VerificationModeAnyOf && userVerificationMode != pkg.VerificationModeAllOf {
+		return UserValidationNotaryConfig{}, errors.Errorf("verification mode %s is not supported", userVerificationMode)
+	}
 	userNotaryURL, okNotaryURL := ns.GetAnnotations()[pkg.NamespaceNotaryURLAnnotation]
-	if !okNotaryURL {
+	if !okNotaryURL && slices.Contains(userVerifiers, pkg.VerifierNotary) {
 		return UserValidationNotaryConfig{}, errors.New("notary URL is not set")
 	}
+	userCosignPublicKeys, okCosignPublicKeys := ns.GetAnnotations()[pkg.NamespaceCosignPublicKeysAnnotation]
+	if !okCosignPublicKeys && slices.Contains(userVerifiers, pkg.VerifierCosign) {
+		return UserValidationNotaryConfig{}, errors.New("cosign public keys are not set")
+	}
+	userNotationSecret, okNotationSecret := ns.GetAnnotations()[pkg.NamespaceNotationSecretAnnotation]
+	if !okNotationSecret && slices.Contains(userVerifiers, pkg.VerifierNotation) {
+		return UserValidationNotaryConfig{}, errors.New("notation secret is not set")
+	}
 	userAllowedRegistries, okAllowedRegistries := ns.GetAnnotations()[pkg.NamespaceAllowedRegistriesAnnotation]
 	if !okAllowedRegistries {
 		userAllowedRegistries = DefaultUserAllowedRegistries
@@ -38,12 +77,32 @@ func GetUserValidationNotaryConfig(ns *corev1.Namespace) (UserValidationNotaryCo
 		return UserValidationNotaryConfig{}, errNotaryTimeoutParse
 	}
 	return UserValidationNotaryConfig{
+		Verifier:          userVerifier,
+		VerificationMode:  userVerificationMode,
 		NotaryURL:         userNotaryURL,
 		AllowedRegistries: userAllowedRegistries,
 		NotaryTimeout:     userNotaryTimeout,
+		CosignPublicKeys:  userCosignPublicKeys,
+		NotationSecret:    userNotationSecret,
 	}, nil
 }
 
+// ParseVerifiers splits the comma-separated list of verifiers
+func ParseVerifiers(verifiers string) []string {
+	var verifiersList []string
+	for _, verifier := range strings.Split(verifiers, verifiersSeparator) {
+		sanitizedVerifier := strings.TrimSpace(verifier)
+		if sanitizedVerifier != "" {
+			verifiersList = append(verifiersList, sanitizedVerifier)
+		}
+	}
+	return verifiersList
+}
+
+func IsSupportedVerifier(verifier string) bool {
+	return verifier == pkg.VerifierNotary || verifier == pkg.VerifierCosign || verifier == pkg.VerifierNotation
+}
+
 func GetUserValidationStrictMode(ns *corev1.Namespace) (bool, error) {
 	strictModeString, ok := ns.GetAnnotations()[pkg.NamespaceStrictModeAnnotation]
 	if !ok {
@@ -1,12 +1,20 @@
 package tracing
 
-import "context"
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
 
 func GetMetadata(ctx context.Context) map[string]string {
 	m := map[string]string{
 		TRACE_KEY: UNKNOWN_VALUE,
 		SPAN_KEY:  UNKNOWN_VALUE,
 	}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		m[TRACE_KEY] = spanCtx.TraceID().String()
+		m[SPAN_KEY] = spanCtx.SpanID().String()
+	}
 	if val, ok := ctx.Value(TRACE_KEY).(string); ok {
 		m[TRACE_KEY] = val
 	}
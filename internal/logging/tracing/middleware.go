@@ -4,6 +4,9 @@ import (
 	"context"
 	"net/http"
 	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 const (
@@ -25,7 +28,9 @@ func NewTracingMiddleware(handler func(w http.ResponseWriter, r *http.Request))
 }
 
 func (m *tracingMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	newCtx := addHeaderToCtx(r.Context(), r.Header, TRACE_HEADER_KEY, TRACE_KEY)
+	// spans started by handlers are children of the API server span propagated with W3C or B3 headers
+	newCtx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	newCtx = addHeaderToCtx(newCtx, r.Header, TRACE_HEADER_KEY, TRACE_KEY)
 	newCtx = addHeaderToCtx(newCtx, r.Header, SPAN_HEADER_KEY, SPAN_KEY)
 
 	m.handler(w, r.WithContext(newCtx))
@@ -0,0 +1,149 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kyma-project/warden/internal/logging/tracing"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetupTracerProvider(t *testing.T) {
+	t.Run("tracing is disabled by default", func(t *testing.T) {
+		//WHEN
+		shutdown, err := tracing.SetupTracerProvider(context.Background(), "warden-test", tracing.Config{})
+
+		//THEN
+		require.NoError(t, err)
+		require.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("stdout exporter", func(t *testing.T) {
+		//WHEN
+		shutdown, err := tracing.SetupTracerProvider(context.Background(), "warden-test", tracing.Config{
+			Exporter:      tracing.ExporterStdout,
+			SamplingRatio: 1,
+		})
+
+		//THEN
+		require.NoError(t, err)
+		require.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("unsupported exporter", func(t *testing.T) {
+		//WHEN
+		shutdown, err := tracing.SetupTracerProvider(context.Background(), "warden-test", tracing.Config{Exporter: "zipkin"})
+
+		//THEN
+		require.ErrorContains(t, err, "unsupported tracing exporter: zipkin")
+		require.Nil(t, shutdown)
+	})
+}
+
+func TestMiddlewarePropagation(t *testing.T) {
+	_, err := tracing.SetupTracerProvider(context.Background(), "warden-test", tracing.Config{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{
+			name:    "W3C trace context",
+			headers: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		},
+		{
+			name: "B3 headers",
+			headers: map[string]string{
+				"X-B3-Traceid": "4bf92f3577b34da6a3ce929d0e0e4736",
+				"X-B3-Spanid":  "00f067aa0ba902b7",
+				"X-B3-Sampled": "1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			recorder := tracetest.NewSpanRecorder()
+			setTestTracerProvider(t, recorder)
+
+			middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				_, span := tracing.StartSpan(r.Context(), "handler")
+				span.End()
+			})
+			r, err := http.NewRequest(http.MethodPost, "", nil)
+			require.NoError(t, err)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+
+			//WHEN
+			middleware.ServeHTTP(httptest.NewRecorder(), r)
+
+			//THEN
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+			require.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+			require.True(t, spans[0].Parent().IsRemote())
+		})
+	}
+}
+
+func TestEndSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	setTestTracerProvider(t, recorder)
+
+	t.Run("span with error", func(t *testing.T) {
+		//GIVEN
+		_, span := tracing.StartSpan(context.Background(), "failed")
+
+		//WHEN
+		tracing.EndSpan(span, errors.New("connection refused"))
+
+		//THEN
+		ended := recorder.Ended()
+		require.Equal(t, codes.Error, ended[len(ended)-1].Status().Code)
+		require.Equal(t, "connection refused", ended[len(ended)-1].Status().Description)
+	})
+
+	t.Run("span without error", func(t *testing.T) {
+		//GIVEN
+		_, span := tracing.StartSpan(context.Background(), "succeeded")
+
+		//WHEN
+		tracing.EndSpan(span, nil)
+
+		//THEN
+		ended := recorder.Ended()
+		require.Equal(t, codes.Unset, ended[len(ended)-1].Status().Code)
+	})
+}
+
+func TestGetMetadataFromSpan(t *testing.T) {
+	//GIVEN
+	recorder := tracetest.NewSpanRecorder()
+	setTestTracerProvider(t, recorder)
+	ctx, span := tracing.StartSpan(context.Background(), "span")
+	defer span.End()
+
+	//WHEN
+	out := tracing.GetMetadata(ctx)
+
+	//THEN
+	require.Equal(t, span.SpanContext().TraceID().String(), out[tracing.TRACE_KEY])
+	require.Equal(t, span.SpanContext().SpanID().String(), out[tracing.SPAN_KEY])
+}
+
+func setTestTracerProvider(t *testing.T, recorder *tracetest.SpanRecorder) {
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "warden"
+
+// Requests to the image registry measured by RegistryRequestDuration
+const (
+	RegistryRequestDescriptor         = "descriptor"
+	RegistryRequestResolveDigest      = "resolve_digest"
+	RegistryRequestCosignSignatures   = "cosign_signatures"
+	RegistryRequestNotationSignatures = "notation_signatures"
+)
+
+var (
+	ValidationCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "validation_cache_hits_total",
+		Help:      "Number of image validations answered from the validation result cache.",
+	})
+	ValidationCacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "validation_cache_misses_total",
+		Help:      "Number of image validations which were not found in the validation result cache.",
+	})
+	ValidationResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "validation_results_total",
+		Help:      "Number of validated images by the validation status, the namespace validation mode and the reason of the failure.",
+	}, []string{"status", "mode", "reason"})
+	NotaryRequestDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "notary_request_duration_seconds",
+		Help:      "Duration of requests to the notary server.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	RegistryRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "registry_request_duration_seconds",
+		Help:      "Duration of requests to image registries by the request type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"request"})
+	CircuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "Number of circuit breakers by the state, the backend is the host of the system notary server or other.",
+	}, []string{"backend", "state"})
+	CircuitBreakerRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_rejected_total",
+		Help:      "Number of requests rejected by open circuit breakers, the backend is the host of the system notary server or other.",
+	}, []string{"backend"})
+)
+
+func init() {
+	// metrics are served by the controller-runtime manager
+	metrics.Registry.MustRegister(
+		ValidationCacheHits,
+		ValidationCacheMisses,
+		ValidationResults,
+		NotaryRequestDuration,
+		RegistryRequestDuration,
+		CircuitBreakerState,
+		CircuitBreakerRejections,
+	)
+}
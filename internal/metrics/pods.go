@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/kyma-project/warden/pkg"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const podsCollectTimeout = 10 * time.Second
+
+var _ prometheus.Collector = &podsCollector{}
+
+// podsCollector counts pods by the validation label value when metrics are scraped,
+// pods are listed from the manager cache, so scraping doesn't call the API server
+type podsCollector struct {
+	reader client.Reader
+	desc   *prometheus.Desc
+}
+
+func NewPodsCollector(reader client.Reader) prometheus.Collector {
+	return &podsCollector{
+		reader: reader,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "pods"),
+			"Number of pods by the value of the "+pkg.PodValidationLabel+" label.",
+			[]string{"validation"}, nil),
+	}
+}
+
+// RegisterPodsCollector registers the pods gauge, it's used only by the operator which caches pods anyway
+func RegisterPodsCollector(reader client.Reader) error {
+	return metrics.Registry.Register(NewPodsCollector(reader))
+}
+
+func (c *podsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *podsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), podsCollectTimeout)
+	defer cancel()
+
+	var pods corev1.PodList
+	if err := c.reader.List(ctx, &pods, client.HasLabels{pkg.PodValidationLabel}); err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+
+	counts := map[string]int{
+		pkg.ValidationStatusSuccess: 0,
+		pkg.ValidationStatusFailed:  0,
+		pkg.ValidationStatusPending: 0,
+	}
+	for _, pod := range pods.Items {
+		counts[pod.Labels[pkg.PodValidationLabel]]++
+	}
+	for value, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), value)
+	}
+}
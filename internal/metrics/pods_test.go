@@ -0,0 +1,45 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kyma-project/warden/internal/metrics"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPodsCollector(t *testing.T) {
+	//GIVEN
+	reader := fake.NewClientBuilder().WithObjects(
+		fixPod("pod-1", pkg.ValidationStatusSuccess),
+		fixPod("pod-2", pkg.ValidationStatusSuccess),
+		fixPod("pod-3", pkg.ValidationStatusFailed),
+		fixPod("pod-4", ""),
+	).Build()
+	collector := metrics.NewPodsCollector(reader)
+
+	//WHEN
+	err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP warden_pods Number of pods by the value of the pods.warden.kyma-project.io/validate label.
+# TYPE warden_pods gauge
+warden_pods{validation="failed"} 1
+warden_pods{validation="pending"} 0
+warden_pods{validation="success"} 2
+`))
+
+	//THEN
+	require.NoError(t, err)
+}
+
+func fixPod(name, validationLabel string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+	if validationLabel != "" {
+		pod.Labels = map[string]string{pkg.PodValidationLabel: validationLabel}
+	}
+	return pod
+}
@@ -0,0 +1,275 @@
+package validate
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/kyma-project/warden/internal/metrics"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+)
+
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+const (
+	// maxCircuitBreakers limits the number of breakers, the least recently used ones are evicted,
+	// because notary URLs come from user resources
+	maxCircuitBreakers = 100
+	// otherBackendsLabel is the metrics label of breakers of backends other than the system backend,
+	// so the number of label values is bounded
+	otherBackendsLabel = "other"
+)
+
+// ErrCircuitOpen is the cause of the UnknownResult error returned without calling the unavailable backend
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive UnknownResult failures which open the breaker, breakers are disabled if it's 0
+	FailureThreshold int
+	// OpenTimeout is the time after which the open breaker lets a single request through to probe the backend
+	OpenTimeout time.Duration
+	// SystemBackend is the URL of the system notary server, its host is the metrics label of its breaker,
+	// which is never evicted, breakers of other backends are reported with the "other" label
+	SystemBackend string
+}
+
+// CircuitBreakers keep the circuit breaker of every backend, e.g. the notary server URL,
+// they should be shared by all validators, so all requests to the backend open the same breaker
+type CircuitBreakers struct {
+	config CircuitBreakerConfig
+
+	mutex         sync.Mutex
+	breakers      map[string]*CircuitBreaker
+	systemBackend string
+}
+
+// CircuitBreaker fails fast while the backend is unavailable, it opens after FailureThreshold consecutive
+// UnknownResult failures and closes again after the successful probe request in the half-open state
+type CircuitBreaker struct {
+	backend string
+	config  CircuitBreakerConfig
+	now     func() time.Time
+	// lastUsed is guarded by the mutex of the breakers
+	lastUsed time.Time
+
+	mutex    sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+	// halfOpenTimer updates the state of the open breaker after OpenTimeout even if no request is sent
+	halfOpenTimer *time.Timer
+	// label is the backend label of metrics, the evicted breaker is still used by running validations,
+	// but it isn't reported anymore
+	label   string
+	evicted bool
+}
+
+func NewCircuitBreakers(config CircuitBreakerConfig) *CircuitBreakers {
+	return &CircuitBreakers{
+		config:        config,
+		breakers:      map[string]*CircuitBreaker{},
+		systemBackend: config.SystemBackend,
+	}
+}
+
+// Get returns the breaker of the backend, it returns nil if breakers are disabled and nil breaker allows all requests
+func (c *CircuitBreakers) Get(backend string) *CircuitBreaker {
+	if c == nil || c.config.FailureThreshold <= 0 {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	breaker, ok := c.breakers[backend]
+	if !ok {
+		if len(c.breakers) >= maxCircuitBreakers {
+			c.evictLeastRecentlyUsed()
+		}
+		breaker = newCircuitBreaker(backend, c.config, c.metricsLabel(backend))
+		c.breakers[backend] = breaker
+	}
+	breaker.lastUsed = time.Now()
+	return breaker
+}
+
+// SetSystemBackend changes the system backend when the config is reloaded, breakers are reported with new labels
+func (c *CircuitBreakers) SetSystemBackend(backend string) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.systemBackend = backend
+	for _, breaker := range c.breakers {
+		breaker.setLabel(c.metricsLabel(breaker.backend))
+	}
+}
+
+// evictLeastRecentlyUsed evicts the breaker which wasn't used for the longest time except the breaker of the system backend,
+// it must be called with the lock held
+func (c *CircuitBreakers) evictLeastRecentlyUsed() {
+	var system *CircuitBreaker
+	if breaker, ok := c.breakers[c.systemBackend]; ok {
+		system = breaker
+		delete(c.breakers, c.systemBackend)
+	}
+	evictLeastRecentlyUsed(c.breakers, func(b *CircuitBreaker) time.Time { return b.lastUsed }).evict()
+	if system != nil {
+		c.breakers[c.systemBackend] = system
+	}
+}
+
+// metricsLabel returns the host of the system backend or the label of other backends, it must be called with the lock held
+func (c *CircuitBreakers) metricsLabel(backend string) string {
+	if backend != c.systemBackend {
+		return otherBackendsLabel
+	}
+	u, err := url.Parse(backend)
+	if err != nil || u.Host == "" {
+		return otherBackendsLabel
+	}
+	return u.Host
+}
+
+func newCircuitBreaker(backend string, config CircuitBreakerConfig, label string) *CircuitBreaker {
+	breaker := &CircuitBreaker{
+		backend: backend,
+		config:  config,
+		now:     time.Now,
+		state:   CircuitClosed,
+		label:   label,
+	}
+	metrics.CircuitBreakerState.WithLabelValues(label, string(CircuitClosed)).Inc()
+	return breaker
+}
+
+// Allow returns the UnknownResult error if the request to the backend shouldn't be sent,
+// every allowed request has to be followed by Done with its result or by Cancel
+func (b *CircuitBreaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.currentState() {
+	case CircuitClosed:
+		return nil
+	case CircuitHalfOpen:
+		if !b.probing {
+			// only one request probes the backend, others fail fast until it's done
+			b.probing = true
+			b.setState(CircuitHalfOpen)
+			return nil
+		}
+	}
+	metrics.CircuitBreakerRejections.WithLabelValues(b.label).Inc()
+	return pkg.NewUnknownResultErr(errors.Wrap(ErrCircuitOpen, b.backend))
+}
+
+// Done records the result of the request allowed by Allow, only UnknownResult errors are failures of the backend
+func (b *CircuitBreaker) Done(err error) {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.probing = false
+	if err == nil || pkg.ErrorCode(err) != pkg.UnknownResult {
+		b.failures = 0
+		b.setState(CircuitClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.openedAt = b.now()
+		b.setState(CircuitOpen)
+		b.scheduleHalfOpen()
+	}
+}
+
+// Cancel releases the request allowed by Allow without recording its result, e.g. if the caller cancelled it
+func (b *CircuitBreaker) Cancel() {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.probing = false
+}
+
+// State returns the current state of the breaker, the nil breaker is always closed
+func (b *CircuitBreaker) State() CircuitState {
+	if b == nil {
+		return CircuitClosed
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.currentState()
+}
+
+func (b *CircuitBreaker) currentState() CircuitState {
+	if b.state == CircuitOpen && !b.now().Before(b.openedAt.Add(b.config.OpenTimeout)) {
+		b.setState(CircuitHalfOpen)
+	}
+	return b.state
+}
+
+func (b *CircuitBreaker) scheduleHalfOpen() {
+	if b.halfOpenTimer != nil {
+		b.halfOpenTimer.Stop()
+	}
+	b.halfOpenTimer = time.AfterFunc(b.config.OpenTimeout, func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		b.currentState()
+	})
+}
+
+// setState changes the state and the number of breakers in the state reported by the metric
+func (b *CircuitBreaker) setState(state CircuitState) {
+	if b.state != state && !b.evicted {
+		metrics.CircuitBreakerState.WithLabelValues(b.label, string(b.state)).Dec()
+		metrics.CircuitBreakerState.WithLabelValues(b.label, string(state)).Inc()
+	}
+	b.state = state
+}
+
+func (b *CircuitBreaker) setLabel(label string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.label != label && !b.evicted {
+		metrics.CircuitBreakerState.WithLabelValues(b.label, string(b.state)).Dec()
+		metrics.CircuitBreakerState.WithLabelValues(label, string(b.state)).Inc()
+	}
+	b.label = label
+}
+
+func (b *CircuitBreaker) evict() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.halfOpenTimer != nil {
+		b.halfOpenTimer.Stop()
+	}
+	metrics.CircuitBreakerState.WithLabelValues(b.label, string(b.state)).Dec()
+	b.evicted = true
+}
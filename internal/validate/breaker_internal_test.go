@@ -0,0 +1,204 @@
+package validate
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/warden/internal/metrics"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	unavailableErr := pkg.NewUnknownResultErr(errors.New("connection refused"))
+	invalidErr := pkg.NewValidationFailedErr(errors.New("unexpected image hash value"))
+	config := CircuitBreakerConfig{FailureThreshold: 3, OpenTimeout: time.Minute}
+
+	t.Run("open after consecutive failures", func(t *testing.T) {
+		//GIVEN
+		breaker := newCircuitBreaker("https://notary.io", config, otherBackendsLabel)
+
+		//WHEN
+		doRequests(t, breaker, unavailableErr, 3)
+
+		//THEN
+		require.Equal(t, CircuitOpen, breaker.State())
+		err := breaker.Allow()
+		require.ErrorIs(t, err, ErrCircuitOpen)
+		require.Equal(t, pkg.UnknownResult, pkg.ErrorCode(err))
+	})
+
+	t.Run("successful and invalid results reset failures", func(t *testing.T) {
+		//GIVEN
+		breaker := newCircuitBreaker("https://notary.io", config, otherBackendsLabel)
+
+		//WHEN
+		doRequests(t, breaker, unavailableErr, 2)
+		doRequests(t, breaker, invalidErr, 1)
+		doRequests(t, breaker, unavailableErr, 2)
+		doRequests(t, breaker, nil, 1)
+		doRequests(t, breaker, unavailableErr, 2)
+
+		//THEN
+		require.Equal(t, CircuitClosed, breaker.State())
+		require.NoError(t, breaker.Allow())
+	})
+
+	t.Run("half-open breaker allows a single probe", func(t *testing.T) {
+		//GIVEN
+		now := time.Now()
+		breaker := newCircuitBreaker("https://notary.io", config, otherBackendsLabel)
+		breaker.now = func() time.Time { return now }
+		doRequests(t, breaker, unavailableErr, 3)
+
+		//WHEN
+		now = now.Add(time.Minute)
+
+		//THEN
+		require.Equal(t, CircuitHalfOpen, breaker.State())
+		require.NoError(t, breaker.Allow())
+		require.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+	})
+
+	t.Run("cancelled probe lets the next request probe", func(t *testing.T) {
+		//GIVEN
+		now := time.Now()
+		breaker := newCircuitBreaker("https://notary.io", config, otherBackendsLabel)
+		breaker.now = func() time.Time { return now }
+		doRequests(t, breaker, unavailableErr, 3)
+		now = now.Add(time.Minute)
+
+		//WHEN
+		require.NoError(t, breaker.Allow())
+		breaker.Cancel()
+
+		//THEN
+		require.Equal(t, CircuitHalfOpen, breaker.State())
+		require.NoError(t, breaker.Allow())
+	})
+
+	t.Run("successful probe closes breaker", func(t *testing.T) {
+		//GIVEN
+		now := time.Now()
+		breaker := newCircuitBreaker("https://notary.io", config, otherBackendsLabel)
+		breaker.now = func() time.Time { return now }
+		doRequests(t, breaker, unavailableErr, 3)
+		now = now.Add(time.Minute)
+
+		//WHEN
+		doRequests(t, breaker, nil, 1)
+
+		//THEN
+		require.Equal(t, CircuitClosed, breaker.State())
+		require.NoError(t, breaker.Allow())
+	})
+
+	t.Run("failed probe opens breaker again", func(t *testing.T) {
+		//GIVEN
+		now := time.Now()
+		breaker := newCircuitBreaker("https://notary.io", config, otherBackendsLabel)
+		breaker.now = func() time.Time { return now }
+		doRequests(t, breaker, unavailableErr, 3)
+		now = now.Add(time.Minute)
+
+		//WHEN
+		doRequests(t, breaker, unavailableErr, 1)
+
+		//THEN
+		require.Equal(t, CircuitOpen, breaker.State())
+		now = now.Add(time.Minute)
+		require.Equal(t, CircuitHalfOpen, breaker.State())
+	})
+
+	t.Run("state metric is half-open after open timeout without requests", func(t *testing.T) {
+		//GIVEN
+		breaker := newCircuitBreaker("https://half-open-notary.io", CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: 50 * time.Millisecond}, "half-open-notary.io")
+
+		//WHEN
+		doRequests(t, breaker, unavailableErr, 1)
+
+		//THEN
+		require.Equal(t, 1.0, testutil.ToFloat64(metrics.CircuitBreakerState.WithLabelValues("half-open-notary.io", string(CircuitOpen))))
+		require.Eventually(t, func() bool {
+			return testutil.ToFloat64(metrics.CircuitBreakerState.WithLabelValues("half-open-notary.io", string(CircuitHalfOpen))) == 1
+		}, time.Second, 10*time.Millisecond)
+		require.Equal(t, 0.0, testutil.ToFloat64(metrics.CircuitBreakerState.WithLabelValues("half-open-notary.io", string(CircuitOpen))))
+	})
+}
+
+func TestCircuitBreakers(t *testing.T) {
+	unavailableErr := pkg.NewUnknownResultErr(errors.New("connection refused"))
+
+	t.Run("breakers are kept by backend", func(t *testing.T) {
+		//GIVEN
+		breakers := NewCircuitBreakers(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+
+		//WHEN
+		doRequests(t, breakers.Get("https://notary.io"), unavailableErr, 1)
+
+		//THEN
+		require.Same(t, breakers.Get("https://notary.io"), breakers.Get("https://notary.io"))
+		require.Equal(t, CircuitOpen, breakers.Get("https://notary.io").State())
+		require.Equal(t, CircuitClosed, breakers.Get("https://other-notary.io").State())
+	})
+
+	t.Run("least recently used breakers are evicted except the system breaker", func(t *testing.T) {
+		//GIVEN
+		breakers := NewCircuitBreakers(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, SystemBackend: "https://system-notary.io"})
+		system := breakers.Get("https://system-notary.io")
+		doRequests(t, system, unavailableErr, 1)
+		for i := range maxCircuitBreakers - 1 {
+			breakers.Get(fmt.Sprintf("https://notary-%d.io", i))
+		}
+		system.lastUsed = time.Now().Add(-2 * time.Hour)
+		breakers.breakers["https://notary-0.io"].lastUsed = time.Now().Add(-time.Hour)
+
+		//WHEN
+		breakers.Get("https://new-notary.io")
+
+		//THEN
+		require.Len(t, breakers.breakers, maxCircuitBreakers)
+		require.Same(t, system, breakers.Get("https://system-notary.io"))
+		require.Equal(t, CircuitOpen, system.State())
+		require.NotContains(t, breakers.breakers, "https://notary-0.io")
+	})
+
+	t.Run("metrics of other backends are reported with the bounded label", func(t *testing.T) {
+		//GIVEN
+		breakers := NewCircuitBreakers(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, SystemBackend: "https://labels-notary.io"})
+		system := breakers.Get("https://labels-notary.io")
+		other := breakers.Get("https://labels-other-notary.io")
+
+		//WHEN
+		breakers.SetSystemBackend("https://labels-other-notary.io")
+
+		//THEN
+		require.Equal(t, otherBackendsLabel, system.label)
+		require.Equal(t, "labels-other-notary.io", other.label)
+		require.Equal(t, 1.0, testutil.ToFloat64(metrics.CircuitBreakerState.WithLabelValues("labels-other-notary.io", string(CircuitClosed))))
+		require.Equal(t, 0.0, testutil.ToFloat64(metrics.CircuitBreakerState.WithLabelValues("labels-notary.io", string(CircuitClosed))))
+	})
+
+	t.Run("disabled breakers allow all requests", func(t *testing.T) {
+		//GIVEN
+		breakers := NewCircuitBreakers(CircuitBreakerConfig{})
+
+		//WHEN
+		breaker := breakers.Get("https://notary.io")
+		doRequests(t, breaker, unavailableErr, 10)
+
+		//THEN
+		require.Nil(t, breaker)
+		require.NoError(t, breaker.Allow())
+	})
+}
+
+func doRequests(t *testing.T, breaker *CircuitBreaker, err error, count int) {
+	for i := 0; i < count; i++ {
+		require.NoError(t, breaker.Allow())
+		breaker.Done(err)
+	}
+}
@@ -0,0 +1,252 @@
+package validate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/logging/tracing"
+	"github.com/kyma-project/warden/internal/metrics"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type CacheConfig struct {
+	// ValidTTL is the time for which the Valid result is cached
+	ValidTTL time.Duration
+	// InvalidTTL is the time for which the Invalid result is cached
+	InvalidTTL time.Duration
+	// MaxEntries limits the number of cached results, the least recently used result is evicted when the cache is full
+	MaxEntries int
+}
+
+type cacheEntry struct {
+	err       error
+	expiresAt time.Time
+	lastUsed  time.Time
+}
+
+// ValidationCache keeps image validation results keyed by the image digest and the verifier configuration,
+// UnknownResult is never cached
+type ValidationCache struct {
+	config  CacheConfig
+	mutex   sync.Mutex
+	entries map[string]cacheEntry
+	now     func() time.Time
+}
+
+func NewValidationCache(config CacheConfig) *ValidationCache {
+	return &ValidationCache{
+		config:  config,
+		entries: map[string]cacheEntry{},
+		now:     time.Now,
+	}
+}
+
+func (c *ValidationCache) get(key string) (error, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	now := c.now()
+	if now.After(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	entry.lastUsed = now
+	c.entries[key] = entry
+	return entry.err, true
+}
+
+func (c *ValidationCache) set(key string, err error) {
+	ttl := c.config.ValidTTL
+	if err != nil {
+		ttl = c.config.InvalidTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.config.MaxEntries <= 0 {
+		return
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.config.MaxEntries {
+		c.removeExpired()
+		// the new result is more likely to be used again than the result which wasn't used for the longest time
+		for len(c.entries) >= c.config.MaxEntries {
+			evictLeastRecentlyUsed(c.entries, func(entry cacheEntry) time.Time { return entry.lastUsed })
+		}
+	}
+	now := c.now()
+	c.entries[key] = cacheEntry{err: err, expiresAt: now.Add(ttl), lastUsed: now}
+}
+
+func (c *ValidationCache) removeExpired() {
+	now := c.now()
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+type cachedService struct {
+	validator          ImageValidatorService
+	cache              *ValidationCache
+	configKey          string
+	perTag             bool
+	allowedRegistries  []string
+	credentialProvider CredentialProvider
+}
+
+// NewCachedValidator puts the cache in front of the validator,
+// configKey has to identify the validator configuration, so results of differently configured validators are not mixed,
+// and results of validators which verify the image tag (perTag) are cached by the tag and the digest
+func NewCachedValidator(validator ImageValidatorService, cache *ValidationCache, configKey string, perTag bool, allowedRegistries []string, credentialProvider CredentialProvider) ImageValidatorService {
+	return &cachedService{
+		validator:          validator,
+		cache:              cache,
+		configKey:          configKey,
+		perTag:             perTag,
+		allowedRegistries:  allowedRegistries,
+		credentialProvider: credentialProvider,
+	}
+}
+
+func (s *cachedService) Validate(ctx context.Context, image string, imagePullCredentials helpers.RegistryCredentials) error {
+	// allowed images are accepted by validators without any request, there is nothing to cache
+	if allowed := isImageAllowed(image, s.allowedRegistries); allowed {
+		return s.validator.Validate(ctx, image, imagePullCredentials)
+	}
+
+	logger := helpers.LoggerFromCtx(ctx).With("image", image)
+
+	key, err := s.loggedGetCacheKey(ctx, image, imagePullCredentials)
+	if err != nil {
+		logger.Debugf("validation result cache skipped: %s", err.Error())
+		return s.validator.Validate(ctx, image, imagePullCredentials)
+	}
+
+	if cachedErr, ok := s.cache.get(key); ok {
+		metrics.ValidationCacheHits.Inc()
+		logger.Debug("validation result found in cache")
+		return cachedErr
+	}
+	metrics.ValidationCacheMisses.Inc()
+
+	err = s.validator.Validate(ctx, image, imagePullCredentials)
+	if isCacheable(err) {
+		s.cache.set(key, err)
+	}
+	return err
+}
+
+// isCacheable checks if the result depends only on the image, results depending on the availability of services
+// or on registry credentials of the pod aren't cached
+func isCacheable(err error) bool {
+	if err == nil {
+		return true
+	}
+	return pkg.ErrorCode(err) != pkg.UnknownResult && !errors.Is(err, pkg.ErrInvalidCredentials)
+}
+
+func (s *cachedService) loggedGetCacheKey(ctx context.Context, image string, imagePullCredentials helpers.RegistryCredentials) (string, error) {
+	const message = "request to image registry (resolve digest)"
+	closeLog := helpers.LogStartTime(ctx, message)
+	defer closeLog()
+	defer prometheus.NewTimer(metrics.RegistryRequestDuration.WithLabelValues(metrics.RegistryRequestResolveDigest)).ObserveDuration()
+	return s.getCacheKey(ctx, image, imagePullCredentials)
+}
+
+func (s *cachedService) getCacheKey(ctx context.Context, image string, imagePullCredentials helpers.RegistryCredentials) (string, error) {
+	ref, err := name.ParseReference(image, name.StrictValidation)
+	if err != nil {
+		return "", errors.Wrap(err, "image name could not be parsed")
+	}
+
+	digest, ok := ref.(name.Digest)
+	if !ok {
+		hash, err := getRemoteDigest(ctx, ref, imagePullCredentials, s.credentialProvider)
+		if err != nil {
+			return "", err
+		}
+		digest = ref.Context().Digest(hash.String())
+	}
+	repository := digest.Context().Name()
+	// trust data of notary is signed per tag, so tags of the same image can have different results
+	if tag, hasTag := getImageTag(image, ref); s.perTag && hasTag {
+		repository += ":" + tag
+	}
+	return s.configKey + "/" + repository + "@" + digest.DigestStr(), nil
+}
+
+// getRemoteDigest resolves the image digest with the HEAD request, the same way as getRemoteDescriptor gets the image
+func getRemoteDigest(ctx context.Context, ref name.Reference, imagePullCredentials helpers.RegistryCredentials, credentialProvider CredentialProvider) (v1.Hash, error) {
+	_, span := startRegistrySpan(ctx, "registry.Head", ref)
+	descriptor, err := remote.Head(ref, remote.WithContext(ctx))
+	tracing.EndSpan(span, err)
+	if err == nil {
+		return descriptor.Digest, nil
+	}
+
+	credentials := lookupCredentials(ctx, ref, imagePullCredentials, credentialProvider)
+	if len(credentials) == 0 {
+		return v1.Hash{}, errors.Wrap(err, "get image digest anonymously")
+	}
+	for _, credential := range credentials {
+		auth, parseErr := parseCredentials(credential)
+		if parseErr != nil {
+			err = parseErr
+			continue
+		}
+
+		_, span := startRegistrySpan(ctx, "registry.Head", ref)
+		span.SetAttributes(attribute.Bool("warden.registry.authenticated", true))
+		descriptor, err = remote.Head(ref, remote.WithContext(ctx), remote.WithAuth(auth))
+		tracing.EndSpan(span, err)
+		if err == nil {
+			return descriptor.Digest, nil
+		}
+	}
+	return v1.Hash{}, errors.Wrap(err, "get image digest")
+}
+
+// validatorConfigKey identifies all settings which have an impact on the validation result
+func validatorConfigKey(config ValidatorSvcConfig, allowedRegistries []string) string {
+	hash := sha256.New()
+	for _, value := range []string{
+		config.Verifier,
+		config.VerificationMode,
+		config.NotaryURL,
+		strings.Join(allowedRegistries, ","),
+		config.CosignPublicKeys,
+		config.NotationConfig.TrustPolicy,
+	} {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	for _, storeName := range slices.Sorted(maps.Keys(config.NotationConfig.TrustStore)) {
+		hash.Write([]byte(storeName))
+		hash.Write([]byte{0})
+		hash.Write([]byte(config.NotationConfig.TrustStore[storeName]))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
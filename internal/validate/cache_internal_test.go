@@ -0,0 +1,89 @@
+package validate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationCache(t *testing.T) {
+	invalidErr := pkg.NewValidationFailedErr(errors.New("invalid image"))
+
+	t.Run("entries expire after their TTL", func(t *testing.T) {
+		//GIVEN
+		now := time.Now()
+		cache := NewValidationCache(CacheConfig{ValidTTL: time.Minute, InvalidTTL: time.Second, MaxEntries: 10})
+		cache.now = func() time.Time { return now }
+		cache.set("valid", nil)
+		cache.set("invalid", invalidErr)
+
+		//WHEN
+		now = now.Add(2 * time.Second)
+		_, validFound := cache.get("valid")
+		_, invalidFound := cache.get("invalid")
+
+		//THEN
+		require.True(t, validFound)
+		require.False(t, invalidFound)
+	})
+
+	t.Run("entries are not cached with zero TTL", func(t *testing.T) {
+		//GIVEN
+		cache := NewValidationCache(CacheConfig{ValidTTL: time.Minute, MaxEntries: 10})
+
+		//WHEN
+		cache.set("invalid", invalidErr)
+
+		//THEN
+		_, found := cache.get("invalid")
+		require.False(t, found)
+	})
+
+	t.Run("full cache drops expired entries", func(t *testing.T) {
+		//GIVEN
+		now := time.Now()
+		cache := NewValidationCache(CacheConfig{ValidTTL: time.Minute, InvalidTTL: time.Second, MaxEntries: 2})
+		cache.now = func() time.Time { return now }
+		cache.set("valid", nil)
+		cache.set("invalid", invalidErr)
+
+		//WHEN
+		now = now.Add(2 * time.Second)
+		cache.set("added", nil)
+
+		//THEN
+		_, addedFound := cache.get("added")
+		require.True(t, addedFound)
+		_, validFound := cache.get("valid")
+		require.True(t, validFound)
+		require.Len(t, cache.entries, 2)
+	})
+
+	t.Run("full cache evicts the least recently used entry", func(t *testing.T) {
+		//GIVEN
+		now := time.Now()
+		cache := NewValidationCache(CacheConfig{ValidTTL: time.Minute, InvalidTTL: time.Minute, MaxEntries: 2})
+		cache.now = func() time.Time { return now }
+		cache.set("used", nil)
+		now = now.Add(time.Second)
+		cache.set("unused", invalidErr)
+		now = now.Add(time.Second)
+		_, usedFound := cache.get("used")
+		require.True(t, usedFound)
+
+		//WHEN
+		now = now.Add(time.Second)
+		cache.set("added", nil)
+
+		//THEN
+		_, addedFound := cache.get("added")
+		require.True(t, addedFound)
+		_, usedFound = cache.get("used")
+		require.True(t, usedFound)
+		_, unusedFound := cache.get("unused")
+		require.False(t, unusedFound)
+	})
+}
@@ -0,0 +1,149 @@
+package validate_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedValidate(t *testing.T) {
+	testServer := httptest.NewServer(registry.New())
+	defer testServer.Close()
+	registryHost := strings.TrimPrefix(testServer.URL, "http://")
+
+	image := pushRandomImage(t, fmt.Sprintf("%s/cached:v1", registryHost))
+	imageByDigest := image.ref.Context().Digest(image.digest.String()).String()
+
+	cacheConfig := validate.CacheConfig{ValidTTL: time.Minute, InvalidTTL: time.Minute, MaxEntries: 10}
+
+	tests := []struct {
+		name      string
+		result    error
+		wantCalls int
+	}{
+		{
+			name:      "valid result is cached",
+			result:    nil,
+			wantCalls: 1,
+		},
+		{
+			name:      "invalid result is cached",
+			result:    pkg.NewValidationFailedErr(errors.New("invalid image")),
+			wantCalls: 1,
+		},
+		{
+			name:      "unknown result is not cached",
+			result:    pkg.NewUnknownResultErr(errors.New("service unavailable")),
+			wantCalls: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			validatorSvcMock := mocks.ImageValidatorService{}
+			validatorSvcMock.Mock.On("Validate", mock.Anything, mock.Anything, mock.Anything).Return(tt.result)
+			s := validate.NewCachedValidator(&validatorSvcMock, validate.NewValidationCache(cacheConfig), "config", false, nil, nil)
+
+			//WHEN
+			errs := []error{
+				s.Validate(context.TODO(), image.ref.String(), emptyAuthData),
+				s.Validate(context.TODO(), image.ref.String(), emptyAuthData),
+				s.Validate(context.TODO(), imageByDigest, emptyAuthData),
+			}
+
+			//THEN
+			for _, err := range errs {
+				require.Equal(t, tt.result, err)
+			}
+			validatorSvcMock.AssertNumberOfCalls(t, "Validate", tt.wantCalls)
+		})
+	}
+
+	t.Run("results of different configs are not mixed", func(t *testing.T) {
+		//GIVEN
+		cache := validate.NewValidationCache(cacheConfig)
+		validSvcMock := mocks.ImageValidatorService{}
+		validSvcMock.Mock.On("Validate", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		invalidSvcMock := mocks.ImageValidatorService{}
+		invalidSvcMock.Mock.On("Validate", mock.Anything, mock.Anything, mock.Anything).
+			Return(pkg.NewValidationFailedErr(errors.New("invalid image")))
+		validSvc := validate.NewCachedValidator(&validSvcMock, cache, "valid-config", false, nil, nil)
+		invalidSvc := validate.NewCachedValidator(&invalidSvcMock, cache, "invalid-config", false, nil, nil)
+
+		//WHEN
+		validErr := validSvc.Validate(context.TODO(), imageByDigest, emptyAuthData)
+		invalidErr := invalidSvc.Validate(context.TODO(), imageByDigest, emptyAuthData)
+
+		//THEN
+		require.NoError(t, validErr)
+		require.Error(t, invalidErr)
+	})
+
+	t.Run("results of tags are not mixed if validator verifies tags", func(t *testing.T) {
+		//GIVEN
+		otherTag := fmt.Sprintf("%s/cached:v2", registryHost)
+		require.NoError(t, crane.Tag(image.ref.String(), "v2"))
+		validatorSvcMock := mocks.ImageValidatorService{}
+		validatorSvcMock.Mock.On("Validate", mock.Anything, image.ref.String(), mock.Anything).Return(nil)
+		validatorSvcMock.Mock.On("Validate", mock.Anything, otherTag, mock.Anything).
+			Return(pkg.NewValidationFailedErr(errors.New("image is not signed")))
+		s := validate.NewCachedValidator(&validatorSvcMock, validate.NewValidationCache(cacheConfig), "config", true, nil, nil)
+
+		//WHEN
+		signedErr := s.Validate(context.TODO(), image.ref.String(), emptyAuthData)
+		unsignedErr := s.Validate(context.TODO(), otherTag, emptyAuthData)
+		cachedUnsignedErr := s.Validate(context.TODO(), otherTag, emptyAuthData)
+
+		//THEN
+		require.NoError(t, signedErr)
+		require.ErrorContains(t, unsignedErr, "image is not signed")
+		require.ErrorContains(t, cachedUnsignedErr, "image is not signed")
+		validatorSvcMock.AssertNumberOfCalls(t, "Validate", 2)
+	})
+
+	t.Run("invalid result caused by credentials is not cached", func(t *testing.T) {
+		//GIVEN
+		validatorSvcMock := mocks.ImageValidatorService{}
+		validatorSvcMock.Mock.On("Validate", mock.Anything, mock.Anything, mock.Anything).
+			Return(pkg.NewInvalidCredentialsErr(errors.New("unknown auth secret format")))
+		s := validate.NewCachedValidator(&validatorSvcMock, validate.NewValidationCache(cacheConfig), "config", false, nil, nil)
+
+		//WHEN
+		err1 := s.Validate(context.TODO(), imageByDigest, emptyAuthData)
+		err2 := s.Validate(context.TODO(), imageByDigest, emptyAuthData)
+
+		//THEN
+		require.ErrorIs(t, err1, pkg.ErrInvalidCredentials)
+		require.ErrorIs(t, err2, pkg.ErrInvalidCredentials)
+		validatorSvcMock.AssertNumberOfCalls(t, "Validate", 2)
+	})
+
+	t.Run("image which can't be resolved is not cached", func(t *testing.T) {
+		//GIVEN
+		validatorSvcMock := mocks.ImageValidatorService{}
+		validatorSvcMock.Mock.On("Validate", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		s := validate.NewCachedValidator(&validatorSvcMock, validate.NewValidationCache(cacheConfig), "config", false, nil, nil)
+		unknownImage := fmt.Sprintf("%s/unknown:v1", registryHost)
+
+		//WHEN
+		err1 := s.Validate(context.TODO(), unknownImage, emptyAuthData)
+		err2 := s.Validate(context.TODO(), unknownImage, emptyAuthData)
+
+		//THEN
+		require.NoError(t, err1)
+		require.NoError(t, err2)
+		validatorSvcMock.AssertNumberOfCalls(t, "Validate", 2)
+	})
+}
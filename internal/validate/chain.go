@@ -0,0 +1,78 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/pkg"
+)
+
+const chainErrorsSeparator = "; "
+
+type NamedValidator struct {
+	Name      string
+	Validator ImageValidatorService
+}
+
+type chainService struct {
+	mode       string
+	validators []NamedValidator
+}
+
+// NewChainValidator combines validators using the anyOf or allOf mode,
+// the image is not valid if any validator finds it invalid (allOf) or none of them finds it valid (anyOf),
+// and the result is unknown if it depends on validators which couldn't verify the image
+func NewChainValidator(mode string, validators ...NamedValidator) ImageValidatorService {
+	return &chainService{
+		mode:       mode,
+		validators: validators,
+	}
+}
+
+func (s *chainService) Validate(ctx context.Context, image string, imagePullCredentials helpers.RegistryCredentials) error {
+	var validationErrs, unknownErrs chainError
+	for _, v := range s.validators {
+		err := v.Validator.Validate(ctx, image, imagePullCredentials)
+		if err == nil {
+			if s.mode != pkg.VerificationModeAllOf {
+				return nil
+			}
+			continue
+		}
+
+		namedErr := fmt.Errorf("%s: %w", v.Name, err)
+		if pkg.ErrorCode(err) == pkg.UnknownResult {
+			unknownErrs = append(unknownErrs, namedErr)
+			continue
+		}
+		if s.mode == pkg.VerificationModeAllOf {
+			return pkg.NewValidationFailedErr(chainError{namedErr})
+		}
+		validationErrs = append(validationErrs, namedErr)
+	}
+
+	if len(unknownErrs) != 0 {
+		return pkg.NewUnknownResultErr(append(validationErrs, unknownErrs...))
+	}
+	if len(validationErrs) != 0 {
+		return pkg.NewValidationFailedErr(validationErrs)
+	}
+	return nil
+}
+
+// chainError joins errors of validators in a single line, causes of all errors can be checked with errors.Is
+type chainError []error
+
+func (e chainError) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, chainErrorsSeparator)
+}
+
+func (e chainError) Unwrap() []error {
+	return e
+}
@@ -0,0 +1,154 @@
+package validate_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainValidate(t *testing.T) {
+	const image = "test-image"
+
+	validErr := error(nil)
+	invalidErr := pkg.NewValidationFailedErr(errors.New("invalid image"))
+	unknownErr := pkg.NewUnknownResultErr(errors.New("service unavailable"))
+
+	tests := []struct {
+		name     string
+		mode     string
+		results  []error
+		wantErr  string
+		wantCode pkg.ErrorType
+	}{
+		{
+			name:    "anyOf all valid",
+			mode:    pkg.VerificationModeAnyOf,
+			results: []error{validErr, validErr},
+		},
+		{
+			name:    "anyOf one valid",
+			mode:    pkg.VerificationModeAnyOf,
+			results: []error{invalidErr, validErr},
+		},
+		{
+			name:    "anyOf valid and unknown",
+			mode:    pkg.VerificationModeAnyOf,
+			results: []error{unknownErr, validErr},
+		},
+		{
+			name:     "anyOf all invalid",
+			mode:     pkg.VerificationModeAnyOf,
+			results:  []error{invalidErr, invalidErr},
+			wantErr:  "first: notary validation error: invalid image; second: notary validation error: invalid image",
+			wantCode: pkg.ValidationError,
+		},
+		{
+			name:     "anyOf invalid and unknown",
+			mode:     pkg.VerificationModeAnyOf,
+			results:  []error{unknownErr, invalidErr},
+			wantErr:  "second: notary validation error: invalid image; first: notary service unknown error: service unavailable",
+			wantCode: pkg.UnknownResult,
+		},
+		{
+			name:    "allOf all valid",
+			mode:    pkg.VerificationModeAllOf,
+			results: []error{validErr, validErr},
+		},
+		{
+			name:     "allOf one invalid",
+			mode:     pkg.VerificationModeAllOf,
+			results:  []error{validErr, invalidErr},
+			wantErr:  "second: notary validation error: invalid image",
+			wantCode: pkg.ValidationError,
+		},
+		{
+			name:     "allOf invalid and unknown",
+			mode:     pkg.VerificationModeAllOf,
+			results:  []error{unknownErr, invalidErr},
+			wantErr:  "second: notary validation error: invalid image",
+			wantCode: pkg.ValidationError,
+		},
+		{
+			name:     "allOf valid and unknown",
+			mode:     pkg.VerificationModeAllOf,
+			results:  []error{validErr, unknownErr},
+			wantErr:  "second: notary service unknown error: service unavailable",
+			wantCode: pkg.UnknownResult,
+		},
+		{
+			name:     "unexpected error is treated as invalid image",
+			mode:     pkg.VerificationModeAnyOf,
+			results:  []error{errors.New("unexpected"), invalidErr},
+			wantErr:  "first: unexpected",
+			wantCode: pkg.ValidationError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			first := mocks.ImageValidatorService{}
+			first.Mock.On("Validate", mock.Anything, image, mock.Anything).Return(tt.results[0]).Maybe()
+			second := mocks.ImageValidatorService{}
+			second.Mock.On("Validate", mock.Anything, image, mock.Anything).Return(tt.results[1]).Maybe()
+
+			s := validate.NewChainValidator(tt.mode,
+				validate.NamedValidator{Name: "first", Validator: &first},
+				validate.NamedValidator{Name: "second", Validator: &second})
+
+			//WHEN
+			err := s.Validate(context.TODO(), image, emptyAuthData)
+
+			//THEN
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+			require.Equal(t, tt.wantCode, pkg.ErrorCode(err))
+		})
+	}
+
+	t.Run("causes of validator errors are kept", func(t *testing.T) {
+		//GIVEN
+		first := mocks.ImageValidatorService{}
+		first.Mock.On("Validate", mock.Anything, image, mock.Anything).
+			Return(pkg.NewInvalidCredentialsErr(errors.New("unknown auth secret format")))
+		second := mocks.ImageValidatorService{}
+		second.Mock.On("Validate", mock.Anything, image, mock.Anything).Return(invalidErr)
+
+		s := validate.NewChainValidator(pkg.VerificationModeAnyOf,
+			validate.NamedValidator{Name: "first", Validator: &first},
+			validate.NamedValidator{Name: "second", Validator: &second})
+
+		//WHEN
+		err := s.Validate(context.TODO(), image, emptyAuthData)
+
+		//THEN
+		require.ErrorIs(t, err, pkg.ErrInvalidCredentials)
+		require.Equal(t, pkg.ValidationError, pkg.ErrorCode(err))
+	})
+
+	t.Run("anyOf stops on first valid result", func(t *testing.T) {
+		//GIVEN
+		first := mocks.ImageValidatorService{}
+		first.Mock.On("Validate", mock.Anything, image, mock.Anything).Return(nil)
+		second := mocks.ImageValidatorService{}
+
+		s := validate.NewChainValidator(pkg.VerificationModeAnyOf,
+			validate.NamedValidator{Name: "first", Validator: &first},
+			validate.NamedValidator{Name: "second", Validator: &second})
+
+		//WHEN
+		err := s.Validate(context.TODO(), image, emptyAuthData)
+
+		//THEN
+		require.NoError(t, err)
+		second.AssertNotCalled(t, "Validate", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -1,9 +1,9 @@
 package validate_test
 
 import (
-	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/kyma-project/warden/internal/helpers"
 )
 
 var (
-	emptyAuthData = map[string]cliType.AuthConfig{}
+	emptyAuthData = helpers.RegistryCredentials{}
 )
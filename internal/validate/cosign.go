@@ -0,0 +1,263 @@
+package validate
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/logging/tracing"
+	"github.com/kyma-project/warden/internal/metrics"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	CosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	CosignSignatureType       = "cosign container image signature"
+	cosignSignatureTagSuffix  = "sig"
+	cosignMaxPayloadSize      = 1 << 20
+)
+
+type CosignConfig struct {
+	// PublicKeys contains PEM encoded public keys, the image is valid if it's signed with any of them
+	PublicKeys string `json:"publicKeys"`
+}
+
+// cosignPayload is the simple signing payload created by cosign
+// https://github.com/containers/image/blob/main/docs/containers-signature.5.md
+type cosignPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+type cosignSignature struct {
+	payload   []byte
+	signature []byte
+}
+
+type cosignService struct {
+	ServiceConfig
+	publicKeys    []crypto.PublicKey
+	publicKeysErr error
+}
+
+func NewCosignValidator(sc *ServiceConfig) ImageValidatorService {
+	publicKeys, err := ParseCosignPublicKeys(sc.CosignConfig.PublicKeys)
+	return &cosignService{
+		ServiceConfig: ServiceConfig{
+			CosignConfig:       sc.CosignConfig,
+			AllowedRegistries:  sc.AllowedRegistries,
+			CredentialProvider: sc.CredentialProvider,
+		},
+		publicKeys:    publicKeys,
+		publicKeysErr: err,
+	}
+}
+
+func (s *cosignService) Validate(ctx context.Context, image string, imagePullCredentials helpers.RegistryCredentials) error {
+	logger := helpers.LoggerFromCtx(ctx).With("image", image)
+	ctx = helpers.LoggerToContext(ctx, logger)
+
+	if allowed := isImageAllowed(image, s.AllowedRegistries); allowed {
+		logger.Info("image validation skipped, because it's allowed")
+		return nil
+	}
+
+	if s.publicKeysErr != nil {
+		return pkg.NewUnknownResultErr(errors.Wrap(s.publicKeysErr, "cosign public keys could not be parsed"))
+	}
+
+	// strict validation requires image name to contain domain and a tag, and/or sha256
+	ref, err := name.ParseReference(image, name.StrictValidation)
+	if err != nil {
+		return pkg.NewImageNotAllowedErr(errors.Wrap(err, "image name could not be parsed"))
+	}
+
+	descriptor, remoteOptions, err := s.loggedGetRemoteDescriptor(ctx, ref, imagePullCredentials)
+	if err != nil {
+		return err
+	}
+
+	signatures, err := s.loggedGetSignatures(ctx, ref.Context(), descriptor.Digest, remoteOptions...)
+	if err != nil {
+		return err
+	}
+
+	for _, signature := range signatures {
+		if err := s.verifySignature(signature, descriptor.Digest); err != nil {
+			logger.Debugf("cosign signature rejected: %s", err.Error())
+			continue
+		}
+		return nil
+	}
+
+	return pkg.NewValidationFailedErr(errors.New("no valid cosign signature found for image"))
+}
+
+func (s *cosignService) loggedGetRemoteDescriptor(ctx context.Context, ref name.Reference, imagePullCredentials helpers.RegistryCredentials) (*remote.Descriptor, []remote.Option, error) {
+	const message = "request to image registry"
+	closeLog := helpers.LogStartTime(ctx, message)
+	defer closeLog()
+	defer prometheus.NewTimer(metrics.RegistryRequestDuration.WithLabelValues(metrics.RegistryRequestDescriptor)).ObserveDuration()
+	return getRemoteDescriptor(ctx, ref, imagePullCredentials, s.CredentialProvider)
+}
+
+func (s *cosignService) loggedGetSignatures(ctx context.Context, repo name.Repository, digest v1.Hash, remoteOptions ...remote.Option) ([]cosignSignature, error) {
+	const message = "request to image registry (cosign signatures)"
+	closeLog := helpers.LogStartTime(ctx, message)
+	defer closeLog()
+	defer prometheus.NewTimer(metrics.RegistryRequestDuration.WithLabelValues(metrics.RegistryRequestCosignSignatures)).ObserveDuration()
+	return getCosignSignatures(ctx, repo, digest, remoteOptions...)
+}
+
+func (s *cosignService) verifySignature(signature cosignSignature, digest v1.Hash) error {
+	// Validate returns publicKeysErr stored by the constructor for empty keys before it verifies signatures,
+	// the signature still can't be trusted if it's verified without keys
+	verifyErr := errors.New("no public key configured")
+	verified := false
+	for _, publicKey := range s.publicKeys {
+		if verifyErr = verifyCosignSignature(publicKey, signature.payload, signature.signature); verifyErr == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return errors.Wrap(verifyErr, "signature does not match any public key")
+	}
+
+	payload := cosignPayload{}
+	if err := json.Unmarshal(signature.payload, &payload); err != nil {
+		return errors.Wrap(err, "failed to unmarshal signature payload")
+	}
+	if payload.Critical.Type != CosignSignatureType {
+		return errors.Errorf("unexpected signature type: %s", payload.Critical.Type)
+	}
+	if payload.Critical.Image.DockerManifestDigest != digest.String() {
+		return errors.Errorf("signature is for a different image digest: %s", payload.Critical.Image.DockerManifestDigest)
+	}
+	return nil
+}
+
+// getCosignSignatures reads signatures stored by cosign in the image repository under the sha256-<hex>.sig tag
+func getCosignSignatures(ctx context.Context, repo name.Repository, digest v1.Hash, remoteOptions ...remote.Option) ([]cosignSignature, error) {
+	signatureTag := repo.Tag(fmt.Sprintf("%s-%s.%s", digest.Algorithm, digest.Hex, cosignSignatureTagSuffix))
+
+	_, span := startRegistrySpan(ctx, "registry.Image", signatureTag)
+	signatureImage, err := remote.Image(signatureTag, remoteOptions...)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		var transportErr *transport.Error
+		if errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound {
+			return nil, pkg.NewValidationFailedErr(errors.Wrap(err, "image is not signed"))
+		}
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get cosign signatures"))
+	}
+
+	manifest, err := signatureImage.Manifest()
+	if err != nil {
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "cosign signatures manifest"))
+	}
+
+	var signatures []cosignSignature
+	for _, layer := range manifest.Layers {
+		encodedSignature, ok := layer.Annotations[CosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+		if err != nil {
+			return nil, pkg.NewValidationFailedErr(errors.Wrap(err, "cannot decode base64 encoded signature"))
+		}
+		payload, err := getCosignPayload(signatureImage, layer.Digest)
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, cosignSignature{payload: payload, signature: signature})
+	}
+	return signatures, nil
+}
+
+func getCosignPayload(signatureImage v1.Image, digest v1.Hash) ([]byte, error) {
+	layer, err := signatureImage.LayerByDigest(digest)
+	if err != nil {
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get cosign signature payload"))
+	}
+	reader, err := layer.Compressed()
+	if err != nil {
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "read cosign signature payload"))
+	}
+	defer reader.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(reader, cosignMaxPayloadSize))
+	if err != nil {
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "read cosign signature payload"))
+	}
+	return payload, nil
+}
+
+func verifyCosignSignature(publicKey crypto.PublicKey, payload, signature []byte) error {
+	payloadHash := sha256.Sum256(payload)
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, payloadHash[:], signature) {
+			return errors.New("invalid ecdsa signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, payloadHash[:], signature)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, payload, signature) {
+			return errors.New("invalid ed25519 signature")
+		}
+		return nil
+	}
+	return errors.Errorf("unsupported public key type: %T", publicKey)
+}
+
+// ParseCosignPublicKeys parses all PEM encoded public keys from the given string
+func ParseCosignPublicKeys(keys string) ([]crypto.PublicKey, error) {
+	var publicKeys []crypto.PublicKey
+	rest := []byte(keys)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			return nil, errors.Errorf("unexpected PEM block type: %s", block.Type)
+		}
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse public key")
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+	if len(publicKeys) == 0 {
+		return nil, errors.New("no public key found")
+	}
+	return publicKeys, nil
+}
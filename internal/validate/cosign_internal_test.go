@@ -0,0 +1,22 @@
+package validate
+
+import (
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCosignVerifySignatureWithoutPublicKeys(t *testing.T) {
+	//GIVEN
+	digest := v1.Hash{Algorithm: "sha256", Hex: "3b6a07d0d404fab4e23b6d34bc6696a6a312dd92821332385e5af7c01c421351"}
+	payload := `{"critical":{"identity":{"docker-reference":"registry.io/signed"},"image":{"docker-manifest-digest":"` +
+		digest.String() + `"},"type":"` + CosignSignatureType + `"}}`
+	s := &cosignService{}
+
+	//WHEN
+	err := s.verifySignature(cosignSignature{payload: []byte(payload), signature: []byte("signature")}, digest)
+
+	//THEN
+	require.ErrorContains(t, err, "no public key configured")
+}
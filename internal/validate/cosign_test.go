@@ -0,0 +1,193 @@
+package validate_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	cosignSimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+)
+
+func Test_CosignValidate(t *testing.T) {
+	testServer := httptest.NewServer(registry.New())
+	defer testServer.Close()
+	registryHost := strings.TrimPrefix(testServer.URL, "http://")
+
+	signingKey, publicKey := generateCosignKeys(t)
+	_, otherPublicKey := generateCosignKeys(t)
+
+	signedImage := pushRandomImage(t, fmt.Sprintf("%s/signed:v1", registryHost))
+	signCosignImage(t, signedImage, signingKey, signedImage.digest)
+
+	unsignedImage := pushRandomImage(t, fmt.Sprintf("%s/unsigned:v1", registryHost))
+
+	wrongDigestImage := pushRandomImage(t, fmt.Sprintf("%s/wrong-digest:v1", registryHost))
+	signCosignImage(t, wrongDigestImage, signingKey, signedImage.digest)
+
+	tests := []struct {
+		name       string
+		image      string
+		publicKeys string
+		wantErr    string
+		wantCode   pkg.ErrorType
+	}{
+		{
+			name:       "signed image",
+			image:      signedImage.ref.String(),
+			publicKeys: publicKey,
+		},
+		{
+			name:       "signed image referenced by digest",
+			image:      signedImage.ref.Context().Digest(signedImage.digest.String()).String(),
+			publicKeys: publicKey,
+		},
+		{
+			name:       "signed image with one of public keys",
+			image:      signedImage.ref.String(),
+			publicKeys: otherPublicKey + publicKey,
+		},
+		{
+			name:       "signed image with different public key",
+			image:      signedImage.ref.String(),
+			publicKeys: otherPublicKey,
+			wantErr:    "no valid cosign signature found for image",
+			wantCode:   pkg.ValidationError,
+		},
+		{
+			name:       "unsigned image",
+			image:      unsignedImage.ref.String(),
+			publicKeys: publicKey,
+			wantErr:    "image is not signed",
+			wantCode:   pkg.ValidationError,
+		},
+		{
+			name:       "signature for different digest",
+			image:      wrongDigestImage.ref.String(),
+			publicKeys: publicKey,
+			wantErr:    "no valid cosign signature found for image",
+			wantCode:   pkg.ValidationError,
+		},
+		{
+			name:       "image which is not in registry",
+			image:      fmt.Sprintf("%s/unknown:v1", registryHost),
+			publicKeys: publicKey,
+			wantErr:    "get image descriptor anonymously",
+			wantCode:   pkg.UnknownResult,
+		},
+		{
+			name:       "no public keys",
+			image:      signedImage.ref.String(),
+			publicKeys: "",
+			wantErr:    "cosign public keys could not be parsed",
+			wantCode:   pkg.UnknownResult,
+		},
+		{
+			name:       "invalid public keys",
+			image:      signedImage.ref.String(),
+			publicKeys: "not a key",
+			wantErr:    "cosign public keys could not be parsed",
+			wantCode:   pkg.UnknownResult,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			cfg := validate.ServiceConfig{CosignConfig: validate.CosignConfig{PublicKeys: tt.publicKeys}}
+			s := validate.NewCosignValidator(&cfg)
+
+			//WHEN
+			err := s.Validate(context.TODO(), tt.image, emptyAuthData)
+
+			//THEN
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+			require.Equal(t, tt.wantCode, pkg.ErrorCode(err))
+		})
+	}
+
+	t.Run("image in allowed list", func(t *testing.T) {
+		//GIVEN
+		cfg := validate.ServiceConfig{
+			CosignConfig:      validate.CosignConfig{PublicKeys: publicKey},
+			AllowedRegistries: []string{"some-registry/allowed-image-name"},
+		}
+		s := validate.NewCosignValidator(&cfg)
+
+		//WHEN
+		err := s.Validate(context.TODO(), "some-registry/allowed-image-name:latest", emptyAuthData)
+
+		//THEN
+		require.NoError(t, err)
+	})
+}
+
+type pushedImage struct {
+	ref    name.Reference
+	digest v1.Hash
+}
+
+func pushRandomImage(t *testing.T, image string) pushedImage {
+	ref, err := name.ParseReference(image)
+	require.NoError(t, err)
+	img, err := random.Image(256, 1)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	return pushedImage{ref: ref, digest: digest}
+}
+
+func signCosignImage(t *testing.T, image pushedImage, key *ecdsa.PrivateKey, signedDigest v1.Hash) {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"%s"},"image":{"docker-manifest-digest":"%s"},"type":"%s"},"optional":null}`,
+		image.ref.Context().Name(), signedDigest.String(), validate.CosignSignatureType))
+	payloadHash := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, payloadHash[:])
+	require.NoError(t, err)
+
+	signatureImage, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: static.NewLayer(payload, cosignSimpleSigningMediaType),
+		Annotations: map[string]string{
+			validate.CosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signature),
+		},
+	})
+	require.NoError(t, err)
+	signatureImage = mutate.MediaType(signatureImage, types.OCIManifestSchema1)
+
+	signatureTag := image.ref.Context().Tag(fmt.Sprintf("%s-%s.sig", image.digest.Algorithm, image.digest.Hex))
+	require.NoError(t, remote.Write(signatureTag, signatureImage))
+}
+
+func generateCosignKeys(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKey, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+}
@@ -0,0 +1,27 @@
+package validate
+
+import (
+	"context"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/kyma-project/warden/internal/helpers"
+)
+
+// CredentialProvider provides registry credentials of images which aren't in image pull secrets,
+// e.g. by executing kubelet credential provider plugins
+//
+//go:generate mockery --name CredentialProvider
+type CredentialProvider interface {
+	Provide(ctx context.Context, ref name.Reference) helpers.RegistryCredentials
+}
+
+// lookupCredentials returns credentials matching the image in the order kubelet tries them,
+// credentials from image pull secrets are followed by credentials of the credential provider
+func lookupCredentials(ctx context.Context, ref name.Reference, imagePullCredentials helpers.RegistryCredentials, credentialProvider CredentialProvider) []cliType.AuthConfig {
+	credentials := imagePullCredentials.Lookup(ref)
+	if credentialProvider == nil {
+		return credentials
+	}
+	return append(credentials, credentialProvider.Provide(ctx, ref).Lookup(ref)...)
+}
@@ -26,20 +26,32 @@ import (
 	cliType "github.com/docker/cli/cli/config/types"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/logging/tracing"
+	"github.com/kyma-project/warden/internal/metrics"
 	"github.com/kyma-project/warden/pkg"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 //go:generate mockery --name=ImageValidatorService
 type ImageValidatorService interface {
-	Validate(ctx context.Context, image string, imagePullCredentials map[string]cliType.AuthConfig) error
+	Validate(ctx context.Context, image string, imagePullCredentials helpers.RegistryCredentials) error
 }
 
 type ServiceConfig struct {
 	NotaryConfig      NotaryConfig
+	CosignConfig      CosignConfig
+	NotationConfig    NotationConfig
 	AllowedRegistries []string
+	// CredentialProvider is used for images which can't be read anonymously or with image pull secrets, it's optional
+	CredentialProvider CredentialProvider
+	// NotaryCircuitBreaker fails fast while the notary server is unavailable, it's optional
+	NotaryCircuitBreaker *CircuitBreaker
 }
 
 type notaryService struct {
@@ -50,18 +62,20 @@ type notaryService struct {
 func NewImageValidator(sc *ServiceConfig, notaryClientFactory RepoFactory) ImageValidatorService {
 	return &notaryService{
 		ServiceConfig: ServiceConfig{
-			NotaryConfig:      sc.NotaryConfig,
-			AllowedRegistries: sc.AllowedRegistries,
+			NotaryConfig:         sc.NotaryConfig,
+			AllowedRegistries:    sc.AllowedRegistries,
+			CredentialProvider:   sc.CredentialProvider,
+			NotaryCircuitBreaker: sc.NotaryCircuitBreaker,
 		},
 		RepoFactory: notaryClientFactory,
 	}
 }
 
-func (s *notaryService) Validate(ctx context.Context, image string, imagePullCredentials map[string]cliType.AuthConfig) error {
+func (s *notaryService) Validate(ctx context.Context, image string, imagePullCredentials helpers.RegistryCredentials) error {
 	logger := helpers.LoggerFromCtx(ctx).With("image", image)
 	ctx = helpers.LoggerToContext(ctx, logger)
 
-	if allowed := s.isImageAllowed(image); allowed {
+	if allowed := isImageAllowed(image, s.AllowedRegistries); allowed {
 		logger.Info("image validation skipped, because it's allowed")
 		return nil
 	}
@@ -69,10 +83,10 @@ func (s *notaryService) Validate(ctx context.Context, image string, imagePullCre
 	// strict validation requires image name to contain domain and a tag, and/or sha256
 	ref, err := name.ParseReference(image, name.StrictValidation)
 	if err != nil {
-		return pkg.NewValidationFailedErr(errors.Wrap(err, "image name could not be parsed"))
+		return pkg.NewImageNotAllowedErr(errors.Wrap(err, "image name could not be parsed"))
 	}
 
-	expectedShaBytes, err := s.loggedGetNotaryImageDigestHash(ctx, ref)
+	expectedShaBytes, err := s.loggedGetNotaryImageDigestHash(ctx, image, ref)
 	if err != nil {
 		return err
 	}
@@ -91,63 +105,35 @@ func (s *notaryService) Validate(ctx context.Context, image string, imagePullCre
 		return nil
 	}
 
-	return pkg.NewValidationFailedErr(errors.New("unexpected image hash value"))
-}
-
-func (s *notaryService) isImageAllowed(imgRepo string) bool {
-	for _, allowed := range s.AllowedRegistries {
-		// repository is in allowed list
-		if strings.HasPrefix(imgRepo, allowed) {
-			return true
-		}
+	if _, isDigest := ref.(name.Digest); isDigest {
+		// the image is pulled by the digest, so the signed tag points to a different image
+		return pkg.NewValidationFailedErr(errors.New("image tag and digest point to different images"))
 	}
-	return false
+	return pkg.NewValidationFailedErr(errors.New("unexpected image hash value"))
 }
 
-func (s *notaryService) loggedGetRepositoryDigestHash(ctx context.Context, ref name.Reference, imagePullCredentials map[string]cliType.AuthConfig) ([]byte, []byte, error) {
+func (s *notaryService) loggedGetRepositoryDigestHash(ctx context.Context, ref name.Reference, imagePullCredentials helpers.RegistryCredentials) ([]byte, []byte, error) {
 	const message = "request to image registry"
 	closeLog := helpers.LogStartTime(ctx, message)
 	defer closeLog()
-	return s.getRepositoryDigestHash(ref, imagePullCredentials)
+	defer prometheus.NewTimer(metrics.RegistryRequestDuration.WithLabelValues(metrics.RegistryRequestDescriptor)).ObserveDuration()
+	return s.getRepositoryDigestHash(ctx, ref, imagePullCredentials)
 }
 
-func (s *notaryService) getRepositoryDigestHash(ref name.Reference, imagePullCredentials map[string]cliType.AuthConfig) ([]byte, []byte, error) {
-	remoteOptions := make([]remote.Option, 0)
-
-	credentials, credentialsOk := imagePullCredentials[ref.Context().RegistryStr()]
-
-	//try to get image info without credentials, mimicking Kuberenetes behavior
-	descriptor, err := remote.Get(ref)
+func (s *notaryService) getRepositoryDigestHash(ctx context.Context, ref name.Reference, imagePullCredentials helpers.RegistryCredentials) ([]byte, []byte, error) {
+	descriptor, remoteOptions, err := getRemoteDescriptor(ctx, ref, imagePullCredentials, s.CredentialProvider)
 	if err != nil {
-		if !credentialsOk {
-			// no fitting credentials, and no public access, return error
-			return nil, nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get image descriptor anonymously"))
-		} else {
-			// to to authenticate to the registry
-
-			credentials, err := parseCredentials(credentials)
-			if err != nil {
-				return nil, nil, err
-			}
-
-			if credentials != nil {
-				remoteOptions = append(remoteOptions, remote.WithAuth(credentials))
-			}
-			descriptor, err = remote.Get(ref, remoteOptions...)
-			if err != nil {
-				return nil, nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get image descriptor"))
-			}
-		}
+		return nil, nil, err
 	}
 
 	if descriptor.MediaType.IsIndex() {
-		digest, err := getIndexDigestHash(ref, remoteOptions...)
+		digest, err := getIndexDigestHash(ctx, ref, remoteOptions...)
 		if err != nil {
 			return nil, nil, err
 		}
 		return digest, nil, nil
 	} else if descriptor.MediaType.IsImage() {
-		digest, manifest, err := getImageDigestHash(ref, remoteOptions...)
+		digest, manifest, err := getImageDigestHash(ctx, ref, remoteOptions...)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -156,6 +142,53 @@ func (s *notaryService) getRepositoryDigestHash(ref name.Reference, imagePullCre
 	return nil, nil, pkg.NewValidationFailedErr(errors.New("not an image or image list"))
 }
 
+// getRemoteDescriptor returns the image descriptor together with the remote options
+// which have to be used for any subsequent request to the same registry, requests are cancelled with the context
+func getRemoteDescriptor(ctx context.Context, ref name.Reference, imagePullCredentials helpers.RegistryCredentials, credentialProvider CredentialProvider) (*remote.Descriptor, []remote.Option, error) {
+	//try to get image info without credentials, mimicking Kuberenetes behavior
+	_, span := startRegistrySpan(ctx, "registry.Get", ref)
+	descriptor, err := remote.Get(ref, remote.WithContext(ctx))
+	tracing.EndSpan(span, err)
+	if err == nil {
+		return descriptor, []remote.Option{remote.WithContext(ctx)}, nil
+	}
+
+	credentials := lookupCredentials(ctx, ref, imagePullCredentials, credentialProvider)
+	if len(credentials) == 0 {
+		// no fitting credentials, and no public access, return error
+		return nil, nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get image descriptor anonymously"))
+	}
+
+	// like kubelet, try all matching credentials in order until one of them is accepted by the registry
+	for _, credential := range credentials {
+		auth, parseErr := parseCredentials(credential)
+		if parseErr != nil {
+			err = parseErr
+			continue
+		}
+
+		remoteOptions := []remote.Option{remote.WithContext(ctx), remote.WithAuth(auth)}
+		_, span := startRegistrySpan(ctx, "registry.Get", ref)
+		span.SetAttributes(attribute.Bool("warden.registry.authenticated", true))
+		descriptor, err = remote.Get(ref, remoteOptions...)
+		tracing.EndSpan(span, err)
+		if err == nil {
+			return descriptor, remoteOptions, nil
+		}
+	}
+	if pkg.ErrorCode(err) == pkg.ValidationError {
+		return nil, nil, err
+	}
+	return nil, nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get image descriptor"))
+}
+
+// startRegistrySpan starts the span of the request to the image registry
+func startRegistrySpan(ctx context.Context, name string, ref name.Reference) (context.Context, trace.Span) {
+	return tracing.StartSpan(ctx, name,
+		attribute.String("warden.registry", ref.Context().RegistryStr()),
+		attribute.String("warden.image", ref.Name()))
+}
+
 func parseCredentials(credentials cliType.AuthConfig) (authn.Authenticator, error) {
 	if credentials.Username != "" && credentials.Password != "" {
 		basicCredentials := &authn.Basic{Username: credentials.Username, Password: credentials.Password}
@@ -167,21 +200,23 @@ func parseCredentials(credentials cliType.AuthConfig) (authn.Authenticator, erro
 		// auth is in base64-encoded "username:password" format
 		decodedCredentials, err := base64.StdEncoding.DecodeString(credentials.Auth)
 		if err != nil {
-			return nil, pkg.NewValidationFailedErr(errors.Wrap(err, "cannot decode base64 encoded auth"))
+			return nil, pkg.NewInvalidCredentialsErr(errors.Wrap(err, "cannot decode base64 encoded auth"))
 		}
 
 		auth := strings.Split(string(decodedCredentials), ":")
 		if len(auth) != 2 {
-			return nil, pkg.NewValidationFailedErr(errors.New("invalid auth format, expected username:password form"))
+			return nil, pkg.NewInvalidCredentialsErr(errors.New("invalid auth format, expected username:password form"))
 		}
 		basicCredentials := &authn.Basic{Username: auth[0], Password: auth[1]}
 		return basicCredentials, nil
 	}
-	return nil, pkg.NewValidationFailedErr(errors.New("unknown auth secret format"))
+	return nil, pkg.NewInvalidCredentialsErr(errors.New("unknown auth secret format"))
 }
 
-func getIndexDigestHash(ref name.Reference, remoteOptions ...remote.Option) ([]byte, error) {
+func getIndexDigestHash(ctx context.Context, ref name.Reference, remoteOptions ...remote.Option) ([]byte, error) {
+	_, span := startRegistrySpan(ctx, "registry.Index", ref)
 	i, err := remote.Index(ref, remoteOptions...)
+	tracing.EndSpan(span, err)
 	if err != nil {
 		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get image"))
 	}
@@ -196,8 +231,10 @@ func getIndexDigestHash(ref name.Reference, remoteOptions ...remote.Option) ([]b
 	return digestBytes, nil
 }
 
-func getImageDigestHash(ref name.Reference, remoteOptions ...remote.Option) ([]byte, []byte, error) {
+func getImageDigestHash(ctx context.Context, ref name.Reference, remoteOptions ...remote.Option) ([]byte, []byte, error) {
+	_, span := startRegistrySpan(ctx, "registry.Image", ref)
 	i, err := remote.Image(ref, remoteOptions...)
+	tracing.EndSpan(span, err)
 	if err != nil {
 		return nil, nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get image"))
 	}
@@ -227,26 +264,47 @@ func getImageDigestHash(ref name.Reference, remoteOptions ...remote.Option) ([]b
 	return digestBytes, manifestBytes, nil
 }
 
-func (s *notaryService) loggedGetNotaryImageDigestHash(ctx context.Context, ref name.Reference) ([]byte, error) {
+func (s *notaryService) loggedGetNotaryImageDigestHash(ctx context.Context, image string, ref name.Reference) ([]byte, error) {
 	const message = "request to notary"
 	closeLog := helpers.LogStartTime(ctx, message)
 	defer closeLog()
-	result, err := s.getNotaryImageDigestHash(ctx, ref)
+	if err := s.NotaryCircuitBreaker.Allow(); err != nil {
+		return nil, err
+	}
+	defer prometheus.NewTimer(metrics.NotaryRequestDuration).ObserveDuration()
+	result, err := s.getNotaryImageDigestHash(ctx, image, ref)
+	if ctx.Err() != nil {
+		// the validation was cancelled, so its failure says nothing about the notary server
+		s.NotaryCircuitBreaker.Cancel()
+		return result, err
+	}
+	s.NotaryCircuitBreaker.Done(err)
 	return result, err
 }
 
-func (s *notaryService) getNotaryImageDigestHash(ctx context.Context, ref name.Reference) ([]byte, error) {
+func (s *notaryService) getNotaryImageDigestHash(ctx context.Context, image string, ref name.Reference) ([]byte, error) {
 	const messageNewRepoClient = "request to notary (NewRepoClient)"
 	closeLog := helpers.LogStartTime(ctx, messageNewRepoClient)
-	c, err := s.RepoFactory.NewRepoClient(ref.Context().Name(), s.NotaryConfig)
+	_, span := startNotarySpan(ctx, "notary.NewRepoClient", ref)
+	c, err := s.RepoFactory.NewRepoClient(ctx, ref.Context().Name(), s.NotaryConfig)
+	tracing.EndSpan(span, err)
 	closeLog()
 	if err != nil {
 		return nil, pkg.NewUnknownResultErr(err)
 	}
 
+	digest, isDigest := ref.(name.Digest)
+	tag, hasTag := getImageTag(image, ref)
+	if isDigest && !hasTag {
+		return getNotarySignedDigestHash(ctx, c, digest)
+	}
+
 	const messageGetTargetByName = "request to notary (GetTargetByName)"
 	closeLog = helpers.LogStartTime(ctx, messageGetTargetByName)
-	target, err := c.GetTargetByName(ref.Identifier())
+	_, span = startNotarySpan(ctx, "notary.GetTargetByName", ref)
+	span.SetAttributes(attribute.String("warden.notary.tag", tag))
+	target, err := c.GetTargetByName(tag)
+	tracing.EndSpan(span, err)
 	closeLog()
 	if err != nil {
 		return nil, parseNotaryErr(err)
@@ -268,6 +326,55 @@ func (s *notaryService) getNotaryImageDigestHash(ctx context.Context, ref name.R
 	return target.Hashes[key], nil
 }
 
+// getNotarySignedDigestHash looks for the image digest in all signed targets of the repository,
+// because images referenced only by the digest don't have the tag which could be used to get the target
+func getNotarySignedDigestHash(ctx context.Context, c NotaryRepoClient, digest name.Digest) ([]byte, error) {
+	hash, err := v1.NewHash(digest.DigestStr())
+	if err != nil {
+		return nil, pkg.NewValidationFailedErr(errors.Wrap(err, "image digest could not be parsed"))
+	}
+	digestBytes, err := hex.DecodeString(hash.Hex)
+	if err != nil {
+		return nil, pkg.NewValidationFailedErr(errors.Wrap(err, "image digest could not be decoded"))
+	}
+
+	const messageListTargets = "request to notary (ListTargets)"
+	closeLog := helpers.LogStartTime(ctx, messageListTargets)
+	_, span := startNotarySpan(ctx, "notary.ListTargets", digest)
+	targets, err := c.ListTargets()
+	tracing.EndSpan(span, err)
+	closeLog()
+	if err != nil {
+		return nil, parseNotaryErr(err)
+	}
+
+	for _, target := range targets {
+		if targetHash, ok := target.Hashes[hash.Algorithm]; ok && subtle.ConstantTimeCompare(targetHash, digestBytes) == 1 {
+			return digestBytes, nil
+		}
+	}
+	return nil, pkg.NewValidationFailedErr(errors.New("image digest is not signed"))
+}
+
+// startNotarySpan starts the span of the request to the notary server
+func startNotarySpan(ctx context.Context, name string, ref name.Reference) (context.Context, trace.Span) {
+	return tracing.StartSpan(ctx, name, attribute.String("warden.notary.repository", ref.Context().Name()))
+}
+
+// getImageTag returns the tag of the image, also when it's referenced by both the tag and the digest
+func getImageTag(image string, ref name.Reference) (string, bool) {
+	if tag, ok := ref.(name.Tag); ok {
+		return tag.TagStr(), true
+	}
+
+	base, _, _ := strings.Cut(image, "@")
+	tag, err := name.NewTag(base, name.StrictValidation)
+	if err != nil {
+		return "", false
+	}
+	return tag.TagStr(), true
+}
+
 func parseNotaryErr(err error) error {
 	errMsg := err.Error()
 	if strings.Contains(errMsg, "does not have trust data for") {
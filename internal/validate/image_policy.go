@@ -0,0 +1,265 @@
+package validate
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kyma-project/warden/api/v1alpha1"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// UserImagePolicy is the ImagePolicy or ClusterImagePolicy used for the user validation of the namespace
+type UserImagePolicy struct {
+	name string
+	spec *v1alpha1.ImagePolicySpec
+	// cluster is true for the ClusterImagePolicy, which can reference secrets of other namespaces
+	cluster bool
+}
+
+// GetUserImagePolicy returns the ImagePolicy of the namespace or the first ClusterImagePolicy which selects it,
+// nil is returned when the namespace doesn't use the user validation or there is no policy and the namespace annotations should be used,
+// policies are listed with uncached reads, so the policy should be resolved once per request
+func GetUserImagePolicy(ctx context.Context, reader k8sclient.Reader, ns *corev1.Namespace) (*UserImagePolicy, error) {
+	if !IsUserValidationForNS(ns) {
+		return nil, nil
+	}
+	var policies v1alpha1.ImagePolicyList
+	if err := reader.List(ctx, &policies, k8sclient.InNamespace(ns.Name)); err != nil {
+		if isImagePolicyNotInstalled(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "while fetching image policies")
+	}
+	if policy := FirstImagePolicy(policies.Items); policy != nil {
+		return &UserImagePolicy{
+			name: "ImagePolicy " + policy.Namespace + "/" + policy.Name,
+			spec: &policy.Spec,
+		}, nil
+	}
+
+	var clusterPolicies v1alpha1.ClusterImagePolicyList
+	if err := reader.List(ctx, &clusterPolicies); err != nil {
+		if isImagePolicyNotInstalled(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "while fetching cluster image policies")
+	}
+	slices.SortFunc(clusterPolicies.Items, func(a, b v1alpha1.ClusterImagePolicy) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for i := range clusterPolicies.Items {
+		clusterPolicy := &clusterPolicies.Items[i]
+		selected, err := IsNamespaceSelected(clusterPolicy, ns)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid namespace selector of ClusterImagePolicy %s", clusterPolicy.Name)
+		}
+		if selected {
+			return &UserImagePolicy{
+				name:    "ClusterImagePolicy " + clusterPolicy.Name,
+				spec:    &clusterPolicy.Spec.ImagePolicySpec,
+				cluster: true,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// FirstImagePolicy returns the policy used for the namespace, which is the first one by name
+func FirstImagePolicy(policies []v1alpha1.ImagePolicy) *v1alpha1.ImagePolicy {
+	var first *v1alpha1.ImagePolicy
+	for i := range policies {
+		if first == nil || policies[i].Name < first.Name {
+			first = &policies[i]
+		}
+	}
+	return first
+}
+
+// IsNamespaceSelected checks if the ClusterImagePolicy applies to the namespace
+func IsNamespaceSelected(policy *v1alpha1.ClusterImagePolicy, ns *corev1.Namespace) (bool, error) {
+	if policy.Spec.NamespaceSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(policy.Spec.NamespaceSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(ns.Labels)), nil
+}
+
+// isImagePolicyNotInstalled allows to use the namespace annotations when the ImagePolicy CRDs are not installed
+func isImagePolicyNotInstalled(err error) bool {
+	return meta.IsNoMatchError(err) || runtime.IsNotRegisteredError(err)
+}
+
+// CheckImagePolicy returns an error if the policy can't be used to validate images, the namespace is empty
+// for the ClusterImagePolicy, so its notation secret is checked only if the secret namespace is set
+func CheckImagePolicy(ctx context.Context, reader k8sclient.Reader, namespace string, spec *v1alpha1.ImagePolicySpec) error {
+	config, err := validatorSvcConfigFromPolicy(ctx, reader, namespace, spec, namespace == "")
+	if err != nil {
+		return err
+	}
+	if config.CosignPublicKeys != "" {
+		if _, err := ParseCosignPublicKeys(config.CosignPublicKeys); err != nil {
+			return errors.Wrap(err, "invalid cosign public keys")
+		}
+	}
+	if config.NotationConfig.TrustPolicy != "" {
+		if _, err := parseNotationTrustPolicies(config.NotationConfig); err != nil {
+			return errors.Wrap(err, "invalid notation trust policy")
+		}
+	}
+	_, err = parseExceptions(spec.Exceptions)
+	return err
+}
+
+// validatorSvcConfigFromPolicy reads the notation secret from the namespace, only the cluster policy can set the secret namespace,
+// because warden reads secrets with its own permissions and the namespaced policy mustn't read secrets of other namespaces
+func validatorSvcConfigFromPolicy(ctx context.Context, reader k8sclient.Reader, namespace string, spec *v1alpha1.ImagePolicySpec, cluster bool) (ValidatorSvcConfig, error) {
+	verifiers := []string{helpers.DefaultUserVerifier}
+	if len(spec.Verifiers) != 0 {
+		verifiers = make([]string, 0, len(spec.Verifiers))
+		for _, verifier := range spec.Verifiers {
+			verifiers = append(verifiers, string(verifier))
+		}
+	}
+	for _, verifier := range verifiers {
+		if !helpers.IsSupportedVerifier(verifier) {
+			return ValidatorSvcConfig{}, errors.Errorf("verifier %s is not supported", verifier)
+		}
+	}
+
+	verificationMode := spec.VerificationMode
+	if verificationMode == "" {
+		verificationMode = helpers.DefaultUserVerificationMode
+	}
+
+	config := ValidatorSvcConfig{
+		Verifier:          strings.Join(verifiers, ","),
+		VerificationMode:  verificationMode,
+		AllowedRegistries: strings.Join(spec.AllowedRegistries, allowedRegistriesSeparator),
+	}
+
+	if slices.Contains(verifiers, string(v1alpha1.VerifierNotary)) {
+		if spec.Notary == nil || spec.Notary.URL == "" {
+			return ValidatorSvcConfig{}, errors.New("notary URL is not set")
+		}
+		config.NotaryURL = spec.Notary.URL
+		config.NotaryTimeout, _ = time.ParseDuration(helpers.DefaultUserNotaryTimeoutString)
+		if spec.Notary.Timeout != nil {
+			config.NotaryTimeout = spec.Notary.Timeout.Duration
+		}
+	}
+
+	if slices.Contains(verifiers, string(v1alpha1.VerifierCosign)) {
+		if spec.Cosign == nil || len(spec.Cosign.PublicKeys) == 0 {
+			return ValidatorSvcConfig{}, errors.New("cosign public keys are not set")
+		}
+		config.CosignPublicKeys = strings.Join(spec.Cosign.PublicKeys, "\n")
+	}
+
+	if slices.Contains(verifiers, string(v1alpha1.VerifierNotation)) {
+		if spec.Notation == nil || spec.Notation.SecretName == "" {
+			return ValidatorSvcConfig{}, errors.New("notation secret is not set")
+		}
+		if !cluster && spec.Notation.SecretNamespace != "" {
+			return ValidatorSvcConfig{}, errors.New("notation secret namespace can be set only in ClusterImagePolicy")
+		}
+		secretNamespace := namespace
+		if spec.Notation.SecretNamespace != "" {
+			secretNamespace = spec.Notation.SecretNamespace
+		}
+		if secretNamespace != "" {
+			notationConfig, err := getNotationConfigFromSecret(ctx, reader, secretNamespace, spec.Notation.SecretName)
+			if err != nil {
+				return ValidatorSvcConfig{}, err
+			}
+			config.NotationConfig = notationConfig
+		}
+	}
+	return config, nil
+}
+
+func newUserValidationSvcFromPolicy(ctx context.Context, reader k8sclient.Reader, ns *corev1.Namespace, policy *UserImagePolicy, validatorFactory ValidatorSvcFactory) (PodValidator, error) {
+	config, err := validatorSvcConfigFromPolicy(ctx, reader, ns.Name, policy.spec, policy.cluster)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s can't be used", policy.name)
+	}
+	exceptions, err := parseExceptions(policy.spec.Exceptions)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s can't be used", policy.name)
+	}
+
+	validationSvc := validatorFactory.NewValidatorSvc(config)
+	if len(exceptions) == 0 {
+		return validationSvc, nil
+	}
+	return &exceptionsPodValidator{
+		PodValidator: validationSvc,
+		exceptions:   exceptions,
+	}, nil
+}
+
+// GetUserValidationStrictMode returns the strict mode of the namespace with the user validation and its policy
+func GetUserValidationStrictMode(ns *corev1.Namespace, policy *UserImagePolicy) (bool, error) {
+	if policy == nil {
+		return helpers.GetUserValidationStrictMode(ns)
+	}
+	if policy.spec.StrictMode == nil {
+		return helpers.DefaultUserStrictMode, nil
+	}
+	return *policy.spec.StrictMode, nil
+}
+
+// GetEnforcementMode returns whether pods with invalid images are rejected or admitted with warnings
+func GetEnforcementMode(ns *corev1.Namespace, policy *UserImagePolicy) string {
+	if IsWarnValidationForNS(ns) {
+		return pkg.EnforcementModeWarn
+	}
+	if !IsUserValidationForNS(ns) || policy == nil || policy.spec.EnforcementMode == "" {
+		return pkg.EnforcementModeEnforce
+	}
+	return policy.spec.EnforcementMode
+}
+
+func parseExceptions(exceptions []v1alpha1.ImagePolicyException) (map[string]labels.Selector, error) {
+	selectors := make(map[string]labels.Selector, len(exceptions))
+	for _, exception := range exceptions {
+		// the empty selector selects all pods, so one exception would disable the validation of the namespace
+		if len(exception.PodSelector.MatchLabels) == 0 && len(exception.PodSelector.MatchExpressions) == 0 {
+			return nil, errors.Errorf("pod selector of exception %s is empty", exception.Name)
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&exception.PodSelector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pod selector of exception %s", exception.Name)
+		}
+		selectors[exception.Name] = selector
+	}
+	return selectors, nil
+}
+
+// exceptionsPodValidator skips the validation of pods selected by policy exceptions
+type exceptionsPodValidator struct {
+	PodValidator
+	exceptions map[string]labels.Selector
+}
+
+func (v *exceptionsPodValidator) ValidatePod(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, imagePullCredentials helpers.RegistryCredentials) (ValidationResult, error) {
+	for name, selector := range v.exceptions {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			helpers.LoggerFromCtx(ctx).Infof("pod validation skipped because of the policy exception %s", name)
+			return ValidationResult{Status: NoAction}, nil
+		}
+	}
+	return v.PodValidator.ValidatePod(ctx, pod, ns, imagePullCredentials)
+}
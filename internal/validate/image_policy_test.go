@@ -0,0 +1,369 @@
+package validate_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/warden/api/v1alpha1"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNewUserValidationSvcWithImagePolicy(t *testing.T) {
+	userNs := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "user-ns",
+			Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationUser, "team": "a"},
+			Annotations: map[string]string{
+				pkg.NamespaceNotaryURLAnnotation: "https://annotation.notary",
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		objects       []client.Object
+		wantNotaryURL string
+	}{
+		{
+			name:          "annotations are used without policies",
+			wantNotaryURL: "https://annotation.notary",
+		},
+		{
+			name: "namespace policy is used before cluster policy",
+			objects: []client.Object{
+				fixImagePolicy("user-ns", "policy", "https://namespace.notary"),
+				fixClusterImagePolicy("cluster-policy", nil, "https://cluster.notary"),
+			},
+			wantNotaryURL: "https://namespace.notary",
+		},
+		{
+			name: "first namespace policy by name is used",
+			objects: []client.Object{
+				fixImagePolicy("user-ns", "policy-b", "https://b.notary"),
+				fixImagePolicy("user-ns", "policy-a", "https://a.notary"),
+			},
+			wantNotaryURL: "https://a.notary",
+		},
+		{
+			name: "policy from other namespace is not used",
+			objects: []client.Object{
+				fixImagePolicy("other-ns", "policy", "https://namespace.notary"),
+			},
+			wantNotaryURL: "https://annotation.notary",
+		},
+		{
+			name: "first selecting cluster policy is used",
+			objects: []client.Object{
+				fixClusterImagePolicy("policy-a", map[string]string{"team": "b"}, "https://a.notary"),
+				fixClusterImagePolicy("policy-b", map[string]string{"team": "a"}, "https://b.notary"),
+				fixClusterImagePolicy("policy-c", nil, "https://c.notary"),
+			},
+			wantNotaryURL: "https://b.notary",
+		},
+		{
+			name: "annotations are used if cluster policy doesn't select namespace",
+			objects: []client.Object{
+				fixClusterImagePolicy("policy", map[string]string{"team": "b"}, "https://cluster.notary"),
+			},
+			wantNotaryURL: "https://annotation.notary",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			reader := fake.NewClientBuilder().WithScheme(fixImagePolicyScheme(t)).WithObjects(tt.objects...).Build()
+			factory := mocks.ValidatorSvcFactory{}
+			factory.On("NewValidatorSvc", mock.Anything).Return(&mocks.PodValidator{})
+
+			//WHEN
+			_, err := validate.NewUserValidationSvc(context.TODO(), reader, userNs, &factory)
+
+			//THEN
+			require.NoError(t, err)
+			factory.AssertNumberOfCalls(t, "NewValidatorSvc", 1)
+			config := factory.Calls[0].Arguments.Get(0).(validate.ValidatorSvcConfig)
+			require.Equal(t, tt.wantNotaryURL, config.NotaryURL)
+		})
+	}
+
+	t.Run("policy is mapped to validator config", func(t *testing.T) {
+		//GIVEN
+		policy := fixImagePolicy("user-ns", "policy", "https://namespace.notary")
+		policy.Spec.Verifiers = []v1alpha1.Verifier{v1alpha1.VerifierNotary, v1alpha1.VerifierCosign}
+		policy.Spec.VerificationMode = pkg.VerificationModeAllOf
+		policy.Spec.Notary.Timeout = &metav1.Duration{Duration: 5 * time.Second}
+		policy.Spec.Cosign = &v1alpha1.CosignVerifier{PublicKeys: []string{"key-1", "key-2"}}
+		policy.Spec.AllowedRegistries = []string{"eu.gcr.io/kyma-project", "docker.io/library"}
+		reader := fake.NewClientBuilder().WithScheme(fixImagePolicyScheme(t)).WithObjects(policy).Build()
+		factory := mocks.ValidatorSvcFactory{}
+		factory.On("NewValidatorSvc", mock.Anything).Return(&mocks.PodValidator{})
+
+		//WHEN
+		_, err := validate.NewUserValidationSvc(context.TODO(), reader, userNs, &factory)
+
+		//THEN
+		require.NoError(t, err)
+		factory.AssertCalled(t, "NewValidatorSvc", validate.ValidatorSvcConfig{
+			Verifier:          "notary,cosign",
+			VerificationMode:  pkg.VerificationModeAllOf,
+			NotaryURL:         "https://namespace.notary",
+			NotaryTimeout:     5 * time.Second,
+			AllowedRegistries: "eu.gcr.io/kyma-project,docker.io/library",
+			CosignPublicKeys:  "key-1\nkey-2",
+		})
+	})
+
+	t.Run("annotations are used if policies are not installed", func(t *testing.T) {
+		//GIVEN
+		scheme := runtime.NewScheme()
+		require.NoError(t, clientgoscheme.AddToScheme(scheme))
+		reader := fake.NewClientBuilder().WithScheme(scheme).Build()
+		factory := mocks.ValidatorSvcFactory{}
+		factory.On("NewValidatorSvc", mock.Anything).Return(&mocks.PodValidator{})
+
+		//WHEN
+		_, err := validate.NewUserValidationSvc(context.TODO(), reader, userNs, &factory)
+
+		//THEN
+		require.NoError(t, err)
+		config := factory.Calls[0].Arguments.Get(0).(validate.ValidatorSvcConfig)
+		require.Equal(t, "https://annotation.notary", config.NotaryURL)
+	})
+
+	t.Run("invalid policy is not used", func(t *testing.T) {
+		//GIVEN
+		policy := fixImagePolicy("user-ns", "policy", "")
+		reader := fake.NewClientBuilder().WithScheme(fixImagePolicyScheme(t)).WithObjects(policy).Build()
+		factory := mocks.ValidatorSvcFactory{}
+
+		//WHEN
+		_, err := validate.NewUserValidationSvc(context.TODO(), reader, userNs, &factory)
+
+		//THEN
+		require.ErrorContains(t, err, "ImagePolicy user-ns/policy can't be used: notary URL is not set")
+		factory.AssertNotCalled(t, "NewValidatorSvc", mock.Anything)
+	})
+	t.Run("policy with empty exception selector is not used", func(t *testing.T) {
+		//GIVEN
+		policy := fixImagePolicy("user-ns", "policy", "https://namespace.notary")
+		policy.Spec.Exceptions = []v1alpha1.ImagePolicyException{{Name: "all"}}
+		reader := fake.NewClientBuilder().WithScheme(fixImagePolicyScheme(t)).WithObjects(policy).Build()
+		factory := mocks.ValidatorSvcFactory{}
+		factory.On("NewValidatorSvc", mock.Anything).Return(&mocks.PodValidator{})
+
+		//WHEN
+		_, err := validate.NewUserValidationSvc(context.TODO(), reader, userNs, &factory)
+
+		//THEN
+		require.ErrorContains(t, err, "ImagePolicy user-ns/policy can't be used: pod selector of exception all is empty")
+	})
+	t.Run("policy with notation secret of other namespace is not used", func(t *testing.T) {
+		//GIVEN
+		policy := fixImagePolicy("user-ns", "policy", "")
+		policy.Spec.Verifiers = []v1alpha1.Verifier{v1alpha1.VerifierNotation}
+		policy.Spec.Notation = &v1alpha1.NotationVerifier{SecretName: "notation", SecretNamespace: "kyma-system"}
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "kyma-system", Name: "notation"}}
+		reader := fake.NewClientBuilder().WithScheme(fixImagePolicyScheme(t)).WithObjects(policy, secret).Build()
+		factory := mocks.ValidatorSvcFactory{}
+
+		//WHEN
+		_, err := validate.NewUserValidationSvc(context.TODO(), reader, userNs, &factory)
+
+		//THEN
+		require.ErrorContains(t, err, "ImagePolicy user-ns/policy can't be used: notation secret namespace can be set only in ClusterImagePolicy")
+		factory.AssertNotCalled(t, "NewValidatorSvc", mock.Anything)
+	})
+}
+
+func TestImagePolicyExceptions(t *testing.T) {
+	//GIVEN
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "user-ns",
+		Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationUser},
+	}}
+	policy := fixImagePolicy("user-ns", "policy", "https://namespace.notary")
+	policy.Spec.Exceptions = []v1alpha1.ImagePolicyException{{
+		Name:        "debug",
+		PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"debug": "true"}},
+	}}
+	reader := fake.NewClientBuilder().WithScheme(fixImagePolicyScheme(t)).WithObjects(policy).Build()
+
+	podValidator := mocks.PodValidator{}
+	podValidator.On("ValidatePod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(validate.ValidationResult{Status: validate.Valid}, nil)
+	factory := mocks.ValidatorSvcFactory{}
+	factory.On("NewValidatorSvc", mock.Anything).Return(&podValidator)
+
+	validator, err := validate.NewUserValidationSvc(context.TODO(), reader, ns, &factory)
+	require.NoError(t, err)
+
+	t.Run("pod selected by exception is not validated", func(t *testing.T) {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"debug": "true"}}}
+
+		//WHEN
+		result, err := validator.ValidatePod(context.TODO(), pod, ns, nil)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, validate.NoAction, result.Status)
+		podValidator.AssertNotCalled(t, "ValidatePod", mock.Anything, pod, mock.Anything, mock.Anything)
+	})
+
+	t.Run("other pod is validated", func(t *testing.T) {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"debug": "false"}}}
+
+		//WHEN
+		result, err := validator.ValidatePod(context.TODO(), pod, ns, nil)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, validate.Valid, result.Status)
+		podValidator.AssertCalled(t, "ValidatePod", mock.Anything, pod, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetUserValidationStrictMode(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "user-ns",
+		Labels:      map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationUser},
+		Annotations: map[string]string{pkg.NamespaceStrictModeAnnotation: "false"},
+	}}
+	disabled := false
+
+	tests := []struct {
+		name       string
+		strictMode *bool
+		withPolicy bool
+		want       bool
+	}{
+		{
+			name:       "annotation is used without policy",
+			withPolicy: false,
+			want:       false,
+		},
+		{
+			name:       "policy is strict by default",
+			withPolicy: true,
+			want:       true,
+		},
+		{
+			name:       "policy strict mode is used",
+			strictMode: &disabled,
+			withPolicy: true,
+			want:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			builder := fake.NewClientBuilder().WithScheme(fixImagePolicyScheme(t))
+			if tt.withPolicy {
+				policy := fixImagePolicy("user-ns", "policy", "https://namespace.notary")
+				policy.Spec.StrictMode = tt.strictMode
+				builder = builder.WithObjects(policy)
+			}
+
+			//WHEN
+			policy, err := validate.GetUserImagePolicy(context.TODO(), builder.Build(), ns)
+			require.NoError(t, err)
+			strictMode, err := validate.GetUserValidationStrictMode(ns, policy)
+
+			//THEN
+			require.NoError(t, err)
+			require.Equal(t, tt.want, strictMode)
+		})
+	}
+}
+
+func TestGetEnforcementMode(t *testing.T) {
+	tests := []struct {
+		name            string
+		label           string
+		enforcementMode string
+		want            string
+	}{
+		{
+			name:  "system validation is enforced",
+			label: pkg.NamespaceValidationSystem,
+			want:  pkg.EnforcementModeEnforce,
+		},
+		{
+			name:  "warn validation",
+			label: pkg.NamespaceValidationWarn,
+			want:  pkg.EnforcementModeWarn,
+		},
+		{
+			name:  "user validation is enforced by default",
+			label: pkg.NamespaceValidationUser,
+			want:  pkg.EnforcementModeEnforce,
+		},
+		{
+			name:            "user validation with policy enforcement mode",
+			label:           pkg.NamespaceValidationUser,
+			enforcementMode: pkg.EnforcementModeWarn,
+			want:            pkg.EnforcementModeWarn,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "user-ns",
+				Labels: map[string]string{pkg.NamespaceValidationLabel: tt.label},
+			}}
+			policy := fixImagePolicy("user-ns", "policy", "https://namespace.notary")
+			policy.Spec.EnforcementMode = tt.enforcementMode
+			reader := fake.NewClientBuilder().WithScheme(fixImagePolicyScheme(t)).WithObjects(policy).Build()
+
+			//WHEN
+			userPolicy, err := validate.GetUserImagePolicy(context.TODO(), reader, ns)
+			require.NoError(t, err)
+			mode := validate.GetEnforcementMode(ns, userPolicy)
+
+			//THEN
+			require.Equal(t, tt.want, mode)
+		})
+	}
+}
+
+func fixImagePolicyScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+func fixImagePolicy(namespace, name, notaryURL string) *v1alpha1.ImagePolicy {
+	return &v1alpha1.ImagePolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: v1alpha1.ImagePolicySpec{
+			Notary: &v1alpha1.NotaryVerifier{URL: notaryURL},
+		},
+	}
+}
+
+func fixClusterImagePolicy(name string, namespaceLabels map[string]string, notaryURL string) *v1alpha1.ClusterImagePolicy {
+	policy := &v1alpha1.ClusterImagePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha1.ClusterImagePolicySpec{
+			ImagePolicySpec: v1alpha1.ImagePolicySpec{
+				Notary: &v1alpha1.NotaryVerifier{URL: notaryURL},
+			},
+		},
+	}
+	if namespaceLabels != nil {
+		policy.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: namespaceLabels}
+	}
+	return policy
+}
@@ -1,13 +1,21 @@
 package validate_test
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/kyma-project/warden/internal/helpers"
 	"github.com/kyma-project/warden/internal/validate"
 	"github.com/kyma-project/warden/internal/validate/mocks"
 	"github.com/kyma-project/warden/pkg"
@@ -193,7 +201,7 @@ func Test_Validate_ImageWhichIsInNotaryButIsNotInRegistry_ShouldReturnError(t *t
 func Test_Validate_WhenNotaryNotResponding_ShouldReturnError(t *testing.T) {
 	//GIVE
 	f := &mocks.RepoFactory{}
-	f.On("NewRepoClient", mock.Anything, mock.Anything).Return(nil, errors.New("no such host"))
+	f.On("NewRepoClient", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("no such host"))
 	cfg := validate.ServiceConfig{NotaryConfig: validate.NotaryConfig{}}
 	s := validate.NewImageValidator(&cfg, f)
 
@@ -205,6 +213,61 @@ func Test_Validate_WhenNotaryNotResponding_ShouldReturnError(t *testing.T) {
 	require.Equal(t, pkg.UnknownResult, pkg.ErrorCode(err))
 }
 
+func Test_Validate_WhenNotaryCircuitBreakerIsOpen_ShouldFailFast(t *testing.T) {
+	//GIVE
+	f := &mocks.RepoFactory{}
+	f.On("NewRepoClient", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("no such host"))
+	breakers := validate.NewCircuitBreakers(validate.CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Hour})
+	cfg := validate.ServiceConfig{
+		NotaryConfig:         validate.NotaryConfig{Url: "https://notary.io"},
+		NotaryCircuitBreaker: breakers.Get("https://notary.io"),
+	}
+	s := validate.NewImageValidator(&cfg, f)
+
+	//WHEN
+	for i := 0; i < 2; i++ {
+		require.ErrorContains(t, s.Validate(context.TODO(), trustedImage.image(), emptyAuthData), "no such host")
+	}
+	err := s.Validate(context.TODO(), trustedImage.image(), emptyAuthData)
+
+	//THEN
+	require.ErrorIs(t, err, validate.ErrCircuitOpen)
+	require.Equal(t, pkg.UnknownResult, pkg.ErrorCode(err))
+	f.AssertNumberOfCalls(t, "NewRepoClient", 2)
+	require.Equal(t, validate.CircuitOpen, breakers.Get("https://notary.io").State())
+}
+
+func Test_Validate_WhenContextIsDone_ShouldCancelRegistryRequests(t *testing.T) {
+	//GIVE
+	testServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		select {
+		case <-request.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer testServer.Close()
+	image := fmt.Sprintf("%s/org/app:signed", strings.TrimPrefix(testServer.URL, "http://"))
+
+	notaryClient := &mocks.NotaryRepoClient{}
+	notaryClient.On("GetTargetByName", "signed").Return(&client.TargetWithRole{Target: client.Target{Name: "signed",
+		Hashes: map[string][]byte{"sha256": []byte("hash")}, Length: 1}}, nil)
+	f := &mocks.RepoFactory{}
+	f.On("NewRepoClient", mock.Anything, mock.Anything, mock.Anything).Return(notaryClient, nil)
+	s := validate.NewImageValidator(&validate.ServiceConfig{}, f)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+
+	//WHEN
+	err := s.Validate(ctx, image, emptyAuthData)
+
+	//THEN
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, pkg.UnknownResult, pkg.ErrorCode(err))
+	require.Less(t, time.Since(start), time.Second)
+}
+
 func Test_Validate_WhenRegistryNotResponding_ShouldReturnError(t *testing.T) {
 	//GIVE
 	notaryClient := &mocks.NotaryRepoClient{}
@@ -213,7 +276,7 @@ func Test_Validate_WhenRegistryNotResponding_ShouldReturnError(t *testing.T) {
 		Length: 1}}
 	notaryClient.On("GetTargetByName", "unknown").Return(response, nil)
 	f := &mocks.RepoFactory{}
-	f.On("NewRepoClient", mock.Anything, mock.Anything).Return(notaryClient, nil)
+	f.On("NewRepoClient", mock.Anything, mock.Anything, mock.Anything).Return(notaryClient, nil)
 	cfg := validate.ServiceConfig{NotaryConfig: validate.NotaryConfig{}}
 	s := validate.NewImageValidator(&cfg, f)
 
@@ -240,10 +303,10 @@ func Test_Validate_ImageWhichIsNotInNotaryButIsInAllowedList_ShouldPass(t *testi
 			},
 		},
 		{
-			name:      "image name prefix is allowed",
+			name:      "image name pattern is allowed",
 			imageName: "some-registry/allowed-image-name:stable",
 			allowedRegistries: []string{
-				"some-registry/allowed-",
+				"some-registry/allowed-*",
 			},
 		},
 		{
@@ -271,7 +334,7 @@ func Test_Validate_ImageWhichIsNotInNotaryButIsInAllowedList_ShouldPass(t *testi
 		},
 	}
 	f := &mocks.RepoFactory{}
-	f.On("NewRepoClient", mock.Anything, mock.Anything).Return(nil, errors.New("Should be called"))
+	f.On("NewRepoClient", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("Should be called"))
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -308,7 +371,7 @@ func Test_Validate_WhenNotaryRespondAfterLongTime_ShouldReturnError(t *testing.T
 			Url: testServer.URL,
 		},
 	}
-	f := validate.NotaryRepoFactory{timeout}
+	f := validate.NewNotaryRepoFactory(timeout)
 	validator := validate.NewImageValidator(sc, f)
 
 	//WHEN
@@ -335,7 +398,7 @@ func Test_Validate_WhenNotaryRespondWithError_ShouldReturnServiceNotAvailable(t
 			Url: testServer.URL,
 		},
 	}
-	f := validate.NotaryRepoFactory{Timeout: 5 * time.Second}
+	f := validate.NewNotaryRepoFactory(5 * time.Second)
 	validator := validate.NewImageValidator(sc, f)
 
 	//WHEN
@@ -350,7 +413,7 @@ func Test_Validate_DEV(t *testing.T) {
 	t.Skip("for testing and debugging real notary service")
 	sc := &validate.ServiceConfig{NotaryConfig: validate.NotaryConfig{
 		Url: "https://signing-dev.repositories.cloud.sap"}}
-	s := validate.NewImageValidator(sc, validate.NotaryRepoFactory{})
+	s := validate.NewImageValidator(sc, validate.NewNotaryRepoFactory(0))
 
 	//WHEN
 	err := s.Validate(context.TODO(), untrustedImage.image(), emptyAuthData)
@@ -359,6 +422,186 @@ func Test_Validate_DEV(t *testing.T) {
 	require.ErrorContains(t, err, "something")
 }
 
+func Test_Validate_DigestReference(t *testing.T) {
+	testServer := httptest.NewServer(registry.New())
+	defer testServer.Close()
+	registryHost := strings.TrimPrefix(testServer.URL, "http://")
+
+	signedImage := pushRandomImage(t, fmt.Sprintf("%s/digest:signed", registryHost))
+	otherImage := pushRandomImage(t, fmt.Sprintf("%s/digest:other", registryHost))
+	signedTarget := &client.TargetWithRole{Target: client.Target{Name: "signed",
+		Hashes: map[string][]byte{"sha256": hashBytes(t, signedImage.digest)},
+		Length: 1}}
+	otherTarget := &client.TargetWithRole{Target: client.Target{Name: "other",
+		Hashes: map[string][]byte{"sha256": hashBytes(t, otherImage.digest)},
+		Length: 1}}
+
+	tests := []struct {
+		name    string
+		image   string
+		targets []*client.TargetWithRole
+		wantErr string
+	}{
+		{
+			name:    "signed digest",
+			image:   signedImage.ref.Context().Digest(signedImage.digest.String()).String(),
+			targets: []*client.TargetWithRole{otherTarget, signedTarget},
+		},
+		{
+			name:    "not signed digest",
+			image:   signedImage.ref.Context().Digest(signedImage.digest.String()).String(),
+			targets: []*client.TargetWithRole{otherTarget},
+			wantErr: "image digest is not signed",
+		},
+		{
+			name:  "tag and digest of the same image",
+			image: fmt.Sprintf("%s@%s", signedImage.ref.String(), signedImage.digest.String()),
+		},
+		{
+			name:    "tag and digest of different images",
+			image:   fmt.Sprintf("%s@%s", signedImage.ref.String(), otherImage.digest.String()),
+			wantErr: "image tag and digest point to different images",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			notaryClient := &mocks.NotaryRepoClient{}
+			notaryClient.On("ListTargets").Return(tt.targets, nil).Maybe()
+			notaryClient.On("GetTargetByName", "signed").Return(signedTarget, nil).Maybe()
+			f := &mocks.RepoFactory{}
+			f.On("NewRepoClient", mock.Anything, mock.Anything, mock.Anything).Return(notaryClient, nil)
+			cfg := validate.ServiceConfig{NotaryConfig: validate.NotaryConfig{}}
+			s := validate.NewImageValidator(&cfg, f)
+
+			//WHEN
+			err := s.Validate(context.TODO(), tt.image, emptyAuthData)
+
+			//THEN
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+			require.Equal(t, pkg.ValidationError, pkg.ErrorCode(err))
+		})
+	}
+}
+
+func Test_Validate_PrivateRegistryCredentials(t *testing.T) {
+	var protected atomic.Bool
+	registryHandler := registry.New()
+	testServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if username, password, ok := request.BasicAuth(); protected.Load() && (!ok || username != "user" || password != "password") {
+			writer.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		registryHandler.ServeHTTP(writer, request)
+	}))
+	defer testServer.Close()
+	registryHost := strings.TrimPrefix(testServer.URL, "http://")
+
+	image := pushRandomImage(t, fmt.Sprintf("%s/org/private:signed", registryHost))
+	protected.Store(true)
+	signedTarget := &client.TargetWithRole{Target: client.Target{Name: "signed",
+		Hashes: map[string][]byte{"sha256": hashBytes(t, image.digest)},
+		Length: 1}}
+
+	validCredentials := cliType.AuthConfig{Username: "user", Password: "password"}
+	invalidCredentials := cliType.AuthConfig{Username: "user", Password: "wrong"}
+
+	tests := []struct {
+		name                string
+		credentials         map[string]cliType.AuthConfig
+		providerCredentials map[string]cliType.AuthConfig
+		wantErr             string
+	}{
+		{
+			name:    "no credentials",
+			wantErr: "get image descriptor anonymously",
+		},
+		{
+			name:        "credentials of the registry",
+			credentials: map[string]cliType.AuthConfig{"https://" + registryHost + "/": validCredentials},
+		},
+		{
+			name:        "credentials of the repository path",
+			credentials: map[string]cliType.AuthConfig{registryHost + "/org": validCredentials},
+		},
+		{
+			name: "invalid credentials of the more specific path are tried first",
+			credentials: map[string]cliType.AuthConfig{
+				registryHost + "/org/private": invalidCredentials,
+				registryHost:                  validCredentials,
+			},
+		},
+		{
+			name:        "credentials of the different path",
+			credentials: map[string]cliType.AuthConfig{registryHost + "/other-org": validCredentials},
+			wantErr:     "get image descriptor anonymously",
+		},
+		{
+			name:        "invalid credentials",
+			credentials: map[string]cliType.AuthConfig{registryHost: invalidCredentials},
+			wantErr:     "get image descriptor: ",
+		},
+		{
+			name:                "credentials of the credential provider",
+			providerCredentials: map[string]cliType.AuthConfig{registryHost: validCredentials},
+		},
+		{
+			name:                "credentials of the credential provider are tried after invalid pull secret credentials",
+			credentials:         map[string]cliType.AuthConfig{registryHost + "/org": invalidCredentials},
+			providerCredentials: map[string]cliType.AuthConfig{registryHost: validCredentials},
+		},
+		{
+			name:                "invalid credentials of the credential provider",
+			providerCredentials: map[string]cliType.AuthConfig{registryHost: invalidCredentials},
+			wantErr:             "get image descriptor: ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			notaryClient := &mocks.NotaryRepoClient{}
+			notaryClient.On("GetTargetByName", "signed").Return(signedTarget, nil)
+			f := &mocks.RepoFactory{}
+			f.On("NewRepoClient", mock.Anything, mock.Anything, mock.Anything).Return(notaryClient, nil)
+			credentialProvider := &mocks.CredentialProvider{}
+			credentialProvider.On("Provide", mock.Anything, mock.Anything).Return(fixRegistryCredentials(tt.providerCredentials))
+			s := validate.NewImageValidator(&validate.ServiceConfig{CredentialProvider: credentialProvider}, f)
+
+			credentials := fixRegistryCredentials(tt.credentials)
+
+			//WHEN
+			err := s.Validate(context.TODO(), image.ref.String(), credentials)
+
+			//THEN
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+			require.Equal(t, pkg.UnknownResult, pkg.ErrorCode(err))
+		})
+	}
+}
+
+func fixRegistryCredentials(auths map[string]cliType.AuthConfig) helpers.RegistryCredentials {
+	credentials := helpers.RegistryCredentials{}
+	for registry, auth := range auths {
+		credentials.Add(registry, auth)
+	}
+	return credentials
+}
+
+func hashBytes(t *testing.T, hash v1.Hash) []byte {
+	bytes, err := hex.DecodeString(hash.Hex)
+	require.NoError(t, err)
+	return bytes
+}
+
 func setupMockFactory() validate.RepoFactory {
 	notaryClient := &mocks.NotaryRepoClient{}
 
@@ -395,7 +638,7 @@ func setupMockFactory() validate.RepoFactory {
 		Return(nil, fmt.Errorf("does not have trust data for %s", untrustedImage.name))
 
 	f := &mocks.RepoFactory{}
-	f.On("NewRepoClient", mock.Anything, mock.Anything).Return(notaryClient, nil)
+	f.On("NewRepoClient", mock.Anything, mock.Anything, mock.Anything).Return(notaryClient, nil)
 
 	return f
 }
@@ -5,7 +5,7 @@ package mocks
 import (
 	context "context"
 
-	types "github.com/docker/cli/cli/config/types"
+	helpers "github.com/kyma-project/warden/internal/helpers"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -15,7 +15,7 @@ type ImageValidatorService struct {
 }
 
 // Validate provides a mock function with given fields: ctx, image, imagePullCredentials
-func (_m *ImageValidatorService) Validate(ctx context.Context, image string, imagePullCredentials map[string]types.AuthConfig) error {
+func (_m *ImageValidatorService) Validate(ctx context.Context, image string, imagePullCredentials helpers.RegistryCredentials) error {
 	ret := _m.Called(ctx, image, imagePullCredentials)
 
 	if len(ret) == 0 {
@@ -23,7 +23,7 @@ func (_m *ImageValidatorService) Validate(ctx context.Context, image string, ima
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, map[string]types.AuthConfig) error); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string, helpers.RegistryCredentials) error); ok {
 		r0 = rf(ctx, image, imagePullCredentials)
 	} else {
 		r0 = ret.Error(0)
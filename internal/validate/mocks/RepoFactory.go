@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	validate "github.com/kyma-project/warden/internal/validate"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,9 +14,9 @@ type RepoFactory struct {
 	mock.Mock
 }
 
-// NewRepoClient provides a mock function with given fields: _a0, _a1
-func (_m *RepoFactory) NewRepoClient(_a0 string, _a1 validate.NotaryConfig) (validate.NotaryRepoClient, error) {
-	ret := _m.Called(_a0, _a1)
+// NewRepoClient provides a mock function with given fields: _a0, _a1, _a2
+func (_m *RepoFactory) NewRepoClient(_a0 context.Context, _a1 string, _a2 validate.NotaryConfig) (validate.NotaryRepoClient, error) {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	if len(ret) == 0 {
 		panic("no return value specified for NewRepoClient")
@@ -22,19 +24,19 @@ func (_m *RepoFactory) NewRepoClient(_a0 string, _a1 validate.NotaryConfig) (val
 
 	var r0 validate.NotaryRepoClient
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, validate.NotaryConfig) (validate.NotaryRepoClient, error)); ok {
-		return rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string, validate.NotaryConfig) (validate.NotaryRepoClient, error)); ok {
+		return rf(_a0, _a1, _a2)
 	}
-	if rf, ok := ret.Get(0).(func(string, validate.NotaryConfig) validate.NotaryRepoClient); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string, validate.NotaryConfig) validate.NotaryRepoClient); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(validate.NotaryRepoClient)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, validate.NotaryConfig) error); ok {
-		r1 = rf(_a0, _a1)
+	if rf, ok := ret.Get(1).(func(context.Context, string, validate.NotaryConfig) error); ok {
+		r1 = rf(_a0, _a1, _a2)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -3,11 +3,8 @@
 package mocks
 
 import (
-	time "time"
-
-	mock "github.com/stretchr/testify/mock"
-
 	validate "github.com/kyma-project/warden/internal/validate"
+	mock "github.com/stretchr/testify/mock"
 )
 
 // ValidatorSvcFactory is an autogenerated mock type for the ValidatorSvcFactory type
@@ -15,17 +12,17 @@ type ValidatorSvcFactory struct {
 	mock.Mock
 }
 
-// NewValidatorSvc provides a mock function with given fields: notaryURL, notaryAllowedRegistries, notaryTimeout
-func (_m *ValidatorSvcFactory) NewValidatorSvc(notaryURL string, notaryAllowedRegistries string, notaryTimeout time.Duration) validate.PodValidator {
-	ret := _m.Called(notaryURL, notaryAllowedRegistries, notaryTimeout)
+// NewValidatorSvc provides a mock function with given fields: config
+func (_m *ValidatorSvcFactory) NewValidatorSvc(config validate.ValidatorSvcConfig) validate.PodValidator {
+	ret := _m.Called(config)
 
 	if len(ret) == 0 {
 		panic("no return value specified for NewValidatorSvc")
 	}
 
 	var r0 validate.PodValidator
-	if rf, ok := ret.Get(0).(func(string, string, time.Duration) validate.PodValidator); ok {
-		r0 = rf(notaryURL, notaryAllowedRegistries, notaryTimeout)
+	if rf, ok := ret.Get(0).(func(validate.ValidatorSvcConfig) validate.PodValidator); ok {
+		r0 = rf(config)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(validate.PodValidator)
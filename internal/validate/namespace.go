@@ -13,7 +13,8 @@ func IsValidationEnabledForNS(ns *corev1.Namespace) bool {
 func IsSupportedValidationLabelValue(value string) bool {
 	return value == pkg.NamespaceValidationEnabled ||
 		value == pkg.NamespaceValidationSystem ||
-		value == pkg.NamespaceValidationUser
+		value == pkg.NamespaceValidationUser ||
+		value == pkg.NamespaceValidationWarn
 }
 
 func IsUserValidationForNS(ns *corev1.Namespace) bool {
@@ -21,12 +22,23 @@ func IsUserValidationForNS(ns *corev1.Namespace) bool {
 	return value == pkg.NamespaceValidationUser
 }
 
+func IsWarnValidationForNS(ns *corev1.Namespace) bool {
+	value := ns.GetLabels()[pkg.NamespaceValidationLabel]
+	return value == pkg.NamespaceValidationWarn
+}
+
+// isSystemValidationLabelValue checks if the value selects the system validator
+func isSystemValidationLabelValue(value string) bool {
+	return value == pkg.NamespaceValidationEnabled ||
+		value == pkg.NamespaceValidationSystem ||
+		value == pkg.NamespaceValidationWarn
+}
+
 func IsChangedSupportedValidationLabelValue(oldValue, newValue string) bool {
 	if !IsSupportedValidationLabelValue(oldValue) && !IsSupportedValidationLabelValue(newValue) {
 		return false
 	}
-	if (oldValue == pkg.NamespaceValidationEnabled || oldValue == pkg.NamespaceValidationSystem) &&
-		(newValue == pkg.NamespaceValidationEnabled || newValue == pkg.NamespaceValidationSystem) {
+	if isSystemValidationLabelValue(oldValue) && isSystemValidationLabelValue(newValue) {
 		return false
 	}
 	return oldValue != newValue
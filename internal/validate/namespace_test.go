@@ -33,6 +33,11 @@ func TestNamespaceLabelsValidation(t *testing.T) {
 			namespaceLabels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationUser},
 			success:         true,
 		},
+		{
+			name:            "namespace has validation enabled (warn)",
+			namespaceLabels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationWarn},
+			success:         true,
+		},
 		{
 			name:            "namespace has validation disabled (invalid)",
 			namespaceLabels: map[string]string{pkg.NamespaceValidationLabel: "invalid"},
@@ -151,6 +156,18 @@ func TestIsChangedSupportedValidationLabelValue(t *testing.T) {
 			newValue: pkg.NamespaceValidationSystem,
 			want:     false,
 		},
+		{
+			name:     "not changed (System->Warn - same validator)",
+			oldValue: pkg.NamespaceValidationSystem,
+			newValue: pkg.NamespaceValidationWarn,
+			want:     false,
+		},
+		{
+			name:     "changed (Warn->User)",
+			oldValue: pkg.NamespaceValidationWarn,
+			newValue: pkg.NamespaceValidationUser,
+			want:     true,
+		},
 		{
 			name:     "changed (System->unsupported)",
 			oldValue: pkg.NamespaceValidationSystem,
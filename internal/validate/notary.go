@@ -17,6 +17,7 @@ limitations under the License.
 package validate
 
 import (
+	"context"
 	"github.com/docker/distribution/registry/client/auth"
 	"github.com/docker/distribution/registry/client/auth/challenge"
 	"github.com/docker/distribution/registry/client/transport"
@@ -27,15 +28,28 @@ import (
 	"github.com/theupdateframework/notary/tuf/data"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 )
 
 const (
 	NotaryDefaultTrustDir = "/tmp/.notary"
+
+	// notaryChallengesTTL limits how long auth challenges of the notary server are reused,
+	// they're obtained again earlier if the server rejects the credentials
+	notaryChallengesTTL = time.Hour
+	// maxNotaryServers and maxNotaryRepos limit the number of cached notary servers and repositories of a server,
+	// the least recently used ones are evicted, because notary URLs and images come from user resources
+	maxNotaryServers = 100
+	maxNotaryRepos   = 1000
+	// notaryDialTimeout limits connecting to the notary server, requests are also limited by the timeout of the config
+	notaryDialTimeout = 30 * time.Second
 )
 
 type NotaryConfig struct {
 	Url string `json:"url"`
+	// Timeout limits the ping and every trust data lookup, the timeout of the factory is used if it's 0
+	Timeout time.Duration `json:"timeout,omitempty"`
 }
 
 type NotaryValidator struct {
@@ -48,41 +62,251 @@ type NotaryRepoClient interface {
 
 //go:generate mockery --name RepoFactory
 type RepoFactory interface {
-	NewRepoClient(string, NotaryConfig) (NotaryRepoClient, error)
+	NewRepoClient(context.Context, string, NotaryConfig) (NotaryRepoClient, error)
 }
 
+// NotaryRepoFactory creates notary repository clients reusing connections, auth challenges and repositories,
+// it's safe for concurrent use and should be long-lived, servers are shared by configs with different timeouts,
+// so the number of cached servers and repositories is bounded for all configs
 type NotaryRepoFactory struct {
+	// Timeout is the default timeout of requests to the notary server, requests aren't limited if it's 0
 	Timeout time.Duration
+
+	mutex   sync.Mutex
+	servers map[string]*notaryServer
+}
+
+// notaryServer keeps state shared by all repositories of a single notary server
+type notaryServer struct {
+	url       string
+	transport *http.Transport
+	// lastUsed is guarded by the mutex of the factory
+	lastUsed time.Time
+
+	mutex              sync.Mutex
+	ping               *notaryPing
+	challenges         challenge.Manager
+	challengesExpireAt time.Time
+	repos              map[data.GUN]*lockedRepoClient
+}
+
+// notaryPing is the ping of the notary server shared by all lookups waiting for the auth challenges,
+// it's cancelled when all of them give up
+type notaryPing struct {
+	done       chan struct{}
+	cancel     context.CancelFunc
+	waiting    int
+	challenges challenge.Manager
+	err        error
+}
+
+// lockedRepoClient serializes trust data lookups, because the notary repository updates
+// its local TUF metadata on every lookup and isn't safe for concurrent use
+type lockedRepoClient struct {
+	NotaryRepoClient
+	// semaphore is used instead of a mutex, so lookups waiting for the repository can be cancelled
+	semaphore chan struct{}
+	transport *contextTransport
+	// lastUsed is guarded by the mutex of the server
+	lastUsed time.Time
+}
+
+// contextRepoClient sends requests of trust data lookups of the shared repository with the context of the validation,
+// so lookups of timed-out validations are cancelled
+type contextRepoClient struct {
+	*lockedRepoClient
+	ctx     context.Context
+	timeout time.Duration
+}
+
+// contextTransport sends requests with the context of the current lookup, the context is set
+// only while the lookup holds the repository lock, so it's never shared by concurrent lookups
+type contextTransport struct {
+	http.RoundTripper
+	ctx context.Context
+	// onUnauthorized is called when the server rejects the credentials of the request
+	onUnauthorized func()
+}
+
+func NewNotaryRepoFactory(timeout time.Duration) *NotaryRepoFactory {
+	return &NotaryRepoFactory{Timeout: timeout}
+}
+
+// NewRepoClient returns the client of the notary repository, all its requests are cancelled with the context
+// and every lookup is limited by the timeout of the config
+func (f *NotaryRepoFactory) NewRepoClient(ctx context.Context, img string, c NotaryConfig) (NotaryRepoClient, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = f.Timeout
+	}
+	pingCtx, cancel := withOptionalTimeout(ctx, timeout)
+	defer cancel()
+	repo, err := f.getServer(c.Url).getRepoClient(pingCtx, data.GUN(img), timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &contextRepoClient{lockedRepoClient: repo, ctx: ctx, timeout: timeout}, nil
 }
 
-func (f NotaryRepoFactory) NewRepoClient(img string, c NotaryConfig) (NotaryRepoClient, error) {
-	base := &http.Transport{
-		Proxy:               http.ProxyFromEnvironment,
-		TLSHandshakeTimeout: 10 * time.Second,
-		DialContext: (&net.Dialer{
-			Timeout:   f.Timeout,
-			KeepAlive: f.Timeout,
-		}).DialContext,
-		DisableKeepAlives: true,
+func (f *NotaryRepoFactory) getServer(url string) *notaryServer {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.servers == nil {
+		f.servers = map[string]*notaryServer{}
+	}
+	server, ok := f.servers[url]
+	if !ok {
+		if len(f.servers) >= maxNotaryServers {
+			evicted := evictLeastRecentlyUsed(f.servers, func(s *notaryServer) time.Time { return s.lastUsed })
+			evicted.transport.CloseIdleConnections()
+		}
+		server = newNotaryServer(url)
+		f.servers[url] = server
+	}
+	server.lastUsed = time.Now()
+	return server
+}
+
+func newNotaryServer(url string) *notaryServer {
+	return &notaryServer{
+		url: url,
+		transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			TLSHandshakeTimeout: 10 * time.Second,
+			DialContext: (&net.Dialer{
+				Timeout:   notaryDialTimeout,
+				KeepAlive: notaryDialTimeout,
+			}).DialContext,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		repos: map[data.GUN]*lockedRepoClient{},
+	}
+}
+
+func (s *notaryServer) getRepoClient(ctx context.Context, gun data.GUN, timeout time.Duration) (*lockedRepoClient, error) {
+	challenges, err := s.getChallenges(ctx, timeout)
+	if err != nil {
+		return nil, err
 	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if repo, ok := s.repos[gun]; ok {
+		repo.lastUsed = time.Now()
+		return repo, nil
+	}
+
+	ctxTransport := &contextTransport{RoundTripper: s.transport, onUnauthorized: s.invalidateChallenges}
+	// token handler caches the token until it expires
 	th := auth.NewTokenHandlerWithOptions(auth.TokenHandlerOptions{
-		Transport: base,
+		Transport: ctxTransport,
 		Scopes: []auth.Scope{
 			auth.RepositoryScope{
-				Repository: img,
+				Repository: gun.String(),
 				Actions:    []string{"pull"},
 			},
 		},
 	})
+	modifier := auth.NewAuthorizer(challenges, th)
+	repo, err := client.NewFileCachedRepository(NotaryDefaultTrustDir, gun, s.url, transport.NewTransport(ctxTransport, modifier), nil, trustpinning.TrustPinConfig{})
+	if err != nil {
+		return nil, err
+	}
 
+	lockedRepo := &lockedRepoClient{
+		NotaryRepoClient: repo,
+		semaphore:        make(chan struct{}, 1),
+		transport:        ctxTransport,
+		lastUsed:         time.Now(),
+	}
+	// the repository isn't cached if the server was pinged again in the meantime
+	if s.challenges != challenges {
+		return lockedRepo, nil
+	}
+	if len(s.repos) >= maxNotaryRepos {
+		evictLeastRecentlyUsed(s.repos, func(r *lockedRepoClient) time.Time { return r.lastUsed })
+	}
+	s.repos[gun] = lockedRepo
+	return lockedRepo, nil
+}
+
+// getChallenges returns the cached auth challenges or waits for the ping of the server without holding the lock,
+// so lookups of other repositories of the server aren't blocked by a slow server
+func (s *notaryServer) getChallenges(ctx context.Context, timeout time.Duration) (challenge.Manager, error) {
+	s.mutex.Lock()
+	if s.challenges != nil && time.Now().Before(s.challengesExpireAt) {
+		challenges := s.challenges
+		s.mutex.Unlock()
+		return challenges, nil
+	}
+	ping := s.ping
+	if ping == nil {
+		ping = s.startPing(ctx, timeout)
+	}
+	ping.waiting++
+	s.mutex.Unlock()
+
+	select {
+	case <-ping.done:
+		return ping.challenges, ping.err
+	case <-ctx.Done():
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		ping.waiting--
+		if ping.waiting == 0 && s.ping == ping {
+			ping.cancel()
+			s.ping = nil
+		}
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(ctx.Err(), "while waiting for notary ping"))
+	}
+}
+
+// startPing pings the server in the background with the timeout of the first lookup, it must be called with the lock held
+func (s *notaryServer) startPing(ctx context.Context, timeout time.Duration) *notaryPing {
+	// the ping is shared, so it's cancelled when all waiting lookups give up and not with the context of the first one
+	pingCtx, cancel := withOptionalTimeout(context.WithoutCancel(ctx), timeout)
+	ping := &notaryPing{done: make(chan struct{}), cancel: cancel}
+	s.ping = ping
+	go func() {
+		defer cancel()
+		challenges, err := s.doPing(pingCtx)
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		ping.challenges, ping.err = challenges, err
+		if s.ping == ping {
+			s.ping = nil
+			if err == nil {
+				// repositories authorize requests with the challenges they were created with
+				s.challenges = challenges
+				s.challengesExpireAt = time.Now().Add(notaryChallengesTTL)
+				s.repos = map[data.GUN]*lockedRepoClient{}
+			}
+		}
+		close(ping.done)
+	}()
+	return ping
+}
+
+// invalidateChallenges makes the next lookup ping the server again, the auth challenges could have changed
+func (s *notaryServer) invalidateChallenges() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.challenges = nil
+}
+
+// doPing obtains auth challenges of the notary server
+func (s *notaryServer) doPing(ctx context.Context) (challenge.Manager, error) {
 	// challenge manager expects to connect to /v2/ endpoint to obtain the challenges:
 	// https://github.com/notaryproject/notary/blob/master/vendor/github.com/docker/distribution/registry/client/auth/session.go#L75
-	u := c.Url + "/v2/"
+	u := s.url + "/v2/"
 	pingClient := &http.Client{
-		Transport: base,
-		Timeout:   f.Timeout,
+		Transport: s.transport,
 	}
-	req, err := http.NewRequest("GET", u, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +328,82 @@ func (f NotaryRepoFactory) NewRepoClient(img string, c NotaryConfig) (NotaryRepo
 	if err = cm.AddResponse(resp); err != nil {
 		return nil, err
 	}
-	modifier := auth.NewAuthorizer(cm, th)
-	return client.NewFileCachedRepository(NotaryDefaultTrustDir, data.GUN(img), c.Url, transport.NewTransport(base, modifier), nil, trustpinning.TrustPinConfig{})
+	return cm, nil
+}
+
+func (r *contextRepoClient) GetTargetByName(name string, roles ...data.RoleName) (*client.TargetWithRole, error) {
+	unlock, err := r.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.NotaryRepoClient.GetTargetByName(name, roles...)
+}
+
+func (r *contextRepoClient) ListTargets(roles ...data.RoleName) ([]*client.TargetWithRole, error) {
+	unlock, err := r.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.NotaryRepoClient.ListTargets(roles...)
+}
+
+func (r *contextRepoClient) GetAllTargetMetadataByName(name string) ([]client.TargetSignedStruct, error) {
+	unlock, err := r.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.NotaryRepoClient.GetAllTargetMetadataByName(name)
+}
+
+// lock locks the repository and sets the context of its requests limited by the timeout, the returned function unlocks it,
+// it fails if the context is done before the lookups of other validations are finished
+func (r *contextRepoClient) lock() (func(), error) {
+	select {
+	case r.semaphore <- struct{}{}:
+	case <-r.ctx.Done():
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(r.ctx.Err(), "while waiting for notary repository"))
+	}
+	ctx, cancel := withOptionalTimeout(r.ctx, r.timeout)
+	r.transport.ctx = ctx
+	return func() {
+		r.transport.ctx = nil
+		cancel()
+		<-r.semaphore
+	}, nil
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.ctx != nil {
+		req = req.WithContext(t.ctx)
+	}
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && t.onUnauthorized != nil {
+		t.onUnauthorized()
+	}
+	return resp, err
+}
+
+// withOptionalTimeout returns the context limited by the timeout, the context isn't limited if the timeout is 0
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// evictLeastRecentlyUsed removes the entry which wasn't used for the longest time and returns it
+func evictLeastRecentlyUsed[K comparable, V any](entries map[K]V, lastUsed func(V) time.Time) V {
+	var oldestKey K
+	var oldest V
+	first := true
+	for key, entry := range entries {
+		if first || lastUsed(entry).Before(lastUsed(oldest)) {
+			oldestKey, oldest, first = key, entry, false
+		}
+	}
+	delete(entries, oldestKey)
+	return oldest
 }
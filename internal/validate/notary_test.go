@@ -1,10 +1,12 @@
 package validate
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -14,8 +16,8 @@ func TestNewReadOnlyRepo(t *testing.T) {
 	nc := NotaryConfig{
 		Url: "https://signing-dev.repositories.cloud.sap",
 	}
-	f := NotaryRepoFactory{}
-	c, err := f.NewRepoClient("europe-docker.pkg.dev/kyma-project/dev/bootstrap", nc)
+	f := NewNotaryRepoFactory(0)
+	c, err := f.NewRepoClient(context.Background(), "europe-docker.pkg.dev/kyma-project/dev/bootstrap", nc)
 	require.NoError(t, err)
 
 	name, err := c.GetTargetByName("PR-6200")
@@ -40,10 +42,10 @@ func TestNotaryHTTPTimeout(t *testing.T) {
 	nc := NotaryConfig{
 		Url: testServer.URL,
 	}
-	f := NotaryRepoFactory{Timeout: time.Second}
+	f := NewNotaryRepoFactory(time.Second)
 
 	//WHEN
-	_, err := f.NewRepoClient("europe-docker.pkg.dev/kyma-project/dev/bootstrap", nc)
+	_, err := f.NewRepoClient(context.Background(), "europe-docker.pkg.dev/kyma-project/dev/bootstrap", nc)
 
 	//THEn
 	require.Error(t, err)
@@ -51,3 +53,265 @@ func TestNotaryHTTPTimeout(t *testing.T) {
 	require.InDelta(t, timeout.Milliseconds(), time.Since(start).Milliseconds(), 100, "timeout duration is not respected")
 
 }
+
+func TestNotaryRepoFactoryReusesRepositories(t *testing.T) {
+	//GIVEN
+	pings := 0
+	h := func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/v2/" {
+			pings++
+		}
+	}
+	testServer := httptest.NewServer(http.HandlerFunc(h))
+	defer testServer.Close()
+
+	nc := NotaryConfig{
+		Url: testServer.URL,
+	}
+	f := NewNotaryRepoFactory(time.Second)
+
+	//WHEN
+	first, err := f.NewRepoClient(context.Background(), "europe-docker.pkg.dev/kyma-project/dev/first", nc)
+	require.NoError(t, err)
+	again, err := f.NewRepoClient(context.Background(), "europe-docker.pkg.dev/kyma-project/dev/first", nc)
+	require.NoError(t, err)
+	second, err := f.NewRepoClient(context.Background(), "europe-docker.pkg.dev/kyma-project/dev/second", nc)
+	require.NoError(t, err)
+
+	//THEN
+	require.Same(t, first.(*contextRepoClient).lockedRepoClient, again.(*contextRepoClient).lockedRepoClient)
+	require.NotSame(t, first.(*contextRepoClient).lockedRepoClient, second.(*contextRepoClient).lockedRepoClient)
+	require.Equal(t, 1, pings)
+}
+
+func TestNotaryRepoFactoryDoesNotCacheFailedPing(t *testing.T) {
+	//GIVEN
+	pings := 0
+	h := func(writer http.ResponseWriter, request *http.Request) {
+		pings++
+		if pings == 1 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}
+	testServer := httptest.NewServer(http.HandlerFunc(h))
+	defer testServer.Close()
+
+	nc := NotaryConfig{
+		Url: testServer.URL,
+	}
+	f := NewNotaryRepoFactory(time.Second)
+
+	//WHEN
+	_, firstErr := f.NewRepoClient(context.Background(), "europe-docker.pkg.dev/kyma-project/dev/bootstrap", nc)
+	_, secondErr := f.NewRepoClient(context.Background(), "europe-docker.pkg.dev/kyma-project/dev/bootstrap", nc)
+
+	//THEN
+	require.ErrorContains(t, firstErr, "couldn't correctly connect to notary, status code: 503")
+	require.NoError(t, secondErr)
+	require.Equal(t, 2, pings)
+}
+
+func TestNotaryRepoFactoryCancelsRequestsWithContext(t *testing.T) {
+	// the server answers the ping, but it doesn't answer trust data requests until they're cancelled
+	var pingOnly atomic.Bool
+	h := func(writer http.ResponseWriter, request *http.Request) {
+		if pingOnly.Load() && request.URL.Path == "/v2/" {
+			return
+		}
+		select {
+		case <-request.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}
+	testServer := httptest.NewServer(http.HandlerFunc(h))
+	defer testServer.Close()
+
+	nc := NotaryConfig{
+		Url: testServer.URL,
+	}
+	f := NewNotaryRepoFactory(10 * time.Second)
+
+	t.Run("ping", func(t *testing.T) {
+		//GIVEN
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+
+		//WHEN
+		_, err := f.NewRepoClient(ctx, "europe-docker.pkg.dev/kyma-project/dev/bootstrap", nc)
+
+		//THEN
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("trust data lookup", func(t *testing.T) {
+		//GIVEN
+		pingOnly.Store(true)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		c, err := f.NewRepoClient(ctx, "europe-docker.pkg.dev/kyma-project/dev/bootstrap", nc)
+		require.NoError(t, err)
+		start := time.Now()
+
+		//WHEN
+		_, err = c.GetTargetByName("1.0")
+
+		//THEN
+		require.Error(t, err)
+		require.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestNotaryRepoFactorySharesPing(t *testing.T) {
+	//GIVEN
+	var pings atomic.Int32
+	pinged := make(chan struct{})
+	release := make(chan struct{})
+	h := func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/v2/" && pings.Add(1) == 1 {
+			close(pinged)
+			<-release
+		}
+	}
+	testServer := httptest.NewServer(http.HandlerFunc(h))
+	defer testServer.Close()
+
+	nc := NotaryConfig{
+		Url: testServer.URL,
+	}
+	f := NewNotaryRepoFactory(10 * time.Second)
+
+	errs := make(chan error, 3)
+	for _, img := range []string{"first", "second", "third"} {
+		go func() {
+			_, err := f.NewRepoClient(context.Background(), "europe-docker.pkg.dev/kyma-project/dev/"+img, nc)
+			errs <- err
+		}()
+	}
+	<-pinged
+
+	//WHEN
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, timedOutErr := f.NewRepoClient(ctx, "europe-docker.pkg.dev/kyma-project/dev/fourth", nc)
+	close(release)
+
+	//THEN
+	require.ErrorIs(t, timedOutErr, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+	for range 3 {
+		require.NoError(t, <-errs)
+	}
+	require.Equal(t, int32(1), pings.Load())
+}
+
+func TestNotaryRepoFactoryPingsAgainAfterUnauthorized(t *testing.T) {
+	//GIVEN
+	pings := 0
+	h := func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/v2/" {
+			pings++
+			return
+		}
+		writer.WriteHeader(http.StatusUnauthorized)
+	}
+	testServer := httptest.NewServer(http.HandlerFunc(h))
+	defer testServer.Close()
+
+	nc := NotaryConfig{
+		Url: testServer.URL,
+	}
+	f := NewNotaryRepoFactory(time.Second)
+	first, err := f.NewRepoClient(context.Background(), "europe-docker.pkg.dev/kyma-project/dev/unauthorized", nc)
+	require.NoError(t, err)
+
+	//WHEN
+	_, lookupErr := first.GetTargetByName("1.0")
+	again, err := f.NewRepoClient(context.Background(), "europe-docker.pkg.dev/kyma-project/dev/unauthorized", nc)
+
+	//THEN
+	require.Error(t, lookupErr)
+	require.NoError(t, err)
+	require.NotSame(t, first.(*contextRepoClient).lockedRepoClient, again.(*contextRepoClient).lockedRepoClient)
+	require.Equal(t, 2, pings)
+}
+
+func TestNotaryRepoClientLockRespectsContext(t *testing.T) {
+	//GIVEN
+	testServer := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer testServer.Close()
+
+	nc := NotaryConfig{
+		Url: testServer.URL,
+	}
+	f := NewNotaryRepoFactory(time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	c, err := f.NewRepoClient(ctx, "europe-docker.pkg.dev/kyma-project/dev/locked", nc)
+	require.NoError(t, err)
+	// another lookup holds the repository
+	c.(*contextRepoClient).semaphore <- struct{}{}
+	start := time.Now()
+
+	//WHEN
+	_, err = c.GetTargetByName("1.0")
+
+	//THEN
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestEvictLeastRecentlyUsed(t *testing.T) {
+	//GIVEN
+	now := time.Now()
+	entries := map[string]time.Time{
+		"recent": now,
+		"oldest": now.Add(-2 * time.Minute),
+		"old":    now.Add(-time.Minute),
+	}
+
+	//WHEN
+	evicted := evictLeastRecentlyUsed(entries, func(lastUsed time.Time) time.Time { return lastUsed })
+
+	//THEN
+	require.Equal(t, now.Add(-2*time.Minute), evicted)
+	require.Equal(t, map[string]time.Time{"recent": now, "old": now.Add(-time.Minute)}, entries)
+}
+
+func TestNotaryRepoFactorySharesServerBetweenTimeouts(t *testing.T) {
+	//GIVEN
+	pings := 0
+	h := func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/v2/" {
+			pings++
+			return
+		}
+		select {
+		case <-request.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}
+	testServer := httptest.NewServer(http.HandlerFunc(h))
+	defer testServer.Close()
+
+	f := NewNotaryRepoFactory(10 * time.Second)
+	short, err := f.NewRepoClient(context.Background(), "europe-docker.pkg.dev/kyma-project/dev/bootstrap",
+		NotaryConfig{Url: testServer.URL, Timeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	long, err := f.NewRepoClient(context.Background(), "europe-docker.pkg.dev/kyma-project/dev/bootstrap",
+		NotaryConfig{Url: testServer.URL, Timeout: time.Minute})
+	require.NoError(t, err)
+	start := time.Now()
+
+	//WHEN
+	_, lookupErr := short.GetTargetByName("1.0")
+
+	//THEN
+	require.Error(t, lookupErr)
+	require.Less(t, time.Since(start), time.Second)
+	require.Len(t, f.servers, 1)
+	require.Same(t, short.(*contextRepoClient).lockedRepoClient, long.(*contextRepoClient).lockedRepoClient)
+	require.Equal(t, 1, pings)
+}
@@ -0,0 +1,401 @@
+package validate
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/logging/tracing"
+	"github.com/kyma-project/warden/internal/metrics"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	NotationSignatureArtifactType = "application/vnd.cncf.notary.signature"
+	NotationJWSMediaType          = "application/jose+json"
+	NotationPayloadContentType    = "application/vnd.cncf.notary.payload.v1+json"
+	NotationSigningSchemeX509     = "notary.x509"
+	notationMaxEnvelopeSize       = 1 << 20
+
+	notationHeaderSigningScheme = "io.cncf.notary.signingScheme"
+	notationHeaderExpiry        = "io.cncf.notary.expiry"
+)
+
+// notationSupportedCriticalHeaders are processed by the verification, envelopes with other critical headers
+// (e.g. verification plugins) have to be rejected, because their requirements would be ignored
+// https://github.com/notaryproject/specifications/blob/main/specs/signature-envelope-jws.md#protected-headers
+var notationSupportedCriticalHeaders = []string{
+	notationHeaderSigningScheme,
+	notationHeaderExpiry,
+}
+
+type NotationConfig struct {
+	// TrustPolicy contains the Notation trust policy document (trustpolicy.json)
+	TrustPolicy string
+	// TrustStore maps trust store names (used as "ca:<name>" in the trust policy) to PEM encoded certificates
+	TrustStore map[string]string
+}
+
+// notationEnvelope is the JWS envelope (JSON serialization) created by notation
+// https://github.com/notaryproject/specifications/blob/main/specs/signature-envelope-jws.md
+type notationEnvelope struct {
+	Payload   string `json:"payload"`
+	Protected string `json:"protected"`
+	Header    struct {
+		CertificateChain [][]byte `json:"x5c"`
+	} `json:"header"`
+	Signature string `json:"signature"`
+}
+
+type notationProtectedHeader struct {
+	Algorithm     string     `json:"alg"`
+	Critical      []string   `json:"crit"`
+	ContentType   string     `json:"cty"`
+	SigningScheme string     `json:"io.cncf.notary.signingScheme"`
+	SigningTime   *time.Time `json:"io.cncf.notary.signingTime"`
+	Expiry        *time.Time `json:"io.cncf.notary.expiry"`
+}
+
+type notationPayload struct {
+	TargetArtifact v1.Descriptor `json:"targetArtifact"`
+}
+
+type notationService struct {
+	ServiceConfig
+	trustPolicies    []notationTrustPolicy
+	trustPoliciesErr error
+}
+
+func NewNotationValidator(sc *ServiceConfig) ImageValidatorService {
+	trustPolicies, err := parseNotationTrustPolicies(sc.NotationConfig)
+	return &notationService{
+		ServiceConfig: ServiceConfig{
+			NotationConfig:     sc.NotationConfig,
+			AllowedRegistries:  sc.AllowedRegistries,
+			CredentialProvider: sc.CredentialProvider,
+		},
+		trustPolicies:    trustPolicies,
+		trustPoliciesErr: err,
+	}
+}
+
+func (s *notationService) Validate(ctx context.Context, image string, imagePullCredentials helpers.RegistryCredentials) error {
+	logger := helpers.LoggerFromCtx(ctx).With("image", image)
+	ctx = helpers.LoggerToContext(ctx, logger)
+
+	if allowed := isImageAllowed(image, s.AllowedRegistries); allowed {
+		logger.Info("image validation skipped, because it's allowed")
+		return nil
+	}
+
+	if s.trustPoliciesErr != nil {
+		return pkg.NewUnknownResultErr(errors.Wrap(s.trustPoliciesErr, "notation trust policy could not be loaded"))
+	}
+
+	// strict validation requires image name to contain domain and a tag, and/or sha256
+	ref, err := name.ParseReference(image, name.StrictValidation)
+	if err != nil {
+		return pkg.NewImageNotAllowedErr(errors.Wrap(err, "image name could not be parsed"))
+	}
+
+	policy := findNotationTrustPolicy(s.trustPolicies, ref.Context().Name())
+	if policy == nil {
+		return pkg.NewImageNotAllowedErr(errors.New("no notation trust policy applies to image"))
+	}
+	logger = logger.With("trustPolicy", policy.name)
+	ctx = helpers.LoggerToContext(ctx, logger)
+
+	if policy.level == notationLevelSkip {
+		logger.Info("image validation skipped by notation trust policy")
+		return nil
+	}
+
+	descriptor, remoteOptions, err := s.loggedGetRemoteDescriptor(ctx, ref, imagePullCredentials)
+	if err != nil {
+		return err
+	}
+
+	envelopes, err := s.loggedGetSignatures(ctx, ref.Context().Digest(descriptor.Digest.String()), remoteOptions...)
+	if err != nil {
+		return err
+	}
+	if len(envelopes) == 0 {
+		return pkg.NewValidationFailedErr(errors.New("image is not signed"))
+	}
+
+	for _, envelope := range envelopes {
+		if err := policy.verify(ctx, envelope, descriptor.Descriptor); err != nil {
+			logger.Debugf("notation signature rejected: %s", err.Error())
+			continue
+		}
+		return nil
+	}
+
+	return pkg.NewValidationFailedErr(errors.New("no valid notation signature found for image"))
+}
+
+func (s *notationService) loggedGetRemoteDescriptor(ctx context.Context, ref name.Reference, imagePullCredentials helpers.RegistryCredentials) (*remote.Descriptor, []remote.Option, error) {
+	const message = "request to image registry"
+	closeLog := helpers.LogStartTime(ctx, message)
+	defer closeLog()
+	defer prometheus.NewTimer(metrics.RegistryRequestDuration.WithLabelValues(metrics.RegistryRequestDescriptor)).ObserveDuration()
+	return getRemoteDescriptor(ctx, ref, imagePullCredentials, s.CredentialProvider)
+}
+
+func (s *notationService) loggedGetSignatures(ctx context.Context, digest name.Digest, remoteOptions ...remote.Option) ([][]byte, error) {
+	const message = "request to image registry (notation signatures)"
+	closeLog := helpers.LogStartTime(ctx, message)
+	defer closeLog()
+	defer prometheus.NewTimer(metrics.RegistryRequestDuration.WithLabelValues(metrics.RegistryRequestNotationSignatures)).ObserveDuration()
+	return getNotationSignatures(ctx, digest, remoteOptions...)
+}
+
+// getNotationSignatures discovers notation signatures of the image using the OCI referrers API
+// and returns their JWS envelopes
+func getNotationSignatures(ctx context.Context, digest name.Digest, remoteOptions ...remote.Option) ([][]byte, error) {
+	referrersOptions := append(remoteOptions, remote.WithFilter("artifactType", NotationSignatureArtifactType))
+	_, span := startRegistrySpan(ctx, "registry.Referrers", digest)
+	referrers, err := remote.Referrers(digest, referrersOptions...)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get image referrers"))
+	}
+	referrersManifest, err := referrers.IndexManifest()
+	if err != nil {
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "image referrers manifest"))
+	}
+
+	var envelopes [][]byte
+	for _, referrer := range referrersManifest.Manifests {
+		// not every registry supports filtering, so the artifact type has to be checked anyway
+		if referrer.ArtifactType != NotationSignatureArtifactType {
+			continue
+		}
+		signatureRef := digest.Context().Digest(referrer.Digest.String())
+		_, span := startRegistrySpan(ctx, "registry.Image", signatureRef)
+		signatureImage, err := remote.Image(signatureRef, remoteOptions...)
+		tracing.EndSpan(span, err)
+		if err != nil {
+			return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get notation signature"))
+		}
+		manifest, err := signatureImage.Manifest()
+		if err != nil {
+			return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "notation signature manifest"))
+		}
+		for _, layer := range manifest.Layers {
+			// COSE envelopes are not supported
+			if layer.MediaType != NotationJWSMediaType {
+				continue
+			}
+			envelope, err := getNotationEnvelope(signatureImage, layer.Digest)
+			if err != nil {
+				return nil, err
+			}
+			envelopes = append(envelopes, envelope)
+		}
+	}
+	return envelopes, nil
+}
+
+func getNotationEnvelope(signatureImage v1.Image, digest v1.Hash) ([]byte, error) {
+	layer, err := signatureImage.LayerByDigest(digest)
+	if err != nil {
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get notation signature envelope"))
+	}
+	reader, err := layer.Compressed()
+	if err != nil {
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "read notation signature envelope"))
+	}
+	defer reader.Close()
+
+	envelope, err := io.ReadAll(io.LimitReader(reader, notationMaxEnvelopeSize))
+	if err != nil {
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "read notation signature envelope"))
+	}
+	return envelope, nil
+}
+
+// verify checks the signature envelope against the trust policy,
+// integrity of the signature is always enforced, other checks depend on the verification level
+func (p *notationTrustPolicy) verify(ctx context.Context, rawEnvelope []byte, target v1.Descriptor) error {
+	logger := helpers.LoggerFromCtx(ctx)
+
+	envelope := notationEnvelope{}
+	if err := json.Unmarshal(rawEnvelope, &envelope); err != nil {
+		return errors.Wrap(err, "failed to unmarshal signature envelope")
+	}
+	header, payload, certs, err := verifyNotationIntegrity(envelope)
+	if err != nil {
+		return errors.Wrap(err, "integrity")
+	}
+	if payload.TargetArtifact.Digest != target.Digest {
+		return errors.Errorf("integrity: signature is for a different image digest: %s", payload.TargetArtifact.Digest.String())
+	}
+
+	if err := p.verifyAuthenticity(certs); err != nil {
+		if p.actions[notationCheckAuthenticity] == notationActionEnforce {
+			return errors.Wrap(err, "authenticity")
+		}
+		if p.actions[notationCheckAuthenticity] == notationActionLog {
+			logger.Warnf("notation authenticity check failed: %s", err.Error())
+		}
+	}
+
+	if header.Expiry != nil && time.Now().After(*header.Expiry) {
+		err := errors.Errorf("signature expired at %s", header.Expiry.String())
+		if p.actions[notationCheckExpiry] == notationActionEnforce {
+			return errors.Wrap(err, "expiry")
+		}
+		if p.actions[notationCheckExpiry] == notationActionLog {
+			logger.Warnf("notation expiry check failed: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+func (p *notationTrustPolicy) verifyAuthenticity(certs []*x509.Certificate) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         p.trustStore,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return errors.Wrap(err, "certificate chain is not trusted")
+	}
+	if !p.isIdentityTrusted(certs[0]) {
+		return errors.Errorf("signing identity is not trusted: %s", certs[0].Subject.String())
+	}
+	return nil
+}
+
+func verifyNotationIntegrity(envelope notationEnvelope) (*notationProtectedHeader, *notationPayload, []*x509.Certificate, error) {
+	rawHeader, err := base64.RawURLEncoding.DecodeString(envelope.Protected)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "cannot decode protected header")
+	}
+	header := notationProtectedHeader{}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to unmarshal protected header")
+	}
+	if header.ContentType != NotationPayloadContentType {
+		return nil, nil, nil, errors.Errorf("unexpected payload content type: %s", header.ContentType)
+	}
+	if header.SigningScheme != NotationSigningSchemeX509 {
+		return nil, nil, nil, errors.Errorf("unsupported signing scheme: %s", header.SigningScheme)
+	}
+	if err := verifyNotationCriticalHeaders(header.Critical, rawHeader); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(envelope.Header.CertificateChain) == 0 {
+		return nil, nil, nil, errors.New("certificate chain is missing")
+	}
+	certs := make([]*x509.Certificate, 0, len(envelope.Header.CertificateChain))
+	for _, rawCert := range envelope.Header.CertificateChain {
+		cert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return nil, nil, nil, errors.Wrap(err, "failed to parse certificate chain")
+		}
+		certs = append(certs, cert)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(envelope.Signature)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "cannot decode signature")
+	}
+	signingInput := envelope.Protected + "." + envelope.Payload
+	if err := verifyJWSSignature(header.Algorithm, certs[0].PublicKey, []byte(signingInput), signature); err != nil {
+		return nil, nil, nil, err
+	}
+
+	rawPayload, err := base64.RawURLEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "cannot decode payload")
+	}
+	payload := notationPayload{}
+	if err := json.Unmarshal(rawPayload, &payload); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to unmarshal payload")
+	}
+	return &header, &payload, certs, nil
+}
+
+// verifyNotationCriticalHeaders checks that all critical headers are supported and present,
+// the signing scheme has to be marked as critical
+func verifyNotationCriticalHeaders(critical []string, rawHeader []byte) error {
+	if !slices.Contains(critical, notationHeaderSigningScheme) {
+		return errors.Errorf("%s header is not marked as critical", notationHeaderSigningScheme)
+	}
+	headers := map[string]json.RawMessage{}
+	if err := json.Unmarshal(rawHeader, &headers); err != nil {
+		return errors.Wrap(err, "failed to unmarshal protected header")
+	}
+	for _, name := range critical {
+		if !slices.Contains(notationSupportedCriticalHeaders, name) {
+			return errors.Errorf("unsupported critical header: %s", name)
+		}
+		if _, ok := headers[name]; !ok {
+			return errors.Errorf("critical header %s is missing", name)
+		}
+	}
+	return nil
+}
+
+// verifyJWSSignature supports algorithms allowed by the notation signature specification
+func verifyJWSSignature(algorithm string, publicKey crypto.PublicKey, signingInput, signature []byte) error {
+	var hash crypto.Hash
+	switch algorithm {
+	case "PS256", "ES256":
+		hash = crypto.SHA256
+	case "PS384", "ES384":
+		hash = crypto.SHA384
+	case "PS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return errors.Errorf("unsupported signature algorithm: %s", algorithm)
+	}
+	hasher := hash.New()
+	hasher.Write(signingInput)
+	digest := hasher.Sum(nil)
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(algorithm, "PS") {
+			return errors.Errorf("algorithm %s does not match rsa key", algorithm)
+		}
+		return rsa.VerifyPSS(key, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(algorithm, "ES") {
+			return errors.Errorf("algorithm %s does not match ecdsa key", algorithm)
+		}
+		// JWS encodes ecdsa signatures as concatenated R and S
+		if len(signature)%2 != 0 {
+			return errors.New("invalid ecdsa signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:len(signature)/2])
+		s := new(big.Int).SetBytes(signature[len(signature)/2:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return errors.New("invalid ecdsa signature")
+		}
+		return nil
+	}
+	return errors.Errorf("unsupported public key type: %T", publicKey)
+}
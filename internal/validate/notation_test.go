@@ -0,0 +1,408 @@
+package validate_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	notationTrustPolicyTemplate = `{
+  "version": "1.0",
+  "trustPolicies": [
+    {
+      "name": "images",
+      "registryScopes": [ %s ],
+      "signatureVerification": %s,
+      "trustStores": [ "ca:acme" ],
+      "trustedIdentities": [ %s ]
+    }
+  ]
+}`
+	notationTrustedIdentity = `"x509.subject: C=US, O=acme, CN=signer"`
+)
+
+func Test_NotationValidate(t *testing.T) {
+	testServer := httptest.NewServer(registry.New(registry.WithReferrersSupport(true)))
+	defer testServer.Close()
+	registryHost := strings.TrimPrefix(testServer.URL, "http://")
+
+	ca := newNotationCA(t, "acme")
+	otherCA := newNotationCA(t, "other")
+	signer := ca.issue(t, pkix.Name{Country: []string{"US"}, Organization: []string{"acme"}, CommonName: "signer"}, time.Now().Add(time.Hour))
+	otherSigner := otherCA.issue(t, pkix.Name{Country: []string{"US"}, Organization: []string{"acme"}, CommonName: "signer"}, time.Now().Add(time.Hour))
+	unknownIdentitySigner := ca.issue(t, pkix.Name{Country: []string{"US"}, Organization: []string{"evil"}, CommonName: "signer"}, time.Now().Add(time.Hour))
+
+	signedImage := pushRandomImage(t, fmt.Sprintf("%s/signed:v1", registryHost))
+	signNotationImage(t, signedImage, signer, signedImage.digest, nil)
+
+	unsignedImage := pushRandomImage(t, fmt.Sprintf("%s/unsigned:v1", registryHost))
+
+	untrustedImage := pushRandomImage(t, fmt.Sprintf("%s/untrusted:v1", registryHost))
+	signNotationImage(t, untrustedImage, otherSigner, untrustedImage.digest, nil)
+
+	unknownIdentityImage := pushRandomImage(t, fmt.Sprintf("%s/unknown-identity:v1", registryHost))
+	signNotationImage(t, unknownIdentityImage, unknownIdentitySigner, unknownIdentityImage.digest, nil)
+
+	wrongDigestImage := pushRandomImage(t, fmt.Sprintf("%s/wrong-digest:v1", registryHost))
+	signNotationImage(t, wrongDigestImage, signer, signedImage.digest, nil)
+
+	expired := time.Now().Add(-time.Minute)
+	expiredImage := pushRandomImage(t, fmt.Sprintf("%s/expired:v1", registryHost))
+	signNotationImage(t, expiredImage, signer, expiredImage.digest, &expired)
+
+	unknownCriticalImage := pushRandomImage(t, fmt.Sprintf("%s/unknown-critical:v1", registryHost))
+	signNotationImage(t, unknownCriticalImage, signer, unknownCriticalImage.digest, nil, "io.cncf.notary.verificationPlugin")
+
+	trustStore := map[string]string{"acme": ca.pem}
+
+	tests := []struct {
+		name        string
+		image       string
+		trustPolicy string
+		wantErr     string
+		wantCode    pkg.ErrorType
+	}{
+		{
+			name:        "signed image",
+			image:       signedImage.ref.String(),
+			trustPolicy: notationTrustPolicy(`"*"`, "strict", notationTrustedIdentity),
+		},
+		{
+			name:        "signed image referenced by digest",
+			image:       signedImage.ref.Context().Digest(signedImage.digest.String()).String(),
+			trustPolicy: notationTrustPolicy(`"*"`, "strict", notationTrustedIdentity),
+		},
+		{
+			name:        "signed image with registry scope",
+			image:       signedImage.ref.String(),
+			trustPolicy: notationTrustPolicy(fmt.Sprintf(`"%s"`, signedImage.ref.Context().Name()), "strict", `"*"`),
+		},
+		{
+			name:        "image out of registry scopes",
+			image:       signedImage.ref.String(),
+			trustPolicy: notationTrustPolicy(`"other-registry/image"`, "strict", `"*"`),
+			wantErr:     "no notation trust policy applies to image",
+			wantCode:    pkg.ValidationError,
+		},
+		{
+			name:        "unsigned image",
+			image:       unsignedImage.ref.String(),
+			trustPolicy: notationTrustPolicy(`"*"`, "strict", notationTrustedIdentity),
+			wantErr:     "image is not signed",
+			wantCode:    pkg.ValidationError,
+		},
+		{
+			name:        "unsigned image with skip level",
+			image:       unsignedImage.ref.String(),
+			trustPolicy: notationTrustPolicy(`"*"`, "skip", notationTrustedIdentity),
+		},
+		{
+			name:        "image signed by untrusted CA",
+			image:       untrustedImage.ref.String(),
+			trustPolicy: notationTrustPolicy(`"*"`, "strict", notationTrustedIdentity),
+			wantErr:     "no valid notation signature found for image",
+			wantCode:    pkg.ValidationError,
+		},
+		{
+			name:        "image signed by untrusted CA with audit level",
+			image:       untrustedImage.ref.String(),
+			trustPolicy: notationTrustPolicy(`"*"`, "audit", notationTrustedIdentity),
+		},
+		{
+			name:        "image signed by untrusted identity",
+			image:       unknownIdentityImage.ref.String(),
+			trustPolicy: notationTrustPolicy(`"*"`, "strict", notationTrustedIdentity),
+			wantErr:     "no valid notation signature found for image",
+			wantCode:    pkg.ValidationError,
+		},
+		{
+			name:        "signature for different digest",
+			image:       wrongDigestImage.ref.String(),
+			trustPolicy: notationTrustPolicy(`"*"`, "audit", notationTrustedIdentity),
+			wantErr:     "no valid notation signature found for image",
+			wantCode:    pkg.ValidationError,
+		},
+		{
+			name:        "expired signature",
+			image:       expiredImage.ref.String(),
+			trustPolicy: notationTrustPolicy(`"*"`, "strict", notationTrustedIdentity),
+			wantErr:     "no valid notation signature found for image",
+			wantCode:    pkg.ValidationError,
+		},
+		{
+			name:        "signature with unknown critical header",
+			image:       unknownCriticalImage.ref.String(),
+			trustPolicy: notationTrustPolicy(`"*"`, "audit", notationTrustedIdentity),
+			wantErr:     "no valid notation signature found for image",
+			wantCode:    pkg.ValidationError,
+		},
+		{
+			name:        "expired signature with permissive level",
+			image:       expiredImage.ref.String(),
+			trustPolicy: notationTrustPolicy(`"*"`, "permissive", notationTrustedIdentity),
+		},
+		{
+			name:        "image which is not in registry",
+			image:       fmt.Sprintf("%s/unknown:v1", registryHost),
+			trustPolicy: notationTrustPolicy(`"*"`, "strict", notationTrustedIdentity),
+			wantErr:     "get image descriptor anonymously",
+			wantCode:    pkg.UnknownResult,
+		},
+		{
+			name:        "invalid trust policy",
+			image:       signedImage.ref.String(),
+			trustPolicy: notationTrustPolicy(`"*"`, "unknown", notationTrustedIdentity),
+			wantErr:     "signature verification level unknown is not supported",
+			wantCode:    pkg.UnknownResult,
+		},
+		{
+			name:        "strict level without overrides of unsupported checks",
+			image:       signedImage.ref.String(),
+			trustPolicy: fmt.Sprintf(notationTrustPolicyTemplate, `"*"`, `{ "level": "strict" }`, notationTrustedIdentity),
+			wantErr:     "verification check authenticTimestamp is not supported and can't be enforced",
+			wantCode:    pkg.UnknownResult,
+		},
+		{
+			name:        "enforced revocation check",
+			image:       signedImage.ref.String(),
+			trustPolicy: fmt.Sprintf(notationTrustPolicyTemplate, `"*"`, `{ "level": "permissive", "override": { "revocation": "enforce" } }`, notationTrustedIdentity),
+			wantErr:     "verification check revocation is not supported and can't be enforced",
+			wantCode:    pkg.UnknownResult,
+		},
+		{
+			name:        "invalid trusted identity",
+			image:       signedImage.ref.String(),
+			trustPolicy: notationTrustPolicy(`"*"`, "strict", `"x509.subject: acme"`),
+			wantErr:     "invalid distinguished name attribute",
+			wantCode:    pkg.UnknownResult,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			cfg := validate.ServiceConfig{NotationConfig: validate.NotationConfig{
+				TrustPolicy: tt.trustPolicy,
+				TrustStore:  trustStore,
+			}}
+			s := validate.NewNotationValidator(&cfg)
+
+			//WHEN
+			err := s.Validate(context.TODO(), tt.image, emptyAuthData)
+
+			//THEN
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+			require.Equal(t, tt.wantCode, pkg.ErrorCode(err))
+		})
+	}
+
+	t.Run("missing trust store", func(t *testing.T) {
+		//GIVEN
+		cfg := validate.ServiceConfig{NotationConfig: validate.NotationConfig{
+			TrustPolicy: notationTrustPolicy(`"*"`, "strict", notationTrustedIdentity),
+		}}
+		s := validate.NewNotationValidator(&cfg)
+
+		//WHEN
+		err := s.Validate(context.TODO(), signedImage.ref.String(), emptyAuthData)
+
+		//THEN
+		require.ErrorContains(t, err, "trust store ca:acme not found")
+		require.Equal(t, pkg.UnknownResult, pkg.ErrorCode(err))
+	})
+}
+
+func Test_NotationConfig(t *testing.T) {
+	ca := newNotationCA(t, "acme")
+	trustPolicy := notationTrustPolicy(`"*"`, "strict", notationTrustedIdentity)
+
+	t.Run("load from files", func(t *testing.T) {
+		//GIVEN
+		dir := t.TempDir()
+		trustPolicyPath := filepath.Join(dir, validate.NotationTrustPolicyKey)
+		require.NoError(t, os.WriteFile(trustPolicyPath, []byte(trustPolicy), 0600))
+		trustStorePath := filepath.Join(dir, "truststore")
+		require.NoError(t, os.Mkdir(trustStorePath, 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(trustStorePath, "acme.pem"), []byte(ca.pem), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(trustStorePath, "README.md"), []byte("readme"), 0600))
+
+		//WHEN
+		cfg, err := validate.LoadNotationConfig(trustPolicyPath, trustStorePath)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, trustPolicy, cfg.TrustPolicy)
+		require.Equal(t, map[string]string{"acme": ca.pem}, cfg.TrustStore)
+	})
+
+	t.Run("load from secret", func(t *testing.T) {
+		//GIVEN
+		secret := &corev1.Secret{Data: map[string][]byte{
+			validate.NotationTrustPolicyKey: []byte(trustPolicy),
+			"acme.crt":                      []byte(ca.pem),
+			"other-key":                     []byte("value"),
+		}}
+
+		//WHEN
+		cfg, err := validate.NotationConfigFromSecret(secret)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, trustPolicy, cfg.TrustPolicy)
+		require.Equal(t, map[string]string{"acme": ca.pem}, cfg.TrustStore)
+	})
+
+	t.Run("secret without trust policy", func(t *testing.T) {
+		//GIVEN
+		secret := &corev1.Secret{Data: map[string][]byte{"acme.crt": []byte(ca.pem)}}
+
+		//WHEN
+		_, err := validate.NotationConfigFromSecret(secret)
+
+		//THEN
+		require.ErrorContains(t, err, "trustpolicy.json not found in secret")
+	})
+}
+
+// notationTrustPolicy returns the trust policy with the verification level,
+// checks which aren't supported are skipped on the strict level
+func notationTrustPolicy(scopes, level, identities string) string {
+	verification := fmt.Sprintf(`{ "level": "%s" }`, level)
+	if level == "strict" {
+		verification = `{ "level": "strict", "override": { "authenticTimestamp": "skip", "revocation": "skip" } }`
+	}
+	return fmt.Sprintf(notationTrustPolicyTemplate, scopes, verification, identities)
+}
+
+type notationCA struct {
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+	pem  string
+}
+
+type notationSigner struct {
+	key   *ecdsa.PrivateKey
+	chain [][]byte
+}
+
+func newNotationCA(t *testing.T, name string) notationCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{name}, CommonName: name + " CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return notationCA{
+		key:  key,
+		cert: cert,
+		pem:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+}
+
+func (ca notationCA) issue(t *testing.T, subject pkix.Name, notAfter time.Time) notationSigner {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	require.NoError(t, err)
+	return notationSigner{key: key, chain: [][]byte{der, ca.cert.Raw}}
+}
+
+// signNotationImage signs the image, extra critical headers are added to the protected header with a dummy value
+func signNotationImage(t *testing.T, image pushedImage, signer notationSigner, signedDigest v1.Hash, expiry *time.Time, extraCritical ...string) {
+	header := map[string]interface{}{
+		"alg":                          "ES256",
+		"crit":                         append([]string{"io.cncf.notary.signingScheme"}, extraCritical...),
+		"cty":                          validate.NotationPayloadContentType,
+		"io.cncf.notary.signingScheme": validate.NotationSigningSchemeX509,
+		"io.cncf.notary.signingTime":   time.Now(),
+		"io.cncf.notary.expiry":        expiry,
+	}
+	for _, name := range extraCritical {
+		header[name] = "value"
+	}
+	protected, err := json.Marshal(header)
+	require.NoError(t, err)
+	payload, err := json.Marshal(map[string]interface{}{
+		"targetArtifact": v1.Descriptor{MediaType: types.OCIManifestSchema1, Digest: signedDigest},
+	})
+	require.NoError(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(protected) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, signer.key, hash[:])
+	require.NoError(t, err)
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	envelope, err := json.Marshal(map[string]interface{}{
+		"payload":   base64.RawURLEncoding.EncodeToString(payload),
+		"protected": base64.RawURLEncoding.EncodeToString(protected),
+		"header":    map[string]interface{}{"x5c": signer.chain},
+		"signature": base64.RawURLEncoding.EncodeToString(signature),
+	})
+	require.NoError(t, err)
+
+	signatureImage, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: static.NewLayer(envelope, validate.NotationJWSMediaType),
+	})
+	require.NoError(t, err)
+	signatureImage = mutate.MediaType(signatureImage, types.OCIManifestSchema1)
+	signatureImage = mutate.ConfigMediaType(signatureImage, validate.NotationSignatureArtifactType)
+
+	subject, err := remote.Get(image.ref)
+	require.NoError(t, err)
+	signatureImage = mutate.Subject(signatureImage, subject.Descriptor).(v1.Image)
+
+	signatureDigest, err := signatureImage.Digest()
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(image.ref.Context().Digest(signatureDigest.String()), signatureImage))
+}
@@ -0,0 +1,392 @@
+package validate
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// NotationTrustPolicyKey is the name of the trust policy file in a directory or a Secret
+	NotationTrustPolicyKey = "trustpolicy.json"
+
+	notationTrustPolicyVersion = "1.0"
+	notationTrustStoreTypeCA   = "ca"
+	notationIdentityX509Prefix = "x509.subject:"
+	notationWildcard           = "*"
+
+	notationLevelStrict     = "strict"
+	notationLevelPermissive = "permissive"
+	notationLevelAudit      = "audit"
+	notationLevelSkip       = "skip"
+
+	notationCheckAuthenticity       = "authenticity"
+	notationCheckAuthenticTimestamp = "authenticTimestamp"
+	notationCheckExpiry             = "expiry"
+	notationCheckRevocation         = "revocation"
+
+	notationActionEnforce = "enforce"
+	notationActionLog     = "log"
+	notationActionSkip    = "skip"
+)
+
+// notationLevels defines actions for validation checks on every verification level
+// https://github.com/notaryproject/specifications/blob/main/specs/trust-store-trust-policy.md#signature-verification-details
+var notationLevels = map[string]map[string]string{
+	notationLevelStrict: {
+		notationCheckAuthenticity:       notationActionEnforce,
+		notationCheckAuthenticTimestamp: notationActionEnforce,
+		notationCheckExpiry:             notationActionEnforce,
+		notationCheckRevocation:         notationActionEnforce,
+	},
+	notationLevelPermissive: {
+		notationCheckAuthenticity:       notationActionEnforce,
+		notationCheckAuthenticTimestamp: notationActionLog,
+		notationCheckExpiry:             notationActionLog,
+		notationCheckRevocation:         notationActionLog,
+	},
+	notationLevelAudit: {
+		notationCheckAuthenticity:       notationActionLog,
+		notationCheckAuthenticTimestamp: notationActionLog,
+		notationCheckExpiry:             notationActionLog,
+		notationCheckRevocation:         notationActionLog,
+	},
+	notationLevelSkip: {},
+}
+
+// notationUnsupportedChecks aren't performed, so they can't be enforced and the trust policy has to override them,
+// otherwise the policy would claim guarantees which aren't given
+var notationUnsupportedChecks = []string{
+	notationCheckAuthenticTimestamp,
+	notationCheckRevocation,
+}
+
+type notationTrustPolicyDocument struct {
+	Version       string                         `json:"version"`
+	TrustPolicies []notationTrustPolicyStatement `json:"trustPolicies"`
+}
+
+type notationTrustPolicyStatement struct {
+	Name                  string   `json:"name"`
+	RegistryScopes        []string `json:"registryScopes"`
+	SignatureVerification struct {
+		Level    string            `json:"level"`
+		Override map[string]string `json:"override"`
+	} `json:"signatureVerification"`
+	TrustStores       []string `json:"trustStores"`
+	TrustedIdentities []string `json:"trustedIdentities"`
+}
+
+type notationTrustPolicy struct {
+	name       string
+	scopes     []string
+	level      string
+	actions    map[string]string
+	trustStore *x509.CertPool
+	// identities contains required subject attributes, nil means that any identity is trusted
+	identities []map[string]string
+}
+
+// LoadNotationConfig reads the trust policy file and the trust store directory,
+// every certificate file (*.pem, *.crt, *.cer) in the directory is a trust store named after the file
+func LoadNotationConfig(trustPolicyPath, trustStorePath string) (NotationConfig, error) {
+	trustPolicy, err := os.ReadFile(filepath.Clean(trustPolicyPath))
+	if err != nil {
+		return NotationConfig{}, errors.Wrap(err, "failed to read notation trust policy")
+	}
+
+	entries, err := os.ReadDir(filepath.Clean(trustStorePath))
+	if err != nil {
+		return NotationConfig{}, errors.Wrap(err, "failed to read notation trust store")
+	}
+	trustStore := map[string]string{}
+	for _, entry := range entries {
+		storeName, ok := notationTrustStoreName(entry.Name())
+		if entry.IsDir() || !ok {
+			continue
+		}
+		certs, err := os.ReadFile(filepath.Join(trustStorePath, entry.Name()))
+		if err != nil {
+			return NotationConfig{}, errors.Wrap(err, "failed to read notation trust store")
+		}
+		trustStore[storeName] = string(certs)
+	}
+
+	return NotationConfig{TrustPolicy: string(trustPolicy), TrustStore: trustStore}, nil
+}
+
+// NotationConfigFromSecret reads the trust policy and the trust store from the Secret
+// using the same layout as LoadNotationConfig
+func NotationConfigFromSecret(secret *corev1.Secret) (NotationConfig, error) {
+	trustPolicy, ok := secret.Data[NotationTrustPolicyKey]
+	if !ok {
+		return NotationConfig{}, errors.Errorf("%s not found in secret %s/%s", NotationTrustPolicyKey, secret.Namespace, secret.Name)
+	}
+	trustStore := map[string]string{}
+	for key, value := range secret.Data {
+		if storeName, ok := notationTrustStoreName(key); ok {
+			trustStore[storeName] = string(value)
+		}
+	}
+	return NotationConfig{TrustPolicy: string(trustPolicy), TrustStore: trustStore}, nil
+}
+
+func notationTrustStoreName(fileName string) (string, bool) {
+	ext := filepath.Ext(fileName)
+	switch ext {
+	case ".pem", ".crt", ".cer":
+		return strings.TrimSuffix(fileName, ext), true
+	}
+	return "", false
+}
+
+func parseNotationTrustPolicies(config NotationConfig) ([]notationTrustPolicy, error) {
+	document := notationTrustPolicyDocument{}
+	if err := json.Unmarshal([]byte(config.TrustPolicy), &document); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal trust policy")
+	}
+	if document.Version != notationTrustPolicyVersion {
+		return nil, errors.Errorf("trust policy version %s is not supported", document.Version)
+	}
+	if len(document.TrustPolicies) == 0 {
+		return nil, errors.New("trust policy is empty")
+	}
+
+	policyNames := map[string]struct{}{}
+	scopes := map[string]struct{}{}
+	policies := make([]notationTrustPolicy, 0, len(document.TrustPolicies))
+	for _, statement := range document.TrustPolicies {
+		if _, ok := policyNames[statement.Name]; ok || statement.Name == "" {
+			return nil, errors.Errorf("trust policy name %q is empty or not unique", statement.Name)
+		}
+		policyNames[statement.Name] = struct{}{}
+
+		normalizedScopes := make([]string, 0, len(statement.RegistryScopes))
+		for _, scope := range statement.RegistryScopes {
+			if scope == notationWildcard && len(statement.RegistryScopes) > 1 {
+				return nil, errors.Errorf("trust policy %s: wildcard registry scope can't be used with other scopes", statement.Name)
+			}
+			scope, err := normalizeNotationRegistryScope(scope)
+			if err != nil {
+				return nil, errors.Wrapf(err, "trust policy %s", statement.Name)
+			}
+			if _, ok := scopes[scope]; ok {
+				return nil, errors.Errorf("trust policy %s: registry scope %s is used by more than one policy", statement.Name, scope)
+			}
+			scopes[scope] = struct{}{}
+			normalizedScopes = append(normalizedScopes, scope)
+		}
+		statement.RegistryScopes = normalizedScopes
+
+		policy, err := newNotationTrustPolicy(statement, config.TrustStore)
+		if err != nil {
+			return nil, errors.Wrapf(err, "trust policy %s", statement.Name)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// normalizeNotationRegistryScope returns the scope in the form of the repository name of the image reference,
+// so "docker.io/library/nginx" matches images of "index.docker.io/library/nginx"
+func normalizeNotationRegistryScope(scope string) (string, error) {
+	if scope == notationWildcard {
+		return scope, nil
+	}
+	repository, err := name.NewRepository(scope)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid registry scope %s", scope)
+	}
+	return repository.Name(), nil
+}
+
+func newNotationTrustPolicy(statement notationTrustPolicyStatement, trustStores map[string]string) (notationTrustPolicy, error) {
+	if len(statement.RegistryScopes) == 0 {
+		return notationTrustPolicy{}, errors.New("registry scopes are empty")
+	}
+	levelActions, ok := notationLevels[statement.SignatureVerification.Level]
+	if !ok {
+		return notationTrustPolicy{}, errors.Errorf("signature verification level %s is not supported", statement.SignatureVerification.Level)
+	}
+	policy := notationTrustPolicy{
+		name:    statement.Name,
+		scopes:  statement.RegistryScopes,
+		level:   statement.SignatureVerification.Level,
+		actions: map[string]string{},
+	}
+	if policy.level == notationLevelSkip {
+		return policy, nil
+	}
+
+	for check, action := range levelActions {
+		policy.actions[check] = action
+	}
+	for check, action := range statement.SignatureVerification.Override {
+		if _, ok := policy.actions[check]; !ok {
+			return notationTrustPolicy{}, errors.Errorf("verification check %s can't be overridden", check)
+		}
+		if action != notationActionEnforce && action != notationActionLog && action != notationActionSkip {
+			return notationTrustPolicy{}, errors.Errorf("verification action %s is not supported", action)
+		}
+		policy.actions[check] = action
+	}
+	for _, check := range notationUnsupportedChecks {
+		if policy.actions[check] == notationActionEnforce {
+			return notationTrustPolicy{}, errors.Errorf("verification check %s is not supported and can't be enforced, override it with %s or %s",
+				check, notationActionLog, notationActionSkip)
+		}
+	}
+
+	trustStore, err := newNotationTrustStore(statement.TrustStores, trustStores)
+	if err != nil {
+		return notationTrustPolicy{}, err
+	}
+	policy.trustStore = trustStore
+
+	identities, err := parseNotationTrustedIdentities(statement.TrustedIdentities)
+	if err != nil {
+		return notationTrustPolicy{}, err
+	}
+	policy.identities = identities
+	return policy, nil
+}
+
+func newNotationTrustStore(names []string, trustStores map[string]string) (*x509.CertPool, error) {
+	if len(names) == 0 {
+		return nil, errors.New("trust stores are empty")
+	}
+	pool := x509.NewCertPool()
+	for _, storeName := range names {
+		storeType, name, _ := strings.Cut(storeName, ":")
+		if storeType != notationTrustStoreTypeCA {
+			return nil, errors.Errorf("trust store type of %s is not supported", storeName)
+		}
+		certs, ok := trustStores[name]
+		if !ok {
+			return nil, errors.Errorf("trust store %s not found", storeName)
+		}
+		if err := addNotationCertificates(pool, certs); err != nil {
+			return nil, errors.Wrapf(err, "trust store %s", storeName)
+		}
+	}
+	return pool, nil
+}
+
+func addNotationCertificates(pool *x509.CertPool, certs string) error {
+	added := 0
+	rest := []byte(certs)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return errors.Errorf("unexpected PEM block type: %s", block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse certificate")
+		}
+		pool.AddCert(cert)
+		added++
+	}
+	if added == 0 {
+		return errors.New("no certificate found")
+	}
+	return nil
+}
+
+func parseNotationTrustedIdentities(trustedIdentities []string) ([]map[string]string, error) {
+	if len(trustedIdentities) == 0 {
+		return nil, errors.New("trusted identities are empty")
+	}
+	identities := make([]map[string]string, 0, len(trustedIdentities))
+	for _, identity := range trustedIdentities {
+		if identity == notationWildcard {
+			if len(trustedIdentities) > 1 {
+				return nil, errors.New("wildcard trusted identity can't be used with other identities")
+			}
+			return nil, nil
+		}
+		subject, ok := strings.CutPrefix(identity, notationIdentityX509Prefix)
+		if !ok {
+			return nil, errors.Errorf("trusted identity %s is not supported", identity)
+		}
+		attributes, err := parseDistinguishedName(subject)
+		if err != nil {
+			return nil, errors.Wrapf(err, "trusted identity %s", identity)
+		}
+		identities = append(identities, attributes)
+	}
+	return identities, nil
+}
+
+// parseDistinguishedName parses a "C=US, ST=WA, O=acme" like string, escaped separators are not supported
+func parseDistinguishedName(dn string) (map[string]string, error) {
+	attributes := map[string]string{}
+	for _, attribute := range strings.Split(dn, ",") {
+		key, value, ok := strings.Cut(attribute, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return nil, errors.Errorf("invalid distinguished name attribute: %s", attribute)
+		}
+		attributes[key] = value
+	}
+	return attributes, nil
+}
+
+func (p *notationTrustPolicy) isIdentityTrusted(cert *x509.Certificate) bool {
+	if p.identities == nil {
+		return true
+	}
+	subject := map[string][]string{
+		"C":            cert.Subject.Country,
+		"ST":           cert.Subject.Province,
+		"L":            cert.Subject.Locality,
+		"O":            cert.Subject.Organization,
+		"OU":           cert.Subject.OrganizationalUnit,
+		"CN":           {cert.Subject.CommonName},
+		"SERIALNUMBER": {cert.Subject.SerialNumber},
+	}
+	for _, identity := range p.identities {
+		if subjectMatches(subject, identity) {
+			return true
+		}
+	}
+	return false
+}
+
+func subjectMatches(subject map[string][]string, identity map[string]string) bool {
+	for key, value := range identity {
+		if !slices.Contains(subject[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// findNotationTrustPolicy returns the policy with the registry scope matching the image repository,
+// or the wildcard policy if there is no such policy, scopes are normalized like the repository name of the image reference
+func findNotationTrustPolicy(policies []notationTrustPolicy, repository string) *notationTrustPolicy {
+	var wildcardPolicy *notationTrustPolicy
+	for i := range policies {
+		for _, scope := range policies[i].scopes {
+			if scope == repository {
+				return &policies[i]
+			}
+			if scope == notationWildcard {
+				wildcardPolicy = &policies[i]
+			}
+		}
+	}
+	return wildcardPolicy
+}
@@ -0,0 +1,86 @@
+package validate
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/require"
+)
+
+const notationScopesTrustPolicyTemplate = `{
+  "version": "1.0",
+  "trustPolicies": [
+    {
+      "name": "images",
+      "registryScopes": [ %s ],
+      "signatureVerification": { "level": "skip" }
+    }
+  ]
+}`
+
+func TestFindNotationTrustPolicyNormalizesScopes(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope string
+		image string
+	}{
+		{
+			name:  "docker hub scope",
+			scope: "docker.io/library/nginx",
+			image: "nginx:1.0",
+		},
+		{
+			name:  "docker hub scope without library",
+			scope: "docker.io/nginx",
+			image: "index.docker.io/library/nginx:1.0",
+		},
+		{
+			name:  "other registry scope",
+			scope: "eu.gcr.io/kyma-project/warden",
+			image: "eu.gcr.io/kyma-project/warden:1.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			policies, err := parseNotationTrustPolicies(NotationConfig{
+				TrustPolicy: fmtNotationScopesTrustPolicy(`"` + tt.scope + `"`),
+			})
+			require.NoError(t, err)
+			ref, err := name.ParseReference(tt.image)
+			require.NoError(t, err)
+
+			//WHEN
+			policy := findNotationTrustPolicy(policies, ref.Context().Name())
+
+			//THEN
+			require.NotNil(t, policy)
+			require.Equal(t, "images", policy.name)
+		})
+	}
+
+	t.Run("normalized scopes are not unique", func(t *testing.T) {
+		//WHEN
+		_, err := parseNotationTrustPolicies(NotationConfig{
+			TrustPolicy: fmtNotationScopesTrustPolicy(`"docker.io/library/nginx", "index.docker.io/library/nginx"`),
+		})
+
+		//THEN
+		require.ErrorContains(t, err, "registry scope index.docker.io/library/nginx is used by more than one policy")
+	})
+
+	t.Run("invalid scope", func(t *testing.T) {
+		//WHEN
+		_, err := parseNotationTrustPolicies(NotationConfig{
+			TrustPolicy: fmtNotationScopesTrustPolicy(`"eu.gcr.io/Kyma"`),
+		})
+
+		//THEN
+		require.ErrorContains(t, err, "invalid registry scope eu.gcr.io/Kyma")
+	})
+}
+
+func fmtNotationScopesTrustPolicy(scopes string) string {
+	return fmt.Sprintf(notationScopesTrustPolicyTemplate, scopes)
+}
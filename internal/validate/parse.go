@@ -6,6 +6,7 @@ const (
 	allowedRegistriesSeparator = ","
 )
 
+// ParseAllowedRegistries splits the comma-separated list of registry patterns, see isImageAllowed for the patterns syntax
 func ParseAllowedRegistries(registries string) []string {
 	var registriesList []string
 	for _, registry := range strings.Split(registries, allowedRegistriesSeparator) {
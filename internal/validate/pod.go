@@ -2,13 +2,18 @@ package validate
 
 import (
 	"context"
-	"errors"
+	"slices"
+	"sync"
 	"time"
 
-	cliType "github.com/docker/cli/cli/config/types"
 	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/logging/tracing"
+	"github.com/kyma-project/warden/internal/metrics"
 	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 	corev1 "k8s.io/api/core/v1"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type ValidationStatus string
@@ -16,6 +21,8 @@ type ValidationStatus string
 type ValidationResult struct {
 	Status        ValidationStatus
 	InvalidImages []string
+	// ImageErrors contains the reason of the failed validation of every invalid image
+	ImageErrors map[string]error
 }
 
 const (
@@ -25,95 +32,308 @@ const (
 	NoAction           ValidationStatus = "NoAction"
 )
 
+type ValidatorSvcConfig struct {
+	// Verifier is a comma-separated list of verifiers combined according to the VerificationMode
+	Verifier          string
+	VerificationMode  string
+	NotaryURL         string
+	NotaryTimeout     time.Duration
+	AllowedRegistries string
+	CosignPublicKeys  string
+	NotationConfig    NotationConfig
+}
+
 //go:generate mockery --name ValidatorSvcFactory
 type ValidatorSvcFactory interface {
-	NewValidatorSvc(notaryURL string, notaryAllowedRegistries string, notaryTimeout time.Duration) PodValidator
+	NewValidatorSvc(config ValidatorSvcConfig) PodValidator
 }
 
 var _ ValidatorSvcFactory = &validatorSvcFactory{}
 
 type validatorSvcFactory struct {
+	cache                       *ValidationCache
+	credentialProvider          CredentialProvider
+	circuitBreakers             *CircuitBreakers
 	predefinedAllowedRegistries []string
+
+	// the notary repo factory is shared by all validators, so connections and notary repositories are reused
+	// between requests and the number of cached notary servers is bounded, timeouts are set by the notary config
+	notaryRepoFactory *NotaryRepoFactory
 }
 
-func NewValidatorSvcFactory(predefinedAllowedRegistries ...string) ValidatorSvcFactory {
+// NewValidatorSvcFactory creates the factory of pod validators, results of image validation are cached if the cache is not nil,
+// the credential provider and circuit breakers are optional and they're shared by all validators
+func NewValidatorSvcFactory(cache *ValidationCache, credentialProvider CredentialProvider, circuitBreakers *CircuitBreakers, predefinedAllowedRegistries ...string) ValidatorSvcFactory {
 	return &validatorSvcFactory{
+		cache:                       cache,
+		credentialProvider:          credentialProvider,
+		circuitBreakers:             circuitBreakers,
 		predefinedAllowedRegistries: predefinedAllowedRegistries,
+		notaryRepoFactory:           NewNotaryRepoFactory(0),
 	}
 }
 
-func (f validatorSvcFactory) NewValidatorSvc(notaryURL string, notaryAllowedRegistries string, notaryTimeout time.Duration) PodValidator {
-	repoFactory := NotaryRepoFactory{Timeout: notaryTimeout}
+func (f *validatorSvcFactory) NewValidatorSvc(config ValidatorSvcConfig) PodValidator {
 	allowedRegistries := append(
-		ParseAllowedRegistries(notaryAllowedRegistries),
+		ParseAllowedRegistries(config.AllowedRegistries),
 		f.predefinedAllowedRegistries...)
 
-	validatorSvcConfig := ServiceConfig{
-		NotaryConfig:      NotaryConfig{Url: notaryURL},
-		AllowedRegistries: allowedRegistries,
+	imageValidatorSvc := f.newImageValidatorSvc(config, allowedRegistries)
+	if f.cache != nil {
+		imageValidatorSvc = NewCachedValidator(imageValidatorSvc, f.cache,
+			validatorConfigKey(config, allowedRegistries), verifiesTag(config), allowedRegistries, f.credentialProvider)
 	}
-	podValidatorSvc := NewImageValidator(&validatorSvcConfig, repoFactory)
-	validatorSvc := NewPodValidator(podValidatorSvc)
+	validatorSvc := NewPodValidator(imageValidatorSvc)
 	return validatorSvc
 }
 
-func NewUserValidationSvc(ns *corev1.Namespace, validatorFactory ValidatorSvcFactory) (PodValidator, error) {
+// verifiesTag checks if the notary verifier is used, notary verifies the image tag while other verifiers verify the digest
+func verifiesTag(config ValidatorSvcConfig) bool {
+	verifiers := helpers.ParseVerifiers(config.Verifier)
+	return len(verifiers) == 0 || slices.Contains(verifiers, pkg.VerifierNotary)
+}
+
+func (f *validatorSvcFactory) newImageValidatorSvc(config ValidatorSvcConfig, allowedRegistries []string) ImageValidatorService {
+	verifiers := helpers.ParseVerifiers(config.Verifier)
+	if len(verifiers) == 0 {
+		return f.newVerifierSvc(pkg.VerifierNotary, config, allowedRegistries)
+	}
+	if len(verifiers) == 1 {
+		return f.newVerifierSvc(verifiers[0], config, allowedRegistries)
+	}
+
+	validators := make([]NamedValidator, 0, len(verifiers))
+	for _, verifier := range verifiers {
+		validators = append(validators, NamedValidator{
+			Name:      verifier,
+			Validator: f.newVerifierSvc(verifier, config, allowedRegistries),
+		})
+	}
+	return NewChainValidator(config.VerificationMode, validators...)
+}
+
+func (f *validatorSvcFactory) newVerifierSvc(verifier string, config ValidatorSvcConfig, allowedRegistries []string) ImageValidatorService {
+	if verifier == pkg.VerifierCosign {
+		validatorSvcConfig := ServiceConfig{
+			CosignConfig:       CosignConfig{PublicKeys: config.CosignPublicKeys},
+			AllowedRegistries:  allowedRegistries,
+			CredentialProvider: f.credentialProvider,
+		}
+		return NewCosignValidator(&validatorSvcConfig)
+	}
+
+	if verifier == pkg.VerifierNotation {
+		validatorSvcConfig := ServiceConfig{
+			NotationConfig:     config.NotationConfig,
+			AllowedRegistries:  allowedRegistries,
+			CredentialProvider: f.credentialProvider,
+		}
+		return NewNotationValidator(&validatorSvcConfig)
+	}
+
+	validatorSvcConfig := ServiceConfig{
+		NotaryConfig:         NotaryConfig{Url: config.NotaryURL, Timeout: config.NotaryTimeout},
+		AllowedRegistries:    allowedRegistries,
+		CredentialProvider:   f.credentialProvider,
+		NotaryCircuitBreaker: f.circuitBreakers.Get(config.NotaryURL),
+	}
+	return NewImageValidator(&validatorSvcConfig, f.notaryRepoFactory)
+}
+
+// NewUserValidationSvc creates the validator configured by the ImagePolicy or ClusterImagePolicy of the namespace,
+// or by the namespace annotations if there is no policy
+func NewUserValidationSvc(ctx context.Context, reader k8sclient.Reader, ns *corev1.Namespace, validatorFactory ValidatorSvcFactory) (PodValidator, error) {
+	policy, err := GetUserImagePolicy(ctx, reader, ns)
+	if err != nil {
+		return nil, err
+	}
+	return NewUserValidationSvcForPolicy(ctx, reader, ns, policy, validatorFactory)
+}
+
+// NewUserValidationSvcForPolicy creates the validator configured by the already resolved policy of the namespace,
+// or by the namespace annotations if the policy is nil
+func NewUserValidationSvcForPolicy(ctx context.Context, reader k8sclient.Reader, ns *corev1.Namespace, policy *UserImagePolicy, validatorFactory ValidatorSvcFactory) (PodValidator, error) {
+	if policy != nil {
+		return newUserValidationSvcFromPolicy(ctx, reader, ns, policy, validatorFactory)
+	}
+
 	userValidationConfig, errGetUserValidation := helpers.GetUserValidationNotaryConfig(ns)
 	if errGetUserValidation != nil {
 		return nil, errGetUserValidation
 	}
-	validationSvc := validatorFactory.NewValidatorSvc(
-		userValidationConfig.NotaryURL,
-		userValidationConfig.AllowedRegistries,
-		userValidationConfig.NotaryTimeout)
+	notationConfig, errGetNotationConfig := getUserNotationConfig(ctx, reader, ns, userValidationConfig)
+	if errGetNotationConfig != nil {
+		return nil, errGetNotationConfig
+	}
+	validationSvc := validatorFactory.NewValidatorSvc(ValidatorSvcConfig{
+		Verifier:          userValidationConfig.Verifier,
+		VerificationMode:  userValidationConfig.VerificationMode,
+		NotaryURL:         userValidationConfig.NotaryURL,
+		NotaryTimeout:     userValidationConfig.NotaryTimeout,
+		AllowedRegistries: userValidationConfig.AllowedRegistries,
+		CosignPublicKeys:  userValidationConfig.CosignPublicKeys,
+		NotationConfig:    notationConfig,
+	})
 	return validationSvc, nil
 }
 
+func getUserNotationConfig(ctx context.Context, reader k8sclient.Reader, ns *corev1.Namespace, userValidationConfig helpers.UserValidationNotaryConfig) (NotationConfig, error) {
+	if !slices.Contains(helpers.ParseVerifiers(userValidationConfig.Verifier), pkg.VerifierNotation) {
+		return NotationConfig{}, nil
+	}
+	return getNotationConfigFromSecret(ctx, reader, ns.Name, userValidationConfig.NotationSecret)
+}
+
+func getNotationConfigFromSecret(ctx context.Context, reader k8sclient.Reader, namespace, name string) (NotationConfig, error) {
+	secret := &corev1.Secret{}
+	if err := reader.Get(ctx, k8sclient.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+		return NotationConfig{}, errors.Wrapf(err, "can't get %s/%s", namespace, name)
+	}
+	return NotationConfigFromSecret(secret)
+}
+
 //go:generate mockery --name PodValidator
 type PodValidator interface {
-	ValidatePod(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, imagePullCredentials map[string]cliType.AuthConfig) (ValidationResult, error)
+	ValidatePod(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, imagePullCredentials helpers.RegistryCredentials) (ValidationResult, error)
 }
 
 var _ PodValidator = &podValidator{}
 
+// DefaultImageValidationConcurrency is the maximum number of images of a single pod validated at the same time
+const DefaultImageValidationConcurrency = 10
+
 type podValidator struct {
-	Validator ImageValidatorService
+	Validator      ImageValidatorService
+	maxConcurrency int
+}
+
+type imageValidationResult struct {
+	status ValidationStatus
+	err    error
 }
 
 func NewPodValidator(imageValidator ImageValidatorService) PodValidator {
 	return &podValidator{
-		imageValidator,
+		Validator:      imageValidator,
+		maxConcurrency: DefaultImageValidationConcurrency,
 	}
 }
 
-func (a *podValidator) ValidatePod(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, imagePullCredentials map[string]cliType.AuthConfig) (ValidationResult, error) {
+func (a *podValidator) ValidatePod(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, imagePullCredentials helpers.RegistryCredentials) (podResult ValidationResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "ValidatePod",
+		attribute.String("k8s.namespace.name", pod.Namespace),
+		attribute.String("k8s.pod.name", pod.Name))
+	defer func() {
+		span.SetAttributes(attribute.String("warden.validation.status", string(podResult.Status)))
+		tracing.EndSpan(span, err)
+	}()
+
 	logger := helpers.LoggerFromCtx(ctx)
 
 	if ns.Name != pod.Namespace {
-		return ValidationResult{Invalid, nil}, errors.New("pod namespace mismatch with given namespace")
+		return ValidationResult{Status: Invalid}, errors.New("pod namespace mismatch with given namespace")
 	}
 
 	images := getAllImages(pod)
+	span.SetAttributes(attribute.Int("warden.images.count", len(images)))
+	results := a.validateImages(ctx, images, imagePullCredentials)
 
 	admitResult := Valid
 
 	invalidImages := []string{}
+	imageErrors := map[string]error{}
 
-	for s := range images {
-		result, err := a.validateImage(ctx, s, imagePullCredentials)
+	mode := ValidationMode(ns)
+	for i, image := range images {
+		result := results[i]
+		recordValidationResultMetric(result, mode)
 
-		if result != Valid {
-			admitResult = result
-			invalidImages = append(invalidImages, s)
-			logger.With("image", s).Info(err.Error())
+		if result.status != Valid {
+			admitResult = mostSevereStatus(admitResult, result.status)
+			invalidImages = append(invalidImages, image)
+			imageErrors[image] = result.err
+			logger.With("image", image).Info(result.err.Error())
 		}
 	}
 
-	return ValidationResult{admitResult, invalidImages}, nil
+	return ValidationResult{Status: admitResult, InvalidImages: invalidImages, ImageErrors: imageErrors}, nil
 }
 
-func (a *podValidator) validateImage(ctx context.Context, image string, imagePullCredentials map[string]cliType.AuthConfig) (ValidationStatus, error) {
+// validateImages validates images concurrently and returns results in the order of images,
+// images which weren't validated before the context is done are reported as ServiceUnavailable
+func (a *podValidator) validateImages(ctx context.Context, images []string, imagePullCredentials helpers.RegistryCredentials) []imageValidationResult {
+	results := make([]imageValidationResult, len(images))
+	var resultsMutex sync.Mutex
+
+	workers := make(chan struct{}, a.maxConcurrency)
+	var wg sync.WaitGroup
+	for i, image := range images {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case workers <- struct{}{}:
+				defer func() { <-workers }()
+			case <-ctx.Done():
+				return
+			}
+
+			status, err := a.validateImage(ctx, image, imagePullCredentials)
+
+			resultsMutex.Lock()
+			defer resultsMutex.Unlock()
+			results[i] = imageValidationResult{status: status, err: err}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	resultsMutex.Lock()
+	defer resultsMutex.Unlock()
+	finalResults := make([]imageValidationResult, len(images))
+	for i, result := range results {
+		if result.status == "" {
+			result = imageValidationResult{
+				status: ServiceUnavailable,
+				err:    pkg.NewUnknownResultErr(errors.Wrap(ctx.Err(), "image validation was not finished")),
+			}
+		}
+		finalResults[i] = result
+	}
+	return finalResults
+}
+
+func recordValidationResultMetric(result imageValidationResult, mode string) {
+	reason := ""
+	if result.status != Valid {
+		reason = FailureReason(result.err)
+	}
+	metrics.ValidationResults.WithLabelValues(string(result.status), mode, reason).Inc()
+}
+
+// mostSevereStatus returns the status which should win when results of many images are combined
+func mostSevereStatus(current, next ValidationStatus) ValidationStatus {
+	if current == Invalid || next == Invalid {
+		return Invalid
+	}
+	if current == ServiceUnavailable || next == ServiceUnavailable {
+		return ServiceUnavailable
+	}
+	return current
+}
+
+func (a *podValidator) validateImage(ctx context.Context, image string, imagePullCredentials helpers.RegistryCredentials) (ValidationStatus, error) {
+	ctx, span := tracing.StartSpan(ctx, "ValidateImage", attribute.String("warden.image", image))
 	err := a.Validator.Validate(ctx, image, imagePullCredentials)
+	tracing.EndSpan(span, err)
 	if err != nil {
 		if pkg.ErrorCode(err) == pkg.UnknownResult {
 			return ServiceUnavailable, err
@@ -124,10 +344,15 @@ func (a *podValidator) validateImage(ctx context.Context, image string, imagePul
 	return Valid, nil
 }
 
-func getAllImages(pod *corev1.Pod) map[string]struct{} {
-	images := make(map[string]struct{})
+// getAllImages returns sorted and deduplicated images of all pod containers
+func getAllImages(pod *corev1.Pod) []string {
+	images := make([]string, 0, len(pod.Spec.Containers)+len(pod.Spec.InitContainers)+len(pod.Spec.EphemeralContainers))
 	for _, c := range append(pod.Spec.Containers, pod.Spec.InitContainers...) {
-		images[c.Image] = struct{}{}
+		images = append(images, c.Image)
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		images = append(images, c.Image)
 	}
-	return images
+	slices.Sort(images)
+	return slices.Compact(images)
 }
@@ -6,12 +6,19 @@ import (
 	"testing"
 	"time"
 
+	"github.com/kyma-project/warden/internal/metrics"
 	"github.com/kyma-project/warden/internal/validate"
 	"github.com/kyma-project/warden/internal/validate/mocks"
 	"github.com/kyma-project/warden/pkg"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -102,6 +109,24 @@ func TestValidatePod(t *testing.T) {
 			expectedStatus:       validate.ServiceUnavailable,
 			expectedFailedImages: []string{longResp},
 		},
+		{
+			name: "invalid image wins over unavailable image",
+			pod: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNs},
+				Spec: v1.PodSpec{Containers: []v1.Container{
+					longRespContainer, invalidContainer, validContainer,
+				}}},
+			expectedStatus:       validate.Invalid,
+			expectedFailedImages: []string{invalidImage, longResp},
+		},
+		{
+			name: "unavailable image wins over valid image",
+			pod: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNs},
+				Spec: v1.PodSpec{Containers: []v1.Container{
+					validContainer, longRespContainer,
+				}}},
+			expectedStatus:       validate.ServiceUnavailable,
+			expectedFailedImages: []string{longResp},
+		},
 		{
 			name: "pod has invalid image among otters",
 			pod: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNs},
@@ -141,6 +166,18 @@ func TestValidatePod(t *testing.T) {
 			expectedStatus:       validate.Invalid,
 			expectedFailedImages: []string{invalidImage},
 		},
+		{
+			name: "pod has invalid image in ephemeralContainers",
+			pod: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNs},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{validContainer},
+					EphemeralContainers: []v1.EphemeralContainer{
+						{EphemeralContainerCommon: v1.EphemeralContainerCommon{Image: invalidImage}},
+					},
+				}},
+			expectedStatus:       validate.Invalid,
+			expectedFailedImages: []string{invalidImage},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -167,12 +204,199 @@ func TestValidatePod(t *testing.T) {
 			require.ElementsMatchf(t, testCase.expectedFailedImages, result.InvalidImages, "list of images do not match")
 		})
 	}
+
+	t.Run("invalid images are sorted", func(t *testing.T) {
+		//GIVEN
+		ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNs}}
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNs},
+			Spec: v1.PodSpec{Containers: []v1.Container{
+				{Name: "c", Image: "image-c"}, {Name: "a", Image: "image-a"}, {Name: "b", Image: "image-b"}, {Name: "a2", Image: "image-a"},
+			}}}
+
+		validatorSvcMock := mocks.ImageValidatorService{}
+		validatorSvcMock.Mock.On("Validate", mock.Anything, mock.Anything, mock.Anything).
+			Return(errors.New("Invalid image")).Times(3)
+		defer validatorSvcMock.AssertExpectations(t)
+
+		podValidator := validate.NewPodValidator(&validatorSvcMock)
+
+		//WHEN
+		result, err := podValidator.ValidatePod(context.TODO(), pod, ns, emptyAuthData)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, validate.Invalid, result.Status)
+		require.Equal(t, []string{"image-a", "image-b", "image-c"}, result.InvalidImages)
+	})
+
+	t.Run("validation results are counted by status, mode and reason", func(t *testing.T) {
+		//GIVEN
+		ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNs,
+			Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationUser}}}
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNs},
+			Spec: v1.PodSpec{Containers: []v1.Container{
+				{Name: "valid", Image: "image-valid"}, {Name: "unknown", Image: "image-unknown"},
+			}}}
+
+		validatorSvcMock := mocks.ImageValidatorService{}
+		validatorSvcMock.Mock.On("Validate", mock.Anything, "image-valid", mock.Anything).Return(nil)
+		validatorSvcMock.Mock.On("Validate", mock.Anything, "image-unknown", mock.Anything).
+			Return(pkg.NewUnknownResultErr(errors.New("connection refused")))
+		podValidator := validate.NewPodValidator(&validatorSvcMock)
+
+		validCounter := metrics.ValidationResults.WithLabelValues(string(validate.Valid), pkg.NamespaceValidationUser, "")
+		unknownCounter := metrics.ValidationResults.WithLabelValues(string(validate.ServiceUnavailable), pkg.NamespaceValidationUser, validate.ReasonNotaryUnavailable)
+		validBefore, unknownBefore := testutil.ToFloat64(validCounter), testutil.ToFloat64(unknownCounter)
+
+		//WHEN
+		_, err := podValidator.ValidatePod(context.TODO(), pod, ns, emptyAuthData)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, validBefore+1, testutil.ToFloat64(validCounter))
+		require.Equal(t, unknownBefore+1, testutil.ToFloat64(unknownCounter))
+	})
+
+	t.Run("pod and every image validation are traced", func(t *testing.T) {
+		//GIVEN
+		recorder := tracetest.NewSpanRecorder()
+		previousProvider := otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		defer otel.SetTracerProvider(previousProvider)
+
+		ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNs}}
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNs, Name: "pod"},
+			Spec: v1.PodSpec{Containers: []v1.Container{
+				{Name: "valid", Image: "image-valid"}, {Name: "invalid", Image: "image-invalid"},
+			}}}
+
+		validatorSvcMock := mocks.ImageValidatorService{}
+		validatorSvcMock.Mock.On("Validate", mock.Anything, "image-valid", mock.Anything).Return(nil)
+		validatorSvcMock.Mock.On("Validate", mock.Anything, "image-invalid", mock.Anything).
+			Return(pkg.NewValidationFailedErr(errors.New("image is not signed")))
+		podValidator := validate.NewPodValidator(&validatorSvcMock)
+
+		//WHEN
+		_, err := podValidator.ValidatePod(context.TODO(), pod, ns, emptyAuthData)
+
+		//THEN
+		require.NoError(t, err)
+		spans := map[string]sdktrace.ReadOnlySpan{}
+		for _, span := range recorder.Ended() {
+			name := span.Name()
+			for _, attr := range span.Attributes() {
+				if attr.Key == "warden.image" {
+					name += "/" + attr.Value.AsString()
+				}
+			}
+			spans[name] = span
+		}
+		require.Len(t, spans, 3)
+		podSpan := spans["ValidatePod"]
+		require.NotNil(t, podSpan)
+		require.Contains(t, podSpan.Attributes(), attribute.String("warden.validation.status", string(validate.Invalid)))
+		require.Equal(t, podSpan.SpanContext().SpanID(), spans["ValidateImage/image-valid"].Parent().SpanID())
+		require.Equal(t, codes.Unset, spans["ValidateImage/image-valid"].Status().Code)
+		require.Equal(t, podSpan.SpanContext().SpanID(), spans["ValidateImage/image-invalid"].Parent().SpanID())
+		require.Equal(t, codes.Error, spans["ValidateImage/image-invalid"].Status().Code)
+	})
+
+	t.Run("images are validated concurrently", func(t *testing.T) {
+		//GIVEN
+		ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNs}}
+		containers := []v1.Container{}
+		for i := 0; i < validate.DefaultImageValidationConcurrency; i++ {
+			containers = append(containers, v1.Container{Name: fmt.Sprintf("c%d", i), Image: fmt.Sprintf("image-%d", i)})
+		}
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNs}, Spec: v1.PodSpec{Containers: containers}}
+
+		validatorSvcMock := mocks.ImageValidatorService{}
+		validatorSvcMock.Mock.On("Validate", mock.Anything, mock.Anything, mock.Anything).
+			After(200 * time.Millisecond).Return(nil)
+
+		podValidator := validate.NewPodValidator(&validatorSvcMock)
+
+		//WHEN
+		start := time.Now()
+		result, err := podValidator.ValidatePod(context.TODO(), pod, ns, emptyAuthData)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, validate.Valid, result.Status)
+		require.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("images not validated before deadline are unavailable", func(t *testing.T) {
+		//GIVEN
+		ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNs}}
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNs},
+			Spec: v1.PodSpec{Containers: []v1.Container{validContainer, longRespContainer}}}
+
+		validatorSvcMock := mocks.ImageValidatorService{}
+		validatorSvcMock.Mock.On("Validate", mock.Anything, validImage, mock.Anything).Return(nil)
+		validatorSvcMock.Mock.On("Validate", mock.Anything, longResp, mock.Anything).
+			After(time.Second).Return(nil)
+
+		podValidator := validate.NewPodValidator(&validatorSvcMock)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		//WHEN
+		start := time.Now()
+		result, err := podValidator.ValidatePod(ctx, pod, ns, emptyAuthData)
+
+		//THEN
+		require.NoError(t, err)
+		require.Less(t, time.Since(start), time.Second)
+		require.Equal(t, validate.ServiceUnavailable, result.Status)
+		require.Equal(t, []string{longResp}, result.InvalidImages)
+	})
 }
 
 func TestNewValidatorSvc(t *testing.T) {
 	t.Run("create new validator svc", func(t *testing.T) {
-		validatorSvc := validate.NewValidatorSvcFactory().
-			NewValidatorSvc("notaryURL", "allowed,registries", time.Second)
+		validatorSvc := validate.NewValidatorSvcFactory(nil, nil, nil).
+			NewValidatorSvc(validate.ValidatorSvcConfig{
+				NotaryURL:         "notaryURL",
+				AllowedRegistries: "allowed,registries",
+				NotaryTimeout:     time.Second,
+			})
+		result, err := validatorSvc.ValidatePod(context.Background(), &v1.Pod{}, &v1.Namespace{}, emptyAuthData)
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		require.Equal(t, validate.Valid, result.Status)
+	})
+	t.Run("create new cosign validator svc", func(t *testing.T) {
+		validatorSvc := validate.NewValidatorSvcFactory(nil, nil, nil).
+			NewValidatorSvc(validate.ValidatorSvcConfig{
+				Verifier:          pkg.VerifierCosign,
+				AllowedRegistries: "allowed,registries",
+			})
+		result, err := validatorSvc.ValidatePod(context.Background(), &v1.Pod{}, &v1.Namespace{}, emptyAuthData)
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		require.Equal(t, validate.Valid, result.Status)
+	})
+	t.Run("create new notation validator svc", func(t *testing.T) {
+		validatorSvc := validate.NewValidatorSvcFactory(nil, nil, nil).
+			NewValidatorSvc(validate.ValidatorSvcConfig{
+				Verifier:          pkg.VerifierNotation,
+				AllowedRegistries: "allowed,registries",
+			})
+		result, err := validatorSvc.ValidatePod(context.Background(), &v1.Pod{}, &v1.Namespace{}, emptyAuthData)
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		require.Equal(t, validate.Valid, result.Status)
+	})
+	t.Run("create new validator svc with verifier chain", func(t *testing.T) {
+		validatorSvc := validate.NewValidatorSvcFactory(nil, nil, nil).
+			NewValidatorSvc(validate.ValidatorSvcConfig{
+				Verifier:          "notary, cosign",
+				VerificationMode:  pkg.VerificationModeAllOf,
+				NotaryURL:         "notaryURL",
+				AllowedRegistries: "allowed,registries",
+				NotaryTimeout:     time.Second,
+			})
 		result, err := validatorSvc.ValidatePod(context.Background(), &v1.Pod{}, &v1.Namespace{}, emptyAuthData)
 		require.NoError(t, err)
 		require.NotNil(t, result)
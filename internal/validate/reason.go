@@ -0,0 +1,45 @@
+package validate
+
+import (
+	"errors"
+
+	"github.com/kyma-project/warden/pkg"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Reasons of the failed image validation, used in events and metrics
+const (
+	ReasonImageSignatureInvalid = "ImageSignatureInvalid"
+	ReasonNotaryUnavailable     = "NotaryUnavailable"
+	ReasonImageNotAllowed       = "ImageNotAllowed"
+)
+
+// FailureReason returns the reason of the failed image validation
+func FailureReason(err error) string {
+	if pkg.ErrorCode(err) == pkg.UnknownResult {
+		return ReasonNotaryUnavailable
+	}
+	if errors.Is(err, pkg.ErrImageNotAllowed) {
+		return ReasonImageNotAllowed
+	}
+	return ReasonImageSignatureInvalid
+}
+
+// FailureReasonForStatus returns the reason of the failed validation if there is no error of the image
+func FailureReasonForStatus(status ValidationStatus) string {
+	if status == ServiceUnavailable {
+		return ReasonNotaryUnavailable
+	}
+	return ReasonImageSignatureInvalid
+}
+
+// ValidationMode returns the validation mode of the namespace: system, user or warn
+func ValidationMode(ns *corev1.Namespace) string {
+	if IsUserValidationForNS(ns) {
+		return pkg.NamespaceValidationUser
+	}
+	if IsWarnValidationForNS(ns) {
+		return pkg.NamespaceValidationWarn
+	}
+	return pkg.NamespaceValidationSystem
+}
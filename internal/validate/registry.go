@@ -0,0 +1,183 @@
+package validate
+
+import (
+	"path"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+const (
+	// registryPatternNegation marks the pattern of images which are never allowed
+	registryPatternNegation = "!"
+	dockerHubRegistry       = "docker.io"
+	dockerHubLibrary        = "library/"
+)
+
+// registryPattern selects images by the registry, the repository and optionally the tag or digest,
+// every part can contain glob wildcards, e.g. `ghcr.io/org/*`, `*.gcr.io` or `registry.io/app:v1.*`
+type registryPattern struct {
+	negated    bool
+	registry   string
+	repository string
+	tag        string
+	digest     string
+	// orLibrary selects also the official docker hub image, e.g. `docker.io/nginx` is the organization or `library/nginx`
+	orLibrary bool
+}
+
+// imageReference is the normalized image name matched with registry patterns
+type imageReference struct {
+	registry   string
+	repository string
+	tag        string
+	digest     string
+}
+
+// isImageAllowed checks if the image is selected by any of the allowed registry patterns and none of the negated ones,
+// the pattern without wildcards selects its repository and all nested repositories, e.g. `docker.io/org` selects
+// `docker.io/org/app`, but not `docker.io/org-evil/app`
+func isImageAllowed(image string, allowedRegistries []string) bool {
+	if len(allowedRegistries) == 0 {
+		return false
+	}
+	ref, ok := parseImageReference(image)
+	if !ok {
+		return false
+	}
+
+	allowed := false
+	for _, allowedRegistry := range allowedRegistries {
+		pattern := parseRegistryPattern(allowedRegistry)
+		if !pattern.matches(ref) {
+			continue
+		}
+		if pattern.negated {
+			return false
+		}
+		allowed = true
+	}
+	return allowed
+}
+
+func parseImageReference(image string) (imageReference, bool) {
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return imageReference{}, false
+	}
+
+	imageRef := imageReference{
+		registry:   ref.Context().RegistryStr(),
+		repository: ref.Context().RepositoryStr(),
+	}
+	if tag, ok := getImageTag(image, ref); ok {
+		imageRef.tag = tag
+	}
+	if digest, ok := ref.(name.Digest); ok {
+		imageRef.digest = digest.DigestStr()
+	}
+	return imageRef, true
+}
+
+// parseRegistryPattern normalizes the pattern the same way as image names are normalized,
+// so `nginx`, `docker.io/library/nginx` and `index.docker.io/library/nginx` select the same images
+func parseRegistryPattern(value string) registryPattern {
+	pattern := registryPattern{}
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, registryPatternNegation) {
+		pattern.negated = true
+		value = strings.TrimSpace(strings.TrimPrefix(value, registryPatternNegation))
+	}
+
+	value, pattern.digest, _ = strings.Cut(value, "@")
+
+	registry, repository, hasRepository := strings.Cut(value, "/")
+	if !hasRepository && isRegistryHost(registry) {
+		pattern.registry = normalizeRegistry(registry)
+		return pattern
+	}
+	explicitRegistry := hasRepository && isRegistryName(registry)
+	if !explicitRegistry {
+		registry, repository = dockerHubRegistry, value
+	}
+
+	if lastSlash := strings.LastIndex(repository, "/"); strings.Contains(repository[lastSlash+1:], ":") {
+		separator := strings.LastIndex(repository, ":")
+		repository, pattern.tag = repository[:separator], repository[separator+1:]
+	}
+
+	// prefix patterns of the previous versions end with the slash, e.g. `eu.gcr.io/org/` or `myorg/`,
+	// they select nested repositories of the organization
+	organization := strings.HasSuffix(repository, "/")
+	repository = strings.TrimRight(repository, "/")
+
+	pattern.registry = normalizeRegistry(registry)
+	pattern.repository = repository
+	if pattern.registry == name.DefaultRegistry && !strings.Contains(repository, "/") && !organization {
+		// the pattern without the registry is the official image, like the image name, and with the registry
+		// it can be the organization as well
+		pattern.orLibrary = explicitRegistry
+		if !explicitRegistry {
+			pattern.repository = dockerHubLibrary + repository
+		}
+	}
+	return pattern
+}
+
+// isRegistryName uses the same rule as docker to distinguish the registry from the first path component
+func isRegistryName(value string) bool {
+	return strings.ContainsAny(value, ".:") || value == "localhost"
+}
+
+// isRegistryHost distinguishes the registry from the docker hub image with the tag, e.g. `localhost:5000` from `nginx:1.25`
+func isRegistryHost(value string) bool {
+	host, port, hasPort := strings.Cut(value, ":")
+	if hasPort && port != "" && strings.Trim(port, "0123456789") == "" {
+		return true
+	}
+	return strings.Contains(host, ".") || host == "localhost"
+}
+
+func normalizeRegistry(registry string) string {
+	if registry == dockerHubRegistry {
+		return name.DefaultRegistry
+	}
+	return registry
+}
+
+func (p registryPattern) matches(ref imageReference) bool {
+	if !globMatch(p.registry, ref.registry) {
+		return false
+	}
+	if p.repository != "" && !matchesRepository(p.repository, ref.repository) &&
+		!(p.orLibrary && matchesRepository(dockerHubLibrary+p.repository, ref.repository)) {
+		return false
+	}
+	if p.tag != "" && !globMatch(p.tag, ref.tag) {
+		return false
+	}
+	if p.digest != "" && p.digest != ref.digest {
+		return false
+	}
+	return true
+}
+
+// matchesRepository checks the repository and all its parents, so the pattern selects also nested repositories,
+// but only on the path segment boundary
+func matchesRepository(pattern, repository string) bool {
+	for {
+		if globMatch(pattern, repository) {
+			return true
+		}
+		lastSlash := strings.LastIndex(repository, "/")
+		if lastSlash < 0 {
+			return false
+		}
+		repository = repository[:lastSlash]
+	}
+}
+
+func globMatch(pattern, value string) bool {
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
@@ -0,0 +1,196 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsImageAllowed(t *testing.T) {
+	const digest = "sha256:f8ecdcd87d7d84b87e645074084dd7f57dd62c76e120bb21e5abde158755be56"
+
+	tests := []struct {
+		name              string
+		image             string
+		allowedRegistries []string
+		want              bool
+	}{
+		{
+			name:              "empty list",
+			image:             "ghcr.io/org/app:v1",
+			allowedRegistries: nil,
+			want:              false,
+		},
+		{
+			name:              "whole registry",
+			image:             "registry1.io/org/app:v1",
+			allowedRegistries: []string{"registry1.io"},
+			want:              true,
+		},
+		{
+			name:              "registry with port",
+			image:             "localhost:5000/app:v1",
+			allowedRegistries: []string{"localhost:5000"},
+			want:              true,
+		},
+		{
+			name:              "repository",
+			image:             "docker.io/myorg/app:v1",
+			allowedRegistries: []string{"docker.io/myorg"},
+			want:              true,
+		},
+		{
+			name:              "repository is matched on path boundary",
+			image:             "docker.io/myorg-evil/app:v1",
+			allowedRegistries: []string{"docker.io/myorg"},
+			want:              false,
+		},
+		{
+			name:              "repository name is not a prefix",
+			image:             "eu.gcr.io/kyma-project/function-controller-evil:v1",
+			allowedRegistries: []string{"eu.gcr.io/kyma-project/function-controller"},
+			want:              false,
+		},
+		{
+			name:              "registry is not a prefix",
+			image:             "registry1.io.evil.com/app:v1",
+			allowedRegistries: []string{"registry1.io"},
+			want:              false,
+		},
+		{
+			name:              "docker hub short name and full name",
+			image:             "nginx:1.25",
+			allowedRegistries: []string{"docker.io/library/nginx"},
+			want:              true,
+		},
+		{
+			name:              "docker hub full name and short name",
+			image:             "index.docker.io/library/nginx:1.25",
+			allowedRegistries: []string{"nginx"},
+			want:              true,
+		},
+		{
+			name:              "docker hub short name with tag",
+			image:             "nginx:1.25",
+			allowedRegistries: []string{"nginx:1.25"},
+			want:              true,
+		},
+		{
+			name:              "docker hub organization without registry",
+			image:             "docker.io/myorg/app",
+			allowedRegistries: []string{"myorg"},
+			want:              false,
+		},
+		{
+			name:              "docker hub organization with registry",
+			image:             "myorg/app",
+			allowedRegistries: []string{"docker.io/myorg"},
+			want:              true,
+		},
+		{
+			name:              "repository with trailing slash",
+			image:             "eu.gcr.io/kyma-project/function-controller:v1",
+			allowedRegistries: []string{"eu.gcr.io/kyma-project/"},
+			want:              true,
+		},
+		{
+			name:              "repository with trailing slash is matched on path boundary",
+			image:             "eu.gcr.io/kyma-project-evil/function-controller:v1",
+			allowedRegistries: []string{"eu.gcr.io/kyma-project/"},
+			want:              false,
+		},
+		{
+			name:              "registry with trailing slash",
+			image:             "eu.gcr.io/kyma-project/function-controller:v1",
+			allowedRegistries: []string{"eu.gcr.io/"},
+			want:              true,
+		},
+		{
+			name:              "docker hub organization with trailing slash",
+			image:             "docker.io/myorg/app",
+			allowedRegistries: []string{"myorg/"},
+			want:              true,
+		},
+		{
+			name:              "glob selects repositories in organization",
+			image:             "ghcr.io/org/app/nested:v1",
+			allowedRegistries: []string{"ghcr.io/org/*"},
+			want:              true,
+		},
+		{
+			name:              "glob doesn't select other organization",
+			image:             "ghcr.io/org-evil/app:v1",
+			allowedRegistries: []string{"ghcr.io/org/*"},
+			want:              false,
+		},
+		{
+			name:              "glob in registry",
+			image:             "eu.gcr.io/org/app:v1",
+			allowedRegistries: []string{"*.gcr.io/org"},
+			want:              true,
+		},
+		{
+			name:              "tag",
+			image:             "ghcr.io/org/app:v1.2",
+			allowedRegistries: []string{"ghcr.io/org/app:v1.*"},
+			want:              true,
+		},
+		{
+			name:              "other tag",
+			image:             "ghcr.io/org/app:v2.0",
+			allowedRegistries: []string{"ghcr.io/org/app:v1.*"},
+			want:              false,
+		},
+		{
+			name:              "digest",
+			image:             "ghcr.io/org/app@" + digest,
+			allowedRegistries: []string{"ghcr.io/org/app@" + digest},
+			want:              true,
+		},
+		{
+			name:              "tag and digest",
+			image:             "public.ecr.aws/dynatrace/dynatrace-operator:v1.3.2@" + digest,
+			allowedRegistries: []string{"public.ecr.aws/dynatrace/dynatrace-operator:v1.3.2"},
+			want:              true,
+		},
+		{
+			name:              "negation",
+			image:             "ghcr.io/org/untrusted:v1",
+			allowedRegistries: []string{"ghcr.io/org", "!ghcr.io/org/untrusted"},
+			want:              false,
+		},
+		{
+			name:              "negation before allowed pattern",
+			image:             "ghcr.io/org/untrusted:v1",
+			allowedRegistries: []string{"!ghcr.io/org/untrusted", "ghcr.io/org"},
+			want:              false,
+		},
+		{
+			name:              "negation of other image",
+			image:             "ghcr.io/org/app:v1",
+			allowedRegistries: []string{"ghcr.io/org", "!ghcr.io/org/untrusted"},
+			want:              true,
+		},
+		{
+			name:              "negation only",
+			image:             "ghcr.io/org/app:v1",
+			allowedRegistries: []string{"!ghcr.io/org/untrusted"},
+			want:              false,
+		},
+		{
+			name:              "invalid image",
+			image:             "ghcr.io/org/APP:v1",
+			allowedRegistries: []string{"ghcr.io"},
+			want:              false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//WHEN
+			allowed := isImageAllowed(tt.image, tt.allowedRegistries)
+
+			//THEN
+			require.Equal(t, tt.want, allowed)
+		})
+	}
+}
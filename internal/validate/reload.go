@@ -0,0 +1,46 @@
+package validate
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/kyma-project/warden/internal/helpers"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ PodValidator = &ReloadableValidator{}
+
+// ReloadableValidator is the pod validator recreated by the factory when its config is reloaded,
+// validations started before the reload are finished by the previous validator
+type ReloadableValidator struct {
+	factory ValidatorSvcFactory
+	current atomic.Pointer[configuredValidator]
+}
+
+type configuredValidator struct {
+	config    ValidatorSvcConfig
+	validator PodValidator
+}
+
+func NewReloadableValidator(factory ValidatorSvcFactory, config ValidatorSvcConfig) *ReloadableValidator {
+	validator := &ReloadableValidator{factory: factory}
+	validator.Reload(config)
+	return validator
+}
+
+// Reload replaces the validator with the new one created with the config
+func (v *ReloadableValidator) Reload(config ValidatorSvcConfig) {
+	v.current.Store(&configuredValidator{
+		config:    config,
+		validator: v.factory.NewValidatorSvc(config),
+	})
+}
+
+// Config returns the config of the current validator
+func (v *ReloadableValidator) Config() ValidatorSvcConfig {
+	return v.current.Load().config
+}
+
+func (v *ReloadableValidator) ValidatePod(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, imagePullCredentials helpers.RegistryCredentials) (ValidationResult, error) {
+	return v.current.Load().validator.ValidatePod(ctx, pod, ns, imagePullCredentials)
+}
@@ -0,0 +1,46 @@
+package validate_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReloadableValidator(t *testing.T) {
+	//GIVEN
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-ns"}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "test-ns"}}
+	config := validate.ValidatorSvcConfig{NotaryURL: "https://notary.io", AllowedRegistries: "registry.io"}
+	reloadedConfig := validate.ValidatorSvcConfig{NotaryURL: "https://reloaded-notary.io"}
+
+	validator := mocks.NewPodValidator(t)
+	validator.On("ValidatePod", mock.Anything, pod, ns, mock.Anything).
+		Return(validate.ValidationResult{Status: validate.Valid}, nil).Once()
+	reloadedValidator := mocks.NewPodValidator(t)
+	reloadedValidator.On("ValidatePod", mock.Anything, pod, ns, mock.Anything).
+		Return(validate.ValidationResult{Status: validate.Invalid}, nil).Once()
+
+	factory := mocks.NewValidatorSvcFactory(t)
+	factory.On("NewValidatorSvc", config).Return(validator).Once()
+	factory.On("NewValidatorSvc", reloadedConfig).Return(reloadedValidator).Once()
+
+	reloadable := validate.NewReloadableValidator(factory, config)
+	result, err := reloadable.ValidatePod(context.TODO(), pod, ns, nil)
+	require.NoError(t, err)
+	require.Equal(t, validate.Valid, result.Status)
+
+	//WHEN
+	reloadable.Reload(reloadedConfig)
+
+	//THEN
+	require.Equal(t, reloadedConfig, reloadable.Config())
+	result, err = reloadable.ValidatePod(context.TODO(), pod, ns, nil)
+	require.NoError(t, err)
+	require.Equal(t, validate.Invalid, result.Status)
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -23,12 +24,25 @@ import (
 )
 
 const (
-	CertFile       = "server-cert.pem"
-	KeyFile        = "server-key.pem"
-	DefaultCertDir = "/tmp/k8s-webhook-server/serving-certs"
+	CertFile = "server-cert.pem"
+	KeyFile  = "server-key.pem"
+	// CACertFile and CAKeyFile contain the CA which signs the serving certificate
+	CACertFile = "ca-cert.pem"
+	CAKeyFile  = "ca-key.pem"
+	// CABundleFile contains certificates trusted by the API server, it includes the current CA certificate
+	// and previous CA certificates which are not expired yet, because other replicas may still serve certificates signed by them
+	CABundleFile = "ca-bundle.pem"
+	// ExternalCAFile contains the CA of the externally managed kubernetes.io/tls secret, e.g. issued by cert-manager
+	ExternalCAFile = "ca.crt"
 )
 
-func SetupCertSecret(ctx context.Context, secretName, secretNamespace, serviceName, deployName string, addOwnerRef bool, logger *zap.SugaredLogger) error {
+// CABundleUpdater updates the CA bundle of webhooks, it's called with the bundle of the saved secret, so the replica
+// which lost the secret update conflict doesn't replace the bundle with its unused CA, replicas serve the certificate
+// of the secret only after they updated the CA bundle, so the API server trusts the certificate before it's served
+type CABundleUpdater func(ctx context.Context, client ctrlclient.Client, caBundle []byte) error
+
+// SetupCertSecret ensures the secret of the generated mode, config has to be validated by the caller
+func SetupCertSecret(ctx context.Context, secretName, secretNamespace, serviceName, deployName string, addOwnerRef bool, config Config, updateCABundle CABundleUpdater, logger *zap.SugaredLogger) error {
 	// We are going to talk to the API server _before_ we start the manager.
 	// Since the default manager client reads from cache, we will get an error.
 	// So, we create a "serverClient" that would read from the API directly.
@@ -41,143 +55,224 @@ func SetupCertSecret(ctx context.Context, secretName, secretNamespace, serviceNa
 		return errors.Wrap(err, "while adding apiextensions.v1 schema to k8s client")
 	}
 
-	if err := EnsureWebhookSecret(ctx, serverClient, secretName, secretNamespace, serviceName, deployName, addOwnerRef, logger); err != nil {
+	if _, err := EnsureWebhookSecret(ctx, serverClient, secretName, secretNamespace, serviceName, deployName, addOwnerRef, config, updateCABundle, logger); err != nil {
 		return errors.Wrap(err, "failed to ensure webhook secret")
 	}
 	return nil
 }
 
-func EnsureWebhookSecret(ctx context.Context, client ctrlclient.Client, secretName, secretNamespace, serviceName, deployName string, addOwnerRef bool, log *zap.SugaredLogger) error {
+// EnsureWebhookSecret creates the secret with the CA and the serving certificate signed by it,
+// the CA and the serving certificate are rotated when they reach their rotation thresholds,
+// updateCABundle is optional, it's called with the CA bundle of the secret after it's saved, the ensured secret is returned
+func EnsureWebhookSecret(ctx context.Context, client ctrlclient.Client, secretName, secretNamespace, serviceName, deployName string, addOwnerRef bool, config Config, updateCABundle CABundleUpdater, log *zap.SugaredLogger) (*corev1.Secret, error) {
 	secret := &corev1.Secret{}
 	log.Info("ensuring webhook secret")
 	err := client.Get(ctx, types.NamespacedName{Name: secretName, Namespace: secretNamespace}, secret)
 	if err != nil && !apiErrors.IsNotFound(err) {
-		return errors.Wrap(err, "failed to get webhook secret")
+		return nil, errors.Wrap(err, "failed to get webhook secret")
 	}
 
 	if apiErrors.IsNotFound(err) {
 		log.Info("creating webhook secret")
-		return createSecret(ctx, client, secretName, secretNamespace, serviceName, deployName, addOwnerRef)
+		secret, err = createSecret(ctx, client, secretName, secretNamespace, serviceName, deployName, addOwnerRef, config, log)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		log.Info("updating pre-exiting webhook secret")
+		secret, err = updateSecret(ctx, client, log, secret, serviceName, deployName, addOwnerRef, config)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to update secret")
+		}
 	}
 
-	log.Info("updating pre-exiting webhook secret")
-	if err := updateSecret(ctx, client, log, secret, serviceName, deployName, addOwnerRef); err != nil {
-		return errors.Wrap(err, "failed to update secret")
+	// the unchanged secret could have been saved by the replica which failed to update the CA bundle
+	if err := trustCABundle(ctx, client, secret, updateCABundle); err != nil {
+		return nil, err
 	}
-	return nil
+	return secret, nil
 }
 
-func createSecret(ctx context.Context, client ctrlclient.Client, name, namespace, serviceName, deployName string, addOwnerRef bool) error {
-	secret, err := buildSecret(ctx, client, name, namespace, serviceName, deployName, addOwnerRef)
+func createSecret(ctx context.Context, client ctrlclient.Client, name, namespace, serviceName, deployName string, addOwnerRef bool, config Config, log *zap.SugaredLogger) (*corev1.Secret, error) {
+	data, _, err := buildSecretData(nil, serviceName, namespace, config, log)
 	if err != nil {
-		return errors.Wrap(err, "failed to create secret object")
+		return nil, errors.Wrap(err, "failed to create secret data")
 	}
+	ownerRefs, err := buildOwnerRefs(ctx, client, namespace, deployName, addOwnerRef)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build owner reference for secret")
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			OwnerReferences: ownerRefs,
+		},
+		Data: data,
+	}
+
 	if err := client.Create(ctx, secret); err != nil {
-		return errors.Wrap(err, "failed to create secret")
+		return nil, errors.Wrap(err, "failed to create secret")
 	}
-	return nil
+	return secret, nil
 }
 
-func updateSecret(ctx context.Context, client ctrlclient.Client, log *zap.SugaredLogger, secret *corev1.Secret, serviceName, deployName string, addOwnerRef bool) error {
-	valid, err := isValidSecret(secret)
-	if valid {
-		return nil
+// updateSecret returns the saved secret or the secret itself if it doesn't have to be rotated
+func updateSecret(ctx context.Context, client ctrlclient.Client, log *zap.SugaredLogger, secret *corev1.Secret, serviceName, deployName string, addOwnerRef bool, config Config) (*corev1.Secret, error) {
+	data, changed, err := buildSecretData(secret, serviceName, secret.Namespace, config, log)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create secret data")
+	}
+	if !changed {
+		return secret, nil
 	}
+	ownerRefs, err := buildOwnerRefs(ctx, client, secret.Namespace, deployName, addOwnerRef)
 	if err != nil {
-		log.Error(err, "invalid certificate")
+		return nil, errors.Wrap(err, "failed to build owner reference for secret")
 	}
 
-	newSecret, err := buildSecret(ctx, client, secret.Name, secret.Namespace, serviceName, deployName, addOwnerRef)
-	if err != nil {
-		return errors.Wrap(err, "failed to create secret object")
+	newSecret := secret.DeepCopy()
+	newSecret.Data = data
+	newSecret.OwnerReferences = ownerRefs
+	if err := client.Update(ctx, newSecret); err != nil {
+		return nil, errors.Wrap(err, "failed to update secret")
 	}
+	return newSecret, nil
+}
 
-	secret.Data = newSecret.Data
-	secret.OwnerReferences = newSecret.OwnerReferences
-	if err := client.Update(ctx, secret); err != nil {
-		return errors.Wrap(err, "failed to update secret")
+// buildSecretData returns data of the secret with the CA and the serving certificate which are valid
+// for more than their rotation thresholds, it returns true if any of them was generated
+func buildSecretData(secret *corev1.Secret, serviceName, namespace string, config Config, log *zap.SugaredLogger) (map[string][]byte, bool, error) {
+	data := map[string][]byte{}
+	previousCABundle := []byte{}
+	if secret != nil {
+		for key, value := range secret.Data {
+			data[key] = value
+		}
+		previousCABundle = CABundle(secret)
 	}
-	return nil
+	changed := false
+
+	if err := verifyCA(data[CACertFile], data[CAKeyFile], config); err != nil {
+		log.Infof("generating webhook CA certificate: %s", err.Error())
+		caCert, caKey, err := generateCA(serviceName, namespace, config)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "failed to generate CA certificate")
+		}
+		caBundle, err := buildCABundle(caCert, previousCABundle)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "failed to build CA bundle")
+		}
+		data[CACertFile], data[CAKeyFile], data[CABundleFile] = caCert, caKey, caBundle
+		changed = true
+	}
+
+	if err := verifyCertificate(data[CertFile], data[KeyFile], data[CACertFile], config); err != nil {
+		log.Infof("generating webhook serving certificate: %s", err.Error())
+		cert, key, err := generateCertificate(data[CACertFile], data[CAKeyFile], serviceName, namespace, config)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "failed to generate serving certificate")
+		}
+		data[CertFile], data[KeyFile] = cert, key
+		changed = true
+	}
+	return data, changed, nil
 }
 
-func isValidSecret(s *corev1.Secret) (bool, error) {
-	if !hasRequiredKeys(s.Data) {
-		return false, nil
+func trustCABundle(ctx context.Context, client ctrlclient.Client, secret *corev1.Secret, updateCABundle CABundleUpdater) error {
+	if updateCABundle == nil {
+		return nil
 	}
-	if err := verifyCertificate(s.Data[CertFile]); err != nil {
-		return false, err
+	if err := updateCABundle(ctx, client, secret.Data[CABundleFile]); err != nil {
+		return errors.Wrap(err, "failed to update CA bundle of the webhook secret")
 	}
-	if err := verifyKey(s.Data[KeyFile]); err != nil {
-		return false, err
+	return nil
+}
+
+// CABundle returns certificates of the secret which should be trusted by the API server,
+// the secret created by the previous version and the external secret without the CA have only the serving certificate
+func CABundle(secret *corev1.Secret) []byte {
+	for _, key := range []string{CABundleFile, ExternalCAFile} {
+		if caBundle := secret.Data[key]; len(caBundle) > 0 {
+			return caBundle
+		}
 	}
+	cert, _ := ServingCertificate(secret)
+	return cert
+}
 
-	return true, nil
+// ServingCertificate returns the PEM encoded serving certificate and key of the generated or the kubernetes.io/tls secret
+func ServingCertificate(secret *corev1.Secret) ([]byte, []byte) {
+	if _, ok := secret.Data[CertFile]; ok {
+		return secret.Data[CertFile], secret.Data[KeyFile]
+	}
+	return secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
 }
 
-func verifyCertificate(c []byte) error {
-	certificate, err := cert.ParseCertsPEM(c)
-	if err != nil {
-		return errors.Wrap(err, "failed to parse certificate data")
+// buildCABundle returns the new CA certificate followed by certificates of the previous bundle which are not expired yet
+func buildCABundle(caCertPEM, previousCABundle []byte) ([]byte, error) {
+	caBundle := append([]byte{}, caCertPEM...)
+	if len(previousCABundle) == 0 {
+		return caBundle, nil
 	}
-	// certificate is self signed. So we use it as a root cert
-	root, err := cert.NewPoolFromBytes(c)
+
+	previousCerts, err := cert.ParseCertsPEM(previousCABundle)
 	if err != nil {
-		return errors.Wrap(err, "failed to parse root certificate data")
+		// the broken bundle can't be trusted anyway, so it's replaced by the new certificate
+		return caBundle, nil
 	}
-	// make sure the certificate is valid for the next 10 days. Otherwise it will be recreated.
-	_, err = certificate[0].Verify(x509.VerifyOptions{CurrentTime: time.Now().Add(10 * 24 * time.Hour), Roots: root})
+	newCerts, err := cert.ParseCertsPEM(caBundle)
 	if err != nil {
-		return errors.Wrap(err, "certificate verification failed")
+		return nil, errors.Wrap(err, "failed to parse certificate data")
 	}
-	return nil
+
+	now := time.Now()
+	for _, previousCert := range previousCerts {
+		if now.After(previousCert.NotAfter) || slices.ContainsFunc(newCerts, previousCert.Equal) {
+			continue
+		}
+		caBundle = append(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: previousCert.Raw})...)
+	}
+	return caBundle, nil
 }
 
-func verifyKey(k []byte) error {
-	b, _ := pem.Decode(k)
-	key, err := x509.ParsePKCS1PrivateKey(b.Bytes)
+// verifyCA checks that the CA certificate matches its key and it's valid for more than the CA rotation threshold
+func verifyCA(caCertPEM, caKeyPEM []byte, config Config) error {
+	if len(caCertPEM) == 0 || len(caKeyPEM) == 0 {
+		return errors.New("CA certificate is missing")
+	}
+	caCert, _, err := parseKeyPair(caCertPEM, caKeyPEM)
 	if err != nil {
-		return errors.Wrap(err, "failed to parse key data")
+		return errors.Wrap(err, "invalid CA certificate")
 	}
-	if err = key.Validate(); err != nil {
-		return errors.Wrap(err, "key verification failed")
+	if !caCert.IsCA {
+		return errors.New("CA certificate can't sign certificates")
+	}
+	if time.Now().Add(config.CARotationThreshold).After(caCert.NotAfter) {
+		return errors.New("CA certificate reached its rotation threshold")
 	}
 	return nil
 }
 
-func hasRequiredKeys(data map[string][]byte) bool {
-	if data == nil {
-		return false
+// verifyCertificate checks that the serving certificate matches its key, it's signed by the CA
+// and it's valid for more than the rotation threshold
+func verifyCertificate(certPEM, keyPEM, caCertPEM []byte, config Config) error {
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		return errors.New("serving certificate is missing")
 	}
-	for _, key := range []string{CertFile, KeyFile} {
-		if _, ok := data[key]; !ok {
-			return false
-		}
+	certificate, _, err := parseKeyPair(certPEM, keyPEM)
+	if err != nil {
+		return errors.Wrap(err, "invalid serving certificate")
 	}
-	return true
-}
-
-func buildSecret(ctx context.Context, client ctrlclient.Client, name, namespace, serviceName, deployName string, addOwnerRef bool) (*corev1.Secret, error) {
-	cert, key, err := generateWebhookCertificates(serviceName, namespace)
+	root, err := cert.NewPoolFromBytes(caCertPEM)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate webhook certificates")
+		return errors.Wrap(err, "failed to parse root certificate data")
 	}
-
-	ownerRefs, err := buildOwnerRefs(ctx, client, namespace, deployName, addOwnerRef)
+	_, err = certificate.Verify(x509.VerifyOptions{CurrentTime: time.Now().Add(config.RotationThreshold), Roots: root})
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to build owner reference for secret")
+		return errors.Wrap(err, "certificate verification failed")
 	}
-
-	return &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:            name,
-			Namespace:       namespace,
-			OwnerReferences: ownerRefs,
-		},
-		Data: map[string][]byte{
-			CertFile: cert,
-			KeyFile:  key,
-		},
-	}, nil
+	return nil
 }
 
 func buildOwnerRefs(ctx context.Context, client ctrlclient.Client, namespace, deployName string, addOwnerRef bool) ([]metav1.OwnerReference, error) {
@@ -210,11 +305,6 @@ func getDeploymentUID(ctx context.Context, client ctrlclient.Client, deployName,
 	return deploy.GetUID(), nil
 }
 
-func generateWebhookCertificates(serviceName, namespace string) ([]byte, []byte, error) {
-	altNames := serviceAltNames(serviceName, namespace)
-	return cert.GenerateSelfSignedCertKey(altNames[0], nil, altNames)
-}
-
 func serviceAltNames(serviceName, namespace string) []string {
 	namespacedServiceName := strings.Join([]string{serviceName, namespace}, ".")
 	commonName := strings.Join([]string{namespacedServiceName, "svc"}, ".")
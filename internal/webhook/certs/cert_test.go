@@ -1,24 +1,29 @@
 package certs
 
 import (
-	"bytes"
 	"context"
-	"crypto/rand"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
-	"crypto/x509/pkix"
 	"encoding/pem"
-	"math/big"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	certutil "k8s.io/client-go/util/cert"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 const (
@@ -27,6 +32,15 @@ const (
 	testServiceName   = "test-service"
 )
 
+var testConfig = Config{
+	Mode:                ModeGenerated,
+	KeyAlgorithm:        KeyAlgorithmECDSA,
+	CAValidity:          365 * 24 * time.Hour,
+	CARotationThreshold: 30 * 24 * time.Hour,
+	Validity:            30 * 24 * time.Hour,
+	RotationThreshold:   10 * 24 * time.Hour,
+}
+
 func Test_serviceAltNames(t *testing.T) {
 	type args struct {
 		serviceName string
@@ -59,14 +73,14 @@ func Test_serviceAltNames(t *testing.T) {
 
 func TestEnsureWebhookSecret(t *testing.T) {
 	ctx := context.Background()
-	cert, key, err := generateWebhookCertificates(testServiceName, testNamespaceName)
-	require.NoError(t, err)
+	caCert, caKey := fixCA(t, testConfig.CAValidity)
+	cert, key := fixCertificate(t, caCert, caKey, testConfig.Validity)
 	fakeLogger := zap.NewNop().Sugar()
 
 	t.Run("can ensure the secret if it doesn't exist", func(t *testing.T) {
 		client := fake.NewClientBuilder().Build()
 
-		err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, fakeLogger)
+		_, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, nil, fakeLogger)
 		require.NoError(t, err)
 
 		secret := &corev1.Secret{}
@@ -78,6 +92,9 @@ func TestEnsureWebhookSecret(t *testing.T) {
 		require.Equal(t, testNamespaceName, secret.Namespace)
 		require.Contains(t, secret.Data, KeyFile)
 		require.Contains(t, secret.Data, CertFile)
+		require.Contains(t, secret.Data, CACertFile)
+		require.Contains(t, secret.Data, CAKeyFile)
+		require.NoError(t, verifyCertificate(secret.Data[CertFile], secret.Data[KeyFile], secret.Data[CACertFile], testConfig))
 	})
 
 	t.Run("can ensure the secret is updated if it exists", func(t *testing.T) {
@@ -95,7 +112,7 @@ func TestEnsureWebhookSecret(t *testing.T) {
 			WithObjects(secret).
 			Build()
 
-		err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, fakeLogger)
+		_, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, nil, fakeLogger)
 		require.NoError(t, err)
 
 		updatedSecret := &corev1.Secret{}
@@ -120,7 +137,9 @@ func TestEnsureWebhookSecret(t *testing.T) {
 				},
 			},
 			Data: map[string][]byte{
-				KeyFile: key,
+				CACertFile: caCert,
+				CAKeyFile:  caKey,
+				KeyFile:    key,
 			},
 		}
 
@@ -128,7 +147,7 @@ func TestEnsureWebhookSecret(t *testing.T) {
 			WithObjects(secret).
 			Build()
 
-		err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, fakeLogger)
+		_, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, nil, fakeLogger)
 		require.NoError(t, err)
 
 		updatedSecret := &corev1.Secret{}
@@ -142,6 +161,8 @@ func TestEnsureWebhookSecret(t *testing.T) {
 		require.NotEqual(t, secret.ResourceVersion, updatedSecret.ResourceVersion)
 		require.Contains(t, updatedSecret.Data, KeyFile)
 		require.Contains(t, updatedSecret.Data, CertFile)
+		// the valid CA is kept
+		require.Equal(t, caCert, updatedSecret.Data[CACertFile])
 		require.Contains(t, updatedSecret.Labels, "dont-remove-me")
 	})
 
@@ -155,8 +176,11 @@ func TestEnsureWebhookSecret(t *testing.T) {
 				},
 			},
 			Data: map[string][]byte{
-				KeyFile:  key,
-				CertFile: cert,
+				CACertFile:   caCert,
+				CAKeyFile:    caKey,
+				CABundleFile: caCert,
+				KeyFile:      key,
+				CertFile:     cert,
 			},
 		}
 
@@ -164,7 +188,7 @@ func TestEnsureWebhookSecret(t *testing.T) {
 			WithObjects(secret).
 			Build()
 
-		err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, fakeLogger)
+		_, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, nil, fakeLogger)
 		require.NoError(t, err)
 
 		updatedSecret := &corev1.Secret{}
@@ -184,8 +208,7 @@ func TestEnsureWebhookSecret(t *testing.T) {
 	})
 
 	t.Run("should update if the cert will expire in 10 days", func(t *testing.T) {
-		tenDaysCert, err := generateShortLivedCertWithKey(key, testServiceName, 10*24*time.Hour)
-		require.NoError(t, err)
+		tenDaysCert, tenDaysKey := fixCertificate(t, caCert, caKey, 10*24*time.Hour)
 
 		secret := &corev1.Secret{
 			ObjectMeta: v1.ObjectMeta{
@@ -196,8 +219,10 @@ func TestEnsureWebhookSecret(t *testing.T) {
 				},
 			},
 			Data: map[string][]byte{
-				KeyFile:  key,
-				CertFile: tenDaysCert,
+				CACertFile: caCert,
+				CAKeyFile:  caKey,
+				KeyFile:    tenDaysKey,
+				CertFile:   tenDaysCert,
 			},
 		}
 
@@ -205,7 +230,7 @@ func TestEnsureWebhookSecret(t *testing.T) {
 			WithObjects(secret).
 			Build()
 
-		err = EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, fakeLogger)
+		_, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, nil, fakeLogger)
 		require.NoError(t, err)
 
 		updatedSecret := &corev1.Secret{}
@@ -219,14 +244,16 @@ func TestEnsureWebhookSecret(t *testing.T) {
 		require.Contains(t, updatedSecret.Data, CertFile)
 		// make sure it's updated, not overridden.
 		require.NotEqual(t, secret.ResourceVersion, updatedSecret.ResourceVersion)
-		require.NotEqual(t, key, updatedSecret.Data[KeyFile])
-		require.NotEqual(t, cert, updatedSecret.Data[CertFile])
+		require.NotEqual(t, tenDaysKey, updatedSecret.Data[KeyFile])
+		require.NotEqual(t, tenDaysCert, updatedSecret.Data[CertFile])
+		// only the serving certificate is rotated
+		require.Equal(t, caCert, updatedSecret.Data[CACertFile])
+		require.Equal(t, caKey, updatedSecret.Data[CAKeyFile])
 		require.Contains(t, updatedSecret.Labels, "dont-remove-me")
 	})
 
 	t.Run("should not update if the cert will expire in more than 10 days", func(t *testing.T) {
-		elevenDaysCert, err := generateShortLivedCertWithKey(key, testServiceName, 11*24*time.Hour)
-		require.NoError(t, err)
+		elevenDaysCert, elevenDaysKey := fixCertificate(t, caCert, caKey, 11*24*time.Hour)
 
 		secret := &corev1.Secret{
 			ObjectMeta: v1.ObjectMeta{
@@ -237,8 +264,10 @@ func TestEnsureWebhookSecret(t *testing.T) {
 				},
 			},
 			Data: map[string][]byte{
-				KeyFile:  key,
-				CertFile: elevenDaysCert,
+				CACertFile: caCert,
+				CAKeyFile:  caKey,
+				KeyFile:    elevenDaysKey,
+				CertFile:   elevenDaysCert,
 			},
 		}
 
@@ -246,7 +275,7 @@ func TestEnsureWebhookSecret(t *testing.T) {
 			WithObjects(secret).
 			Build()
 
-		err = EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, fakeLogger)
+		_, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, nil, fakeLogger)
 		require.NoError(t, err)
 
 		updatedSecret := &corev1.Secret{}
@@ -260,37 +289,422 @@ func TestEnsureWebhookSecret(t *testing.T) {
 		require.Contains(t, updatedSecret.Data, CertFile)
 		// make sure it's NOT updated, not overridden.
 		require.Equal(t, secret.ResourceVersion, updatedSecret.ResourceVersion)
-		require.Equal(t, key, updatedSecret.Data[KeyFile])
+		require.Equal(t, elevenDaysKey, updatedSecret.Data[KeyFile])
 		require.Equal(t, elevenDaysCert, updatedSecret.Data[CertFile])
 		require.Contains(t, updatedSecret.Labels, "dont-remove-me")
 	})
+
+	t.Run("should rotate CA and cert if the CA reached its rotation threshold", func(t *testing.T) {
+		expiringCACert, expiringCAKey := fixCA(t, 20*24*time.Hour)
+		expiringCert, expiringKey := fixCertificate(t, expiringCACert, expiringCAKey, testConfig.Validity)
+		secret := fixSecret(map[string][]byte{
+			CACertFile:   expiringCACert,
+			CAKeyFile:    expiringCAKey,
+			CABundleFile: expiringCACert,
+			KeyFile:      expiringKey,
+			CertFile:     expiringCert,
+		})
+
+		client := fake.NewClientBuilder().
+			WithObjects(secret).
+			Build()
+
+		_, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, nil, fakeLogger)
+		require.NoError(t, err)
+
+		updatedSecret := &corev1.Secret{}
+		err = client.Get(ctx, types.NamespacedName{Name: testSecretName, Namespace: testNamespaceName}, updatedSecret)
+
+		require.NoError(t, err)
+		require.NotEqual(t, expiringCACert, updatedSecret.Data[CACertFile])
+		require.NotEqual(t, expiringCert, updatedSecret.Data[CertFile])
+		require.NoError(t, verifyCA(updatedSecret.Data[CACertFile], updatedSecret.Data[CAKeyFile], testConfig))
+		require.NoError(t, verifyCertificate(updatedSecret.Data[CertFile], updatedSecret.Data[KeyFile], updatedSecret.Data[CACertFile], testConfig))
+		// replicas which still serve the previous certificate are trusted until the previous CA expires
+		require.Equal(t, append(append([]byte{}, updatedSecret.Data[CACertFile]...), expiringCACert...), updatedSecret.Data[CABundleFile])
+	})
+
+	t.Run("should replace the self-signed certificate of the previous version", func(t *testing.T) {
+		selfSignedCert, selfSignedKey, err := certutil.GenerateSelfSignedCertKey(testServiceName, nil, nil)
+		require.NoError(t, err)
+		secret := fixSecret(map[string][]byte{KeyFile: selfSignedKey, CertFile: selfSignedCert})
+
+		client := fake.NewClientBuilder().
+			WithObjects(secret).
+			Build()
+
+		_, err = EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, nil, fakeLogger)
+		require.NoError(t, err)
+
+		updatedSecret := &corev1.Secret{}
+		err = client.Get(ctx, types.NamespacedName{Name: testSecretName, Namespace: testNamespaceName}, updatedSecret)
+
+		require.NoError(t, err)
+		require.NotEqual(t, selfSignedCert, updatedSecret.Data[CertFile])
+		require.NoError(t, verifyCertificate(updatedSecret.Data[CertFile], updatedSecret.Data[KeyFile], updatedSecret.Data[CACertFile], testConfig))
+		require.Equal(t, append(append([]byte{}, updatedSecret.Data[CACertFile]...), selfSignedCert...), updatedSecret.Data[CABundleFile])
+	})
 }
 
-func generateShortLivedCertWithKey(keyBytes []byte, host string, age time.Duration) ([]byte, error) {
-	pemKey, _ := pem.Decode(keyBytes)
-	key, err := x509.ParsePKCS1PrivateKey(pemKey.Bytes)
-	if err != nil {
-		return nil, err
+func TestEnsureWebhookSecret_KeyAlgorithms(t *testing.T) {
+	ctx := context.Background()
+	fakeLogger := zap.NewNop().Sugar()
+
+	for _, algorithm := range []string{KeyAlgorithmRSA, KeyAlgorithmECDSA, KeyAlgorithmEd25519} {
+		t.Run(algorithm, func(t *testing.T) {
+			//GIVEN
+			config := testConfig
+			config.KeyAlgorithm = algorithm
+			client := fake.NewClientBuilder().Build()
+
+			//WHEN
+			_, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, config, nil, fakeLogger)
+			require.NoError(t, err)
+			secret := &corev1.Secret{}
+			require.NoError(t, client.Get(ctx, types.NamespacedName{Name: testSecretName, Namespace: testNamespaceName}, secret))
+			_, err = EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, config, nil, fakeLogger)
+			require.NoError(t, err)
+
+			//THEN
+			ensuredSecret := &corev1.Secret{}
+			require.NoError(t, client.Get(ctx, types.NamespacedName{Name: testSecretName, Namespace: testNamespaceName}, ensuredSecret))
+			require.Equal(t, secret.ResourceVersion, ensuredSecret.ResourceVersion)
+			block, _ := pem.Decode(secret.Data[KeyFile])
+			require.Equal(t, "PRIVATE KEY", block.Type)
+			_, err = tls.X509KeyPair(secret.Data[CertFile], secret.Data[KeyFile])
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestEnsureWebhookSecret_KeyFormats(t *testing.T) {
+	ctx := context.Background()
+	fakeLogger := zap.NewNop().Sugar()
+
+	tests := []struct {
+		name      string
+		algorithm string
+		encode    func(crypto.Signer) ([]byte, error)
+	}{
+		{
+			name:      "PKCS #1 RSA key",
+			algorithm: KeyAlgorithmRSA,
+			encode: func(key crypto.Signer) ([]byte, error) {
+				der := x509.MarshalPKCS1PrivateKey(key.(*rsa.PrivateKey))
+				return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der}), nil
+			},
+		},
+		{
+			name:      "SEC 1 ECDSA key",
+			algorithm: KeyAlgorithmECDSA,
+			encode: func(key crypto.Signer) ([]byte, error) {
+				der, err := x509.MarshalECPrivateKey(key.(*ecdsa.PrivateKey))
+				return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), err
+			},
+		},
 	}
-	t := x509.Certificate{
-		SerialNumber: big.NewInt(1),
-		Subject: pkix.Name{
-			CommonName: host,
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			config := testConfig
+			config.KeyAlgorithm = tt.algorithm
+			caCert, caKey, err := generateCA(testServiceName, testNamespaceName, config)
+			require.NoError(t, err)
+			cert, pkcs8Key, err := generateCertificate(caCert, caKey, testServiceName, testNamespaceName, config)
+			require.NoError(t, err)
+			_, signer, err := parseKeyPair(cert, pkcs8Key)
+			require.NoError(t, err)
+			key, err := tt.encode(signer)
+			require.NoError(t, err)
+
+			secret := fixSecret(map[string][]byte{
+				CACertFile:   caCert,
+				CAKeyFile:    caKey,
+				CABundleFile: caCert,
+				KeyFile:      key,
+				CertFile:     cert,
+			})
+			client := fake.NewClientBuilder().WithObjects(secret).Build()
+
+			//WHEN
+			_, err = EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, config, nil, fakeLogger)
+
+			//THEN
+			require.NoError(t, err)
+			updatedSecret := &corev1.Secret{}
+			require.NoError(t, client.Get(ctx, types.NamespacedName{Name: testSecretName, Namespace: testNamespaceName}, updatedSecret))
+			require.Equal(t, key, updatedSecret.Data[KeyFile])
+		})
+	}
+}
+
+func TestEnsureWebhookSecret_CABundle(t *testing.T) {
+	ctx := context.Background()
+	caCert, caKey := fixCA(t, testConfig.CAValidity)
+	fakeLogger := zap.NewNop().Sugar()
+
+	t.Run("trust the created CA after the secret is created", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().Build()
+		var trustedCABundle []byte
+		updateCABundle := func(ctx context.Context, c ctrlclient.Client, caBundle []byte) error {
+			// replicas serve the certificate only after they trusted the CA bundle of the secret
+			err := c.Get(ctx, types.NamespacedName{Name: testSecretName, Namespace: testNamespaceName}, &corev1.Secret{})
+			require.NoError(t, err)
+			trustedCABundle = caBundle
+			return nil
+		}
+
+		//WHEN
+		ensuredSecret, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, updateCABundle, fakeLogger)
+
+		//THEN
+		require.NoError(t, err)
+		secret := &corev1.Secret{}
+		require.NoError(t, client.Get(ctx, types.NamespacedName{Name: testSecretName, Namespace: testNamespaceName}, secret))
+		require.Equal(t, secret.Data, ensuredSecret.Data)
+		require.Equal(t, secret.Data[CACertFile], secret.Data[CABundleFile])
+		require.Equal(t, secret.Data[CABundleFile], trustedCABundle)
+	})
+
+	t.Run("trust the rotated and the previous CA after the secret is updated", func(t *testing.T) {
+		//GIVEN
+		expiringCACert, expiringCAKey := fixCA(t, 20*24*time.Hour)
+		expiringCert, expiringKey := fixCertificate(t, expiringCACert, expiringCAKey, testConfig.Validity)
+		secret := fixSecret(map[string][]byte{
+			CACertFile:   expiringCACert,
+			CAKeyFile:    expiringCAKey,
+			CABundleFile: expiringCACert,
+			KeyFile:      expiringKey,
+			CertFile:     expiringCert,
+		})
+		client := fake.NewClientBuilder().WithObjects(secret).Build()
+		var trustedCABundle []byte
+		updateCABundle := func(ctx context.Context, c ctrlclient.Client, caBundle []byte) error {
+			currentSecret := &corev1.Secret{}
+			require.NoError(t, c.Get(ctx, types.NamespacedName{Name: testSecretName, Namespace: testNamespaceName}, currentSecret))
+			require.NotEqual(t, expiringCert, currentSecret.Data[CertFile])
+			trustedCABundle = caBundle
+			return nil
+		}
+
+		//WHEN
+		_, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, updateCABundle, fakeLogger)
+
+		//THEN
+		require.NoError(t, err)
+		updatedSecret := &corev1.Secret{}
+		require.NoError(t, client.Get(ctx, types.NamespacedName{Name: testSecretName, Namespace: testNamespaceName}, updatedSecret))
+		require.NotEqual(t, expiringCACert, updatedSecret.Data[CACertFile])
+		require.Equal(t, append(append([]byte{}, updatedSecret.Data[CACertFile]...), expiringCACert...), updatedSecret.Data[CABundleFile])
+		require.Equal(t, updatedSecret.Data[CABundleFile], trustedCABundle)
+	})
+
+	t.Run("keep the CA bundle if only the serving certificate is rotated", func(t *testing.T) {
+		//GIVEN
+		expiringCert, expiringKey := fixCertificate(t, caCert, caKey, 24*time.Hour)
+		secret := fixSecret(map[string][]byte{
+			CACertFile:   caCert,
+			CAKeyFile:    caKey,
+			CABundleFile: caCert,
+			KeyFile:      expiringKey,
+			CertFile:     expiringCert,
+		})
+		client := fake.NewClientBuilder().WithObjects(secret).Build()
+		var trustedCABundle []byte
+		updateCABundle := func(_ context.Context, _ ctrlclient.Client, caBundle []byte) error {
+			trustedCABundle = caBundle
+			return nil
+		}
+
+		//WHEN
+		_, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, updateCABundle, fakeLogger)
+
+		//THEN
+		require.NoError(t, err)
+		updatedSecret := &corev1.Secret{}
+		require.NoError(t, client.Get(ctx, types.NamespacedName{Name: testSecretName, Namespace: testNamespaceName}, updatedSecret))
+		require.NotEqual(t, expiringCert, updatedSecret.Data[CertFile])
+		require.Equal(t, caCert, updatedSecret.Data[CABundleFile])
+		require.Equal(t, caCert, trustedCABundle)
+	})
+
+	t.Run("don't trust the CA of the replica which lost the secret update conflict", func(t *testing.T) {
+		//GIVEN
+		secret := fixSecret(map[string][]byte{})
+		client := fake.NewClientBuilder().WithObjects(secret).WithInterceptorFuncs(interceptor.Funcs{
+			Update: func(_ context.Context, _ ctrlclient.WithWatch, obj ctrlclient.Object, _ ...ctrlclient.UpdateOption) error {
+				return apiErrors.NewConflict(corev1.Resource("secrets"), obj.GetName(), errors.New("the object has been modified"))
+			},
+		}).Build()
+		updateCABundle := func(_ context.Context, _ ctrlclient.Client, _ []byte) error {
+			require.Fail(t, "CA bundle of the unsaved secret must not be trusted")
+			return nil
+		}
+
+		//WHEN
+		_, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, updateCABundle, fakeLogger)
+
+		//THEN
+		require.True(t, apiErrors.IsConflict(errors.Cause(err)))
+	})
+
+	t.Run("trust the CA bundle of the unchanged secret", func(t *testing.T) {
+		//GIVEN
+		cert, key := fixCertificate(t, caCert, caKey, testConfig.Validity)
+		secret := fixSecret(map[string][]byte{
+			CACertFile:   caCert,
+			CAKeyFile:    caKey,
+			CABundleFile: caCert,
+			KeyFile:      key,
+			CertFile:     cert,
+		})
+		client := fake.NewClientBuilder().WithObjects(secret).Build()
+		var trustedCABundle []byte
+		updateCABundle := func(_ context.Context, _ ctrlclient.Client, caBundle []byte) error {
+			trustedCABundle = caBundle
+			return nil
+		}
+
+		//WHEN
+		ensuredSecret, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, updateCABundle, fakeLogger)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, cert, ensuredSecret.Data[CertFile])
+		require.Equal(t, caCert, trustedCABundle)
+	})
+
+	t.Run("return the error of the CA bundle update", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().Build()
+		updateCABundle := func(_ context.Context, _ ctrlclient.Client, _ []byte) error {
+			return errors.New("webhook update conflict")
+		}
+
+		//WHEN
+		_, err := EnsureWebhookSecret(ctx, client, testSecretName, testNamespaceName, testServiceName, "", false, testConfig, updateCABundle, fakeLogger)
+
+		//THEN
+		require.ErrorContains(t, err, "webhook update conflict")
+	})
+
+	t.Run("CA bundle of the secret without the bundle is its certificate", func(t *testing.T) {
+		//GIVEN
+		cert, key := fixCertificate(t, caCert, caKey, testConfig.Validity)
+		secret := fixSecret(map[string][]byte{KeyFile: key, CertFile: cert})
+
+		//WHEN
+		caBundle := CABundle(secret)
+
+		//THEN
+		require.Equal(t, cert, caBundle)
+	})
+
+	t.Run("CA bundle of the external secret is its CA", func(t *testing.T) {
+		//GIVEN
+		cert, key := fixCertificate(t, caCert, caKey, testConfig.Validity)
+		secret := fixSecret(map[string][]byte{
+			corev1.TLSCertKey:       cert,
+			corev1.TLSPrivateKeyKey: key,
+			ExternalCAFile:          caCert,
+		})
+
+		//WHEN
+		caBundle := CABundle(secret)
+
+		//THEN
+		require.Equal(t, caCert, caBundle)
+	})
+
+	t.Run("CA bundle of the external secret without the CA is its certificate", func(t *testing.T) {
+		//GIVEN
+		cert, key := fixCertificate(t, caCert, caKey, testConfig.Validity)
+		secret := fixSecret(map[string][]byte{
+			corev1.TLSCertKey:       cert,
+			corev1.TLSPrivateKeyKey: key,
+			ExternalCAFile:          {},
+		})
+
+		//WHEN
+		caBundle := CABundle(secret)
+
+		//THEN
+		require.Equal(t, cert, caBundle)
+	})
+}
+
+func Test_buildCABundle(t *testing.T) {
+	caCert, _ := fixCA(t, testConfig.CAValidity)
+	previousCACert, _ := fixCA(t, time.Hour)
+	expiredCACert, _ := fixCA(t, -time.Hour)
+
+	tests := []struct {
+		name             string
+		previousCABundle []byte
+		want             []byte
+	}{
+		{
+			name: "no previous bundle",
+			want: caCert,
+		},
+		{
+			name:             "keep previous certificates",
+			previousCABundle: previousCACert,
+			want:             append(append([]byte{}, caCert...), previousCACert...),
+		},
+		{
+			name:             "drop expired certificates",
+			previousCABundle: append(append([]byte{}, expiredCACert...), previousCACert...),
+			want:             append(append([]byte{}, caCert...), previousCACert...),
+		},
+		{
+			name:             "drop duplicated certificate",
+			previousCABundle: caCert,
+			want:             caCert,
+		},
+		{
+			name:             "replace broken bundle",
+			previousCABundle: []byte("not a certificate"),
+			want:             caCert,
 		},
-		NotBefore: time.Now(),
-		NotAfter:  time.Now().Add(age),
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//WHEN
+			caBundle, err := buildCABundle(caCert, tt.previousCABundle)
 
-	certBytes, err := x509.CreateCertificate(rand.Reader, &t, &t, &key.PublicKey, key)
-	if err != nil {
-		return nil, err
+			//THEN
+			require.NoError(t, err)
+			require.Equal(t, tt.want, caBundle)
+		})
 	}
-	buf := bytes.Buffer{}
-	if err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: certBytes}); err != nil {
-		return nil, err
+}
+
+func fixSecret(data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      testSecretName,
+			Namespace: testNamespaceName,
+		},
+		Data: data,
 	}
+}
 
-	return buf.Bytes(), nil
+func fixCA(t *testing.T, validity time.Duration) ([]byte, []byte) {
+	config := testConfig
+	config.CAValidity = validity
+	caCert, caKey, err := generateCA(testServiceName, testNamespaceName, config)
+	require.NoError(t, err)
+	return caCert, caKey
+}
+
+func fixCertificate(t *testing.T, caCert, caKey []byte, validity time.Duration) ([]byte, []byte) {
+	config := testConfig
+	config.Validity = validity
+	cert, key, err := generateCertificate(caCert, caKey, testServiceName, testNamespaceName, config)
+	require.NoError(t, err)
+	return cert, key
 }
 
 func Test_buildOwnerRefs(t *testing.T) {
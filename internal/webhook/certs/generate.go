@@ -0,0 +1,199 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ModeGenerated generates and rotates the CA and the serving certificate in the secret
+	ModeGenerated = "generated"
+	// ModeExternal reads the serving certificate and the CA from the externally managed TLS secret,
+	// e.g. issued by the cert-manager Certificate, and injects the CA into webhooks
+	ModeExternal = "external"
+	// ModeCAInjector reads the serving certificate from the externally managed TLS secret
+	// and leaves the CA injection to the cert-manager cainjector
+	ModeCAInjector = "cainjector"
+
+	KeyAlgorithmRSA     = "RSA"
+	KeyAlgorithmECDSA   = "ECDSA"
+	KeyAlgorithmEd25519 = "Ed25519"
+
+	rsaKeySize = 2048
+)
+
+// Config configures certificates generated for the webhook, the long-lived CA signs short-lived serving certificates
+type Config struct {
+	// Mode is the source of certificates: generated, external or cainjector, other fields are used only by the generated mode
+	Mode string
+	// KeyAlgorithm is the algorithm of generated keys: RSA, ECDSA (P-256) or Ed25519
+	KeyAlgorithm string
+	// CAValidity is the validity period of the generated CA certificate
+	CAValidity time.Duration
+	// CARotationThreshold is the remaining validity of the CA certificate below which the CA is rotated
+	CARotationThreshold time.Duration
+	// Validity is the validity period of the generated serving certificate
+	Validity time.Duration
+	// RotationThreshold is the remaining validity of the serving certificate below which the certificate is rotated
+	RotationThreshold time.Duration
+}
+
+func (c Config) Validate() error {
+	switch c.Mode {
+	case ModeExternal, ModeCAInjector:
+		return nil
+	case ModeGenerated:
+	default:
+		return errors.Errorf("unsupported certificate mode: %s", c.Mode)
+	}
+
+	switch c.KeyAlgorithm {
+	case KeyAlgorithmRSA, KeyAlgorithmECDSA, KeyAlgorithmEd25519:
+	default:
+		return errors.Errorf("unsupported key algorithm: %s", c.KeyAlgorithm)
+	}
+	if c.CARotationThreshold <= 0 || c.CAValidity <= c.CARotationThreshold {
+		return errors.New("CA validity must be greater than the positive CA rotation threshold")
+	}
+	if c.RotationThreshold <= 0 || c.Validity <= c.RotationThreshold {
+		return errors.New("certificate validity must be greater than the positive rotation threshold")
+	}
+	// serving certificates can't outlive the CA, so the CA is rotated before they reach their rotation threshold
+	if c.CARotationThreshold < c.RotationThreshold {
+		return errors.New("CA rotation threshold must not be lower than the certificate rotation threshold")
+	}
+	return nil
+}
+
+// generateCA returns the PEM encoded self-signed CA certificate and its key
+func generateCA(serviceName, namespace string, config Config) ([]byte, []byte, error) {
+	key, err := generateKey(config.KeyAlgorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: fmt.Sprintf("%s-ca@%d", serviceAltNames(serviceName, namespace)[0], now.Unix()),
+		},
+		NotBefore:             now,
+		NotAfter:              now.Add(config.CAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	return createCertificate(template, template, key, key)
+}
+
+// generateCertificate returns the PEM encoded serving certificate of the service signed by the CA and its key,
+// the certificate expires not later than the CA
+func generateCertificate(caCertPEM, caKeyPEM []byte, serviceName, namespace string, config Config) ([]byte, []byte, error) {
+	caCert, caKey, err := parseKeyPair(caCertPEM, caKeyPEM)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse CA certificate")
+	}
+	key, err := generateKey(config.KeyAlgorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	notAfter := now.Add(config.Validity)
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	altNames := serviceAltNames(serviceName, namespace)
+	template := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: altNames[0],
+		},
+		DNSNames:    altNames,
+		NotBefore:   now,
+		NotAfter:    notAfter,
+		KeyUsage:    keyUsage,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	return createCertificate(template, caCert, key, caKey)
+}
+
+// createCertificate returns the PEM encoded certificate of the key signed by the parent key and the PEM encoded key
+func createCertificate(template, parent *x509.Certificate, key, parentKey crypto.Signer) ([]byte, []byte, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate serial number")
+	}
+	template.SerialNumber = serialNumber
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create certificate")
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM, nil
+}
+
+func generateKey(algorithm string) (crypto.Signer, error) {
+	var key crypto.Signer
+	var err error
+	switch algorithm {
+	case KeyAlgorithmRSA:
+		key, err = rsa.GenerateKey(rand.Reader, rsaKeySize)
+	case KeyAlgorithmECDSA:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyAlgorithmEd25519:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, errors.Errorf("unsupported key algorithm: %s", algorithm)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate %s key", algorithm)
+	}
+	return key, nil
+}
+
+// encodeKey encodes keys of all algorithms in the PKCS #8 form
+func encodeKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal private key")
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// parseKeyPair parses the certificate and its PKCS #1, SEC 1 or PKCS #8 key and checks that they match
+func parseKeyPair(certPEM, keyPEM []byte) (*x509.Certificate, crypto.Signer, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse key pair")
+	}
+	certificate, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse certificate data")
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.New("private key can't sign certificates")
+	}
+	return certificate, key, nil
+}
@@ -0,0 +1,124 @@
+package certs
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{
+			name:   "valid config",
+			modify: func(_ *Config) {},
+		},
+		{
+			name:    "unsupported mode",
+			modify:  func(c *Config) { c.Mode = "self-signed" },
+			wantErr: "unsupported certificate mode: self-signed",
+		},
+		{
+			name:   "external mode doesn't use generated certificate fields",
+			modify: func(c *Config) { *c = Config{Mode: ModeExternal} },
+		},
+		{
+			name:   "cainjector mode doesn't use generated certificate fields",
+			modify: func(c *Config) { *c = Config{Mode: ModeCAInjector} },
+		},
+		{
+			name:    "unsupported key algorithm",
+			modify:  func(c *Config) { c.KeyAlgorithm = "DSA" },
+			wantErr: "unsupported key algorithm: DSA",
+		},
+		{
+			name:    "CA validity lower than CA rotation threshold",
+			modify:  func(c *Config) { c.CAValidity = c.CARotationThreshold },
+			wantErr: "CA validity must be greater than the positive CA rotation threshold",
+		},
+		{
+			name:    "zero rotation threshold",
+			modify:  func(c *Config) { c.RotationThreshold = 0 },
+			wantErr: "certificate validity must be greater than the positive rotation threshold",
+		},
+		{
+			name:    "CA rotation threshold lower than rotation threshold",
+			modify:  func(c *Config) { c.CARotationThreshold = c.RotationThreshold - time.Hour },
+			wantErr: "CA rotation threshold must not be lower than the certificate rotation threshold",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			config := testConfig
+			tt.modify(&config)
+
+			//WHEN
+			err := config.Validate()
+
+			//THEN
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func Test_generateCertificate(t *testing.T) {
+	t.Run("certificate is signed by CA", func(t *testing.T) {
+		//GIVEN
+		caCertPEM, caKeyPEM := fixCA(t, testConfig.CAValidity)
+
+		//WHEN
+		certPEM, keyPEM, err := generateCertificate(caCertPEM, caKeyPEM, testServiceName, testNamespaceName, testConfig)
+
+		//THEN
+		require.NoError(t, err)
+		certificate, _, err := parseKeyPair(certPEM, keyPEM)
+		require.NoError(t, err)
+		caCert, _, err := parseKeyPair(caCertPEM, caKeyPEM)
+		require.NoError(t, err)
+		require.True(t, caCert.IsCA)
+		require.False(t, certificate.IsCA)
+		require.ElementsMatch(t, serviceAltNames(testServiceName, testNamespaceName), certificate.DNSNames)
+		roots := x509.NewCertPool()
+		roots.AddCert(caCert)
+		_, err = certificate.Verify(x509.VerifyOptions{Roots: roots, DNSName: "test-service.test-namespace.svc"})
+		require.NoError(t, err)
+	})
+
+	t.Run("certificate doesn't outlive CA", func(t *testing.T) {
+		//GIVEN
+		caCertPEM, caKeyPEM := fixCA(t, 24*time.Hour)
+
+		//WHEN
+		certPEM, keyPEM, err := generateCertificate(caCertPEM, caKeyPEM, testServiceName, testNamespaceName, testConfig)
+
+		//THEN
+		require.NoError(t, err)
+		certificate, _, err := parseKeyPair(certPEM, keyPEM)
+		require.NoError(t, err)
+		caCert, _, err := parseKeyPair(caCertPEM, caKeyPEM)
+		require.NoError(t, err)
+		require.Equal(t, caCert.NotAfter, certificate.NotAfter)
+	})
+
+	t.Run("invalid CA", func(t *testing.T) {
+		//GIVEN
+		caCertPEM, _ := fixCA(t, testConfig.CAValidity)
+		_, otherCAKeyPEM := fixCA(t, testConfig.CAValidity)
+
+		//WHEN
+		_, _, err := generateCertificate(caCertPEM, otherCAKeyPEM, testServiceName, testNamespaceName, testConfig)
+
+		//THEN
+		require.ErrorContains(t, err, "failed to parse CA certificate")
+	})
+}
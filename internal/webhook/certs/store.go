@@ -0,0 +1,86 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CertificateStore keeps the serving certificate of the webhook server in memory, the server gets the certificate
+// on every TLS handshake, so the certificate rotated in the secret is served without the restart
+type CertificateStore struct {
+	mutex       sync.RWMutex
+	certificate *tls.Certificate
+}
+
+func NewCertificateStore() *CertificateStore {
+	return &CertificateStore{}
+}
+
+// LoadCertificate loads the serving certificate from the secret before the manager is started
+func LoadCertificate(ctx context.Context, secretName, secretNamespace string, store *CertificateStore, log *zap.SugaredLogger) error {
+	// We are going to talk to the API server _before_ we start the manager.
+	// Since the default manager client reads from cache, we will get an error.
+	// So, we create a "serverClient" that would read from the API directly.
+	// We only use it here, this only runs at start up, so it shouldn't be to much for the API
+	serverClient, err := ctrlclient.New(ctrl.GetConfigOrDie(), ctrlclient.Options{})
+	if err != nil {
+		return errors.Wrap(err, "failed to create a server client")
+	}
+
+	return loadFromSecret(ctx, serverClient, secretName, secretNamespace, store, log)
+}
+
+func loadFromSecret(ctx context.Context, client ctrlclient.Client, secretName, secretNamespace string, store *CertificateStore, log *zap.SugaredLogger) error {
+	secret := &corev1.Secret{}
+	log.Info("loading serving certificate")
+	err := client.Get(ctx, types.NamespacedName{Name: secretName, Namespace: secretNamespace}, secret)
+	if err != nil {
+		return errors.Wrap(err, "failed to get webhook secret")
+	}
+
+	_, err = store.UpdateFromSecret(secret)
+	return err
+}
+
+// UpdateFromSecret replaces the serving certificate with the certificate of the secret,
+// it returns true if the certificate was changed
+func (s *CertificateStore) UpdateFromSecret(secret *corev1.Secret) (bool, error) {
+	certificate, err := tls.X509KeyPair(ServingCertificate(secret))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to parse serving certificate")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.certificate != nil && bytes.Equal(s.certificate.Certificate[0], certificate.Certificate[0]) {
+		return false, nil
+	}
+	s.certificate = &certificate
+	return true, nil
+}
+
+// GetCertificate returns the current serving certificate, it can be used as tls.Config.GetCertificate
+func (s *CertificateStore) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.certificate == nil {
+		return nil, errors.New("serving certificate is not loaded")
+	}
+	return s.certificate, nil
+}
+
+// ConfigureTLS sets the store as the source of the serving certificate, it can be used as the webhook server TLSOpts
+func (s *CertificateStore) ConfigureTLS(config *tls.Config) {
+	config.GetCertificate = s.GetCertificate
+}
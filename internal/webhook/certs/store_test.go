@@ -0,0 +1,121 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCertificateStore(t *testing.T) {
+	ctx := context.Background()
+	fakeLogger := zap.NewNop().Sugar()
+	caCert, caKey := fixCA(t, testConfig.CAValidity)
+	cert, key := fixCertificate(t, caCert, caKey, testConfig.Validity)
+
+	t.Run("load certificate from secret", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().WithObjects(fixSecret(map[string][]byte{CertFile: cert, KeyFile: key})).Build()
+		store := NewCertificateStore()
+
+		//WHEN
+		err := loadFromSecret(ctx, client, testSecretName, testNamespaceName, store, fakeLogger)
+
+		//THEN
+		require.NoError(t, err)
+		certificate, err := store.GetCertificate(nil)
+		require.NoError(t, err)
+		expected, err := tls.X509KeyPair(cert, key)
+		require.NoError(t, err)
+		require.Equal(t, expected.Certificate, certificate.Certificate)
+	})
+
+	t.Run("load certificate from external TLS secret", func(t *testing.T) {
+		//GIVEN
+		secret := fixSecret(map[string][]byte{corev1.TLSCertKey: cert, corev1.TLSPrivateKeyKey: key, ExternalCAFile: caCert})
+		secret.Type = corev1.SecretTypeTLS
+		client := fake.NewClientBuilder().WithObjects(secret).Build()
+		store := NewCertificateStore()
+
+		//WHEN
+		err := loadFromSecret(ctx, client, testSecretName, testNamespaceName, store, fakeLogger)
+
+		//THEN
+		require.NoError(t, err)
+		certificate, err := store.GetCertificate(nil)
+		require.NoError(t, err)
+		expected, err := tls.X509KeyPair(cert, key)
+		require.NoError(t, err)
+		require.Equal(t, expected.Certificate, certificate.Certificate)
+	})
+
+	t.Run("missing secret", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().Build()
+		store := NewCertificateStore()
+
+		//WHEN
+		err := loadFromSecret(ctx, client, testSecretName, testNamespaceName, store, fakeLogger)
+
+		//THEN
+		require.ErrorContains(t, err, "failed to get webhook secret")
+	})
+
+	t.Run("no certificate loaded", func(t *testing.T) {
+		//GIVEN
+		store := NewCertificateStore()
+		config := &tls.Config{}
+
+		//WHEN
+		store.ConfigureTLS(config)
+
+		//THEN
+		certificate, err := config.GetCertificate(nil)
+		require.ErrorContains(t, err, "serving certificate is not loaded")
+		require.Nil(t, certificate)
+	})
+
+	t.Run("update certificate when secret is rotated", func(t *testing.T) {
+		//GIVEN
+		store := NewCertificateStore()
+		rotatedCert, rotatedKey := fixCertificate(t, caCert, caKey, testConfig.Validity)
+		_, err := store.UpdateFromSecret(fixSecret(map[string][]byte{CertFile: cert, KeyFile: key}))
+		require.NoError(t, err)
+
+		//WHEN
+		unchanged, err := store.UpdateFromSecret(fixSecret(map[string][]byte{CertFile: cert, KeyFile: key}))
+		require.NoError(t, err)
+		rotated, err := store.UpdateFromSecret(fixSecret(map[string][]byte{CertFile: rotatedCert, KeyFile: rotatedKey}))
+		require.NoError(t, err)
+
+		//THEN
+		require.False(t, unchanged)
+		require.True(t, rotated)
+		certificate, err := store.GetCertificate(nil)
+		require.NoError(t, err)
+		expected, err := tls.X509KeyPair(rotatedCert, rotatedKey)
+		require.NoError(t, err)
+		require.Equal(t, expected.Certificate, certificate.Certificate)
+	})
+
+	t.Run("keep certificate if secret is invalid", func(t *testing.T) {
+		//GIVEN
+		store := NewCertificateStore()
+		_, err := store.UpdateFromSecret(fixSecret(map[string][]byte{CertFile: cert, KeyFile: key}))
+		require.NoError(t, err)
+
+		//WHEN
+		updated, err := store.UpdateFromSecret(fixSecret(map[string][]byte{CertFile: cert}))
+
+		//THEN
+		require.ErrorContains(t, err, "failed to parse serving certificate")
+		require.False(t, updated)
+		certificate, err := store.GetCertificate(nil)
+		require.NoError(t, err)
+		require.NotNil(t, certificate)
+	})
+}
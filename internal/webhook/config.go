@@ -1,7 +1,169 @@
 package webhook
 
+import (
+	"math"
+	"time"
+
+	"github.com/kyma-project/warden/internal/admission"
+	"github.com/pkg/errors"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const maxWebhookTimeout = 30 * time.Second
+
+// handlerResponseTime is how long the handler may respond after the admission timeout expired,
+// the timeout handler sends requests within its budget and the response needs a margin to reach the API server
+const handlerResponseTime = admission.TimeoutHandlerBudget + 500*time.Millisecond
+
 type WebhookConfig struct {
 	CABundel         []byte
 	ServiceName      string
 	ServiceNamespace string
+	// CAInjectionSecret is the <namespace>/<name> of the secret which CA is injected into webhooks by the cert-manager cainjector,
+	// CABundel is ignored and CA bundles of existing webhooks are kept if it's set
+	CAInjectionSecret string
+	Registration      RegistrationConfig
+}
+
+// RegistrationConfig configures how webhooks are registered in the API server, zero values keep the default registration
+type RegistrationConfig struct {
+	// HandlerTimeout is the timeout after which defaulting and workload validation handlers respond,
+	// zero timeouts of these webhooks are derived from it, so the API server gets the handler response
+	HandlerTimeout time.Duration
+	// FailurePolicy of all webhooks, Ignore by default
+	FailurePolicy admissionregistrationv1.FailurePolicyType
+	// NamespaceSelector is combined with the selector of namespaces with the validation label
+	NamespaceSelector *metav1.LabelSelector
+	// ObjectSelector selects validated objects by their labels
+	ObjectSelector     *metav1.LabelSelector
+	Defaulting         WebhookSettings
+	Validation         WebhookSettings
+	WorkloadValidation WebhookSettings
+}
+
+type WebhookSettings struct {
+	Timeout     time.Duration
+	MatchPolicy admissionregistrationv1.MatchPolicyType
+}
+
+func (c RegistrationConfig) Validate() error {
+	switch c.FailurePolicy {
+	case "", admissionregistrationv1.Ignore, admissionregistrationv1.Fail:
+	default:
+		return errors.Errorf("unsupported webhook failure policy: %s", c.FailurePolicy)
+	}
+	if _, err := metav1.LabelSelectorAsSelector(c.NamespaceSelector); err != nil {
+		return errors.Wrap(err, "invalid webhook namespace selector")
+	}
+	if _, err := metav1.LabelSelectorAsSelector(c.ObjectSelector); err != nil {
+		return errors.Wrap(err, "invalid webhook object selector")
+	}
+
+	if c.HandlerTimeout+handlerResponseTime >= maxWebhookTimeout {
+		return errors.Errorf("admission timeout must be lower than %s", maxWebhookTimeout-handlerResponseTime)
+	}
+	for _, webhook := range []struct {
+		name        string
+		settings    WebhookSettings
+		withHandler bool
+	}{
+		{name: "defaulting", settings: c.Defaulting, withHandler: true},
+		{name: "validation", settings: c.Validation},
+		{name: "workload validation", settings: c.WorkloadValidation, withHandler: true},
+	} {
+		switch webhook.settings.MatchPolicy {
+		case "", admissionregistrationv1.Exact, admissionregistrationv1.Equivalent:
+		default:
+			return errors.Errorf("unsupported %s webhook match policy: %s", webhook.name, webhook.settings.MatchPolicy)
+		}
+		timeout := webhook.settings.Timeout
+		if timeout < 0 || timeout > maxWebhookTimeout {
+			return errors.Errorf("%s webhook timeout must be between 1s and %s", webhook.name, maxWebhookTimeout)
+		}
+		// the API server applies the failure policy instead of the pending label if the handler doesn't respond in time
+		if webhook.withHandler && timeout != 0 && timeout <= c.HandlerTimeout+handlerResponseTime {
+			return errors.Errorf("%s webhook timeout %s must be greater than the admission timeout %s extended by %s for the timeout handler response",
+				webhook.name, timeout, c.HandlerTimeout, handlerResponseTime)
+		}
+	}
+	return nil
+}
+
+// ValidateHandlerTimeout checks if the handler timeout reloaded without re-registering webhooks, extended by the handler
+// response time, is lower than timeouts of registered defaulting and workload validation webhooks, so the API server gets the handler response
+func (c RegistrationConfig) ValidateHandlerTimeout(timeout time.Duration) error {
+	for _, webhook := range []struct {
+		name           string
+		settings       WebhookSettings
+		defaultTimeout int32
+	}{
+		{name: "defaulting", settings: c.Defaulting, defaultTimeout: MutationWebhookTimeout},
+		{name: "workload validation", settings: c.WorkloadValidation, defaultTimeout: WorkloadValidationWebhookTimeout},
+	} {
+		registeredTimeout := time.Duration(*c.handlerTimeoutSeconds(webhook.settings, webhook.defaultTimeout)) * time.Second
+		if timeout+handlerResponseTime >= registeredTimeout {
+			return errors.Errorf("admission timeout %s extended by %s for the timeout handler response must be lower than the registered %s webhook timeout %s",
+				timeout, handlerResponseTime, webhook.name, registeredTimeout)
+		}
+	}
+	return nil
+}
+
+func (c RegistrationConfig) failurePolicy() *admissionregistrationv1.FailurePolicyType {
+	failurePolicy := admissionregistrationv1.Ignore
+	if c.FailurePolicy != "" {
+		failurePolicy = c.FailurePolicy
+	}
+	return &failurePolicy
+}
+
+// handlerTimeoutSeconds returns the timeout of the webhook with the handler timeout, it's the configured timeout,
+// or the next whole second after the handler timeout extended by the handler response time, or the default timeout
+func (c RegistrationConfig) handlerTimeoutSeconds(settings WebhookSettings, defaultTimeout int32) *int32 {
+	if settings.Timeout == 0 && c.HandlerTimeout != 0 {
+		return timeoutSeconds((c.HandlerTimeout+handlerResponseTime).Truncate(time.Second)+time.Second, defaultTimeout)
+	}
+	return timeoutSeconds(settings.Timeout, defaultTimeout)
+}
+
+func timeoutSeconds(timeout time.Duration, defaultTimeout int32) *int32 {
+	if timeout == 0 {
+		return &defaultTimeout
+	}
+	seconds := int32(math.Ceil(min(timeout, maxWebhookTimeout).Seconds()))
+	return &seconds
+}
+
+func matchPolicy(settings WebhookSettings, defaultPolicy admissionregistrationv1.MatchPolicyType) *admissionregistrationv1.MatchPolicyType {
+	if settings.MatchPolicy != "" {
+		return &settings.MatchPolicy
+	}
+	return &defaultPolicy
+}
+
+// namespaceSelector returns the selector of namespaces with the validation label combined with the configured selector
+func (c RegistrationConfig) namespaceSelector() *metav1.LabelSelector {
+	selector := validatedNamespacesSelector()
+	if c.NamespaceSelector == nil {
+		return selector
+	}
+	if len(c.NamespaceSelector.MatchLabels) > 0 {
+		selector.MatchLabels = map[string]string{}
+		for key, value := range c.NamespaceSelector.MatchLabels {
+			selector.MatchLabels[key] = value
+		}
+	}
+	for _, requirement := range c.NamespaceSelector.MatchExpressions {
+		selector.MatchExpressions = append(selector.MatchExpressions, *requirement.DeepCopy())
+	}
+	return selector
+}
+
+// objectSelector returns the configured selector, the empty selector matches all objects the same way as the API server default
+func (c RegistrationConfig) objectSelector() *metav1.LabelSelector {
+	if c.ObjectSelector == nil {
+		return &metav1.LabelSelector{}
+	}
+	return c.ObjectSelector.DeepCopy()
 }
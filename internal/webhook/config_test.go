@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRegistrationConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RegistrationConfig
+		wantErr string
+	}{
+		{
+			name:   "default registration",
+			config: RegistrationConfig{},
+		},
+		{
+			name: "configured registration",
+			config: RegistrationConfig{
+				HandlerTimeout:     10 * time.Second,
+				FailurePolicy:      admissionregistrationv1.Fail,
+				NamespaceSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+				Defaulting:         WebhookSettings{Timeout: 15 * time.Second, MatchPolicy: admissionregistrationv1.Equivalent},
+				Validation:         WebhookSettings{Timeout: 2 * time.Second},
+				WorkloadValidation: WebhookSettings{Timeout: 12 * time.Second},
+			},
+		},
+		{
+			name:    "unsupported failure policy",
+			config:  RegistrationConfig{FailurePolicy: "Retry"},
+			wantErr: "unsupported webhook failure policy: Retry",
+		},
+		{
+			name: "invalid namespace selector",
+			config: RegistrationConfig{NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Like"}},
+			}},
+			wantErr: "invalid webhook namespace selector",
+		},
+		{
+			name:    "unsupported match policy",
+			config:  RegistrationConfig{Validation: WebhookSettings{MatchPolicy: "Any"}},
+			wantErr: "unsupported validation webhook match policy: Any",
+		},
+		{
+			name:    "too long timeout",
+			config:  RegistrationConfig{Validation: WebhookSettings{Timeout: time.Minute}},
+			wantErr: "validation webhook timeout must be between 1s and 30s",
+		},
+		{
+			name:    "defaulting timeout not greater than admission timeout",
+			config:  RegistrationConfig{HandlerTimeout: 10 * time.Second, Defaulting: WebhookSettings{Timeout: 10 * time.Second}},
+			wantErr: "defaulting webhook timeout 10s must be greater than the admission timeout 10s extended by 1.5s for the timeout handler response",
+		},
+		{
+			name:   "workload validation timeout greater than admission timeout extended by the handler response time",
+			config: RegistrationConfig{HandlerTimeout: 10 * time.Second, WorkloadValidation: WebhookSettings{Timeout: 11501 * time.Millisecond}},
+		},
+		{
+			name:    "workload validation timeout equal to admission timeout extended by the handler response time",
+			config:  RegistrationConfig{HandlerTimeout: 10 * time.Second, WorkloadValidation: WebhookSettings{Timeout: 11500 * time.Millisecond}},
+			wantErr: "workload validation webhook timeout 11.5s must be greater than the admission timeout 10s extended by 1.5s for the timeout handler response",
+		},
+		{
+			name:   "longest admission timeout",
+			config: RegistrationConfig{HandlerTimeout: 28499 * time.Millisecond},
+		},
+		{
+			name:    "too long admission timeout",
+			config:  RegistrationConfig{HandlerTimeout: 28500 * time.Millisecond},
+			wantErr: "admission timeout must be lower than 28.5s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//WHEN
+			err := tt.config.Validate()
+
+			//THEN
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRegistrationConfig_ValidateHandlerTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RegistrationConfig
+		timeout time.Duration
+		wantErr string
+	}{
+		{
+			name:    "timeout lower than default webhook timeouts",
+			config:  RegistrationConfig{},
+			timeout: 8 * time.Second,
+		},
+		{
+			name:    "timeout with the handler response time not lower than default webhook timeouts",
+			config:  RegistrationConfig{},
+			timeout: 8500 * time.Millisecond,
+			wantErr: "admission timeout 8.5s extended by 1.5s for the timeout handler response must be lower than the registered defaulting webhook timeout 10s",
+		},
+		{
+			name:    "timeout with the handler response time lower than webhook timeouts derived from the registered admission timeout",
+			config:  RegistrationConfig{HandlerTimeout: 2 * time.Second},
+			timeout: 2499 * time.Millisecond,
+		},
+		{
+			name:    "timeout with the handler response time not lower than derived webhook timeouts",
+			config:  RegistrationConfig{HandlerTimeout: 2 * time.Second},
+			timeout: 2500 * time.Millisecond,
+			wantErr: "admission timeout 2.5s extended by 1.5s for the timeout handler response must be lower than the registered defaulting webhook timeout 4s",
+		},
+		{
+			name: "timeout not lower than configured workload validation webhook timeout",
+			config: RegistrationConfig{
+				HandlerTimeout:     2 * time.Second,
+				Defaulting:         WebhookSettings{Timeout: 10 * time.Second},
+				WorkloadValidation: WebhookSettings{Timeout: 5 * time.Second},
+			},
+			timeout: 4 * time.Second,
+			wantErr: "admission timeout 4s extended by 1.5s for the timeout handler response must be lower than the registered workload validation webhook timeout 5s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//WHEN
+			err := tt.config.ValidateHandlerTimeout(tt.timeout)
+
+			//THEN
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -2,8 +2,6 @@ package webhook
 
 import (
 	"context"
-	"os"
-	"path"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -22,19 +20,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
-func SetupResourcesController(ctx context.Context, mgr ctrl.Manager, serviceName, serviceNamespace, secretName, deployName string, addOwnerRef bool, log *zap.SugaredLogger) error {
+// SetupResourcesController ensures webhook configurations and the webhook secret of the generated certificate mode,
+// the serving certificate of the certificate store is updated when the certificate in the secret is rotated
+func SetupResourcesController(ctx context.Context, mgr ctrl.Manager, serviceName, serviceNamespace, secretName, deployName string, addOwnerRef bool, certConfig certs.Config, registration RegistrationConfig, certificateStore *certs.CertificateStore, log *zap.SugaredLogger) error {
 	logger := log.Named("resource-ctrl")
-	certPath := path.Join(certs.DefaultCertDir, certs.CertFile)
-	certBytes, err := os.ReadFile(certPath)
-	if err != nil {
-		return errors.Wrapf(err, "failed to read caBundel file: %s", certPath)
-	}
-
-	webhookConfig := WebhookConfig{
-		CABundel:         certBytes,
-		ServiceName:      serviceName,
-		ServiceNamespace: serviceNamespace,
-	}
 	// We are going to talk to the API server _before_ we start the manager.
 	// Since the default manager client reads from cache, we will get an error.
 	// So, we create a "serverClient" that would read from the API directly.
@@ -44,6 +33,21 @@ func SetupResourcesController(ctx context.Context, mgr ctrl.Manager, serviceName
 		return errors.Wrap(err, "failed to create a server client")
 	}
 
+	webhookConfig := WebhookConfig{
+		ServiceName:      serviceName,
+		ServiceNamespace: serviceNamespace,
+		Registration:     registration,
+	}
+	if certConfig.Mode == certs.ModeCAInjector {
+		webhookConfig.CAInjectionSecret = types.NamespacedName{Name: secretName, Namespace: serviceNamespace}.String()
+	} else {
+		secret := &corev1.Secret{}
+		if err := serverClient.Get(ctx, types.NamespacedName{Name: secretName, Namespace: serviceNamespace}, secret); err != nil {
+			return errors.Wrap(err, "failed to get webhook secret")
+		}
+		webhookConfig.CABundel = certs.CABundle(secret)
+	}
+
 	logger.Info("initializing the defaulting webhook configuration")
 	if err := EnsureWebhookConfigurationFor(ctx, serverClient, webhookConfig, MutatingWebhook); err != nil {
 		return errors.Wrap(err, "failed to ensure defaulting webhook configuration")
@@ -57,12 +61,14 @@ func SetupResourcesController(ctx context.Context, mgr ctrl.Manager, serviceName
 	logger.Info("creating webhook resources controller")
 	c, err := controller.New("webhook-resources-controller", mgr, controller.Options{
 		Reconciler: &resourceReconciler{
-			webhookConfig: webhookConfig,
-			deployName:    deployName,
-			addOwnerRef:   addOwnerRef,
-			client:        mgr.GetClient(),
-			secretName:    secretName,
-			logger:        log.Named("webhook-resource-controller"),
+			webhookConfig:    webhookConfig,
+			deployName:       deployName,
+			addOwnerRef:      addOwnerRef,
+			certConfig:       certConfig,
+			client:           mgr.GetClient(),
+			secretName:       secretName,
+			certificateStore: certificateStore,
+			logger:           log.Named("webhook-resource-controller"),
 		},
 	})
 	if err != nil {
@@ -89,12 +95,14 @@ func SetupResourcesController(ctx context.Context, mgr ctrl.Manager, serviceName
 }
 
 type resourceReconciler struct {
-	webhookConfig WebhookConfig
-	secretName    string
-	deployName    string
-	addOwnerRef   bool
-	client        ctrlclient.Client
-	logger        *zap.SugaredLogger
+	webhookConfig    WebhookConfig
+	secretName       string
+	deployName       string
+	addOwnerRef      bool
+	certConfig       certs.Config
+	client           ctrlclient.Client
+	certificateStore *certs.CertificateStore
+	logger           *zap.SugaredLogger
 }
 
 func (r *resourceReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
@@ -118,15 +126,29 @@ func (r *resourceReconciler) Reconcile(ctx context.Context, request reconcile.Re
 }
 
 func (r *resourceReconciler) reconcilerWebhooks(ctx context.Context, request reconcile.Request) error {
+	if request.Name != DefaultingWebhookName && request.Name != ValidationWebhookName {
+		return nil
+	}
+
+	webhookConfig := r.webhookConfig
+	if webhookConfig.CAInjectionSecret == "" {
+		// the CA bundle is taken from the secret, because the certificate could have been rotated by any replica
+		secret := &corev1.Secret{}
+		if err := r.client.Get(ctx, types.NamespacedName{Name: r.secretName, Namespace: r.webhookConfig.ServiceNamespace}, secret); err != nil {
+			return errors.Wrap(err, "failed to get webhook secret")
+		}
+		webhookConfig.CABundel = certs.CABundle(secret)
+	}
+
 	if request.Name == DefaultingWebhookName {
 		r.logger.Info("reconciling webhook defaulting webhook configuration")
-		if err := EnsureWebhookConfigurationFor(ctx, r.client, r.webhookConfig, MutatingWebhook); err != nil {
+		if err := EnsureWebhookConfigurationFor(ctx, r.client, webhookConfig, MutatingWebhook); err != nil {
 			return errors.Wrap(err, "failed to ensure defaulting webhook configuration")
 		}
 	}
 	if request.Name == ValidationWebhookName {
 		r.logger.Info("reconciling webhook validating webhook configuration")
-		if err := EnsureWebhookConfigurationFor(ctx, r.client, r.webhookConfig, ValidatingWebHook); err != nil {
+		if err := EnsureWebhookConfigurationFor(ctx, r.client, webhookConfig, ValidatingWebHook); err != nil {
 			return errors.Wrap(err, "failed to ensure validating webhook configuration")
 		}
 	}
@@ -139,8 +161,39 @@ func (r *resourceReconciler) reconcilerSecret(ctx context.Context, request recon
 	if request.NamespacedName.String() != secretNamespaced.String() {
 		return nil
 	}
-	if err := certs.EnsureWebhookSecret(ctx, r.client, request.Name, request.Namespace, r.webhookConfig.ServiceName, deployName, addOwnerRef, r.logger); err != nil {
-		return errors.Wrap(err, "failed to reconcile webhook secret")
+	// the certificate is served only when webhooks trust it, so the rotation doesn't break TLS connections
+	// even if this replica gets the rotated secret before the replica which rotated it updated webhooks
+	secret, err := r.trustedSecret(ctx, request, deployName, addOwnerRef)
+	if err != nil {
+		return err
+	}
+	updated, err := r.certificateStore.UpdateFromSecret(secret)
+	if err != nil {
+		return errors.Wrap(err, "failed to update serving certificate")
+	}
+	if updated {
+		r.logger.Info("serving certificate updated")
 	}
 	return nil
 }
+
+// trustedSecret returns the webhook secret whose CA bundle was set in webhooks, the generated secret is the one
+// saved by EnsureWebhookSecret, so the CA bundle isn't replaced by the bundle of the previous secret from the cache
+func (r *resourceReconciler) trustedSecret(ctx context.Context, request reconcile.Request, deployName string, addOwnerRef bool) (*corev1.Secret, error) {
+	// the external secret is managed by cert-manager or by the user
+	if r.certConfig.Mode == certs.ModeGenerated {
+		secret, err := certs.EnsureWebhookSecret(ctx, r.client, request.Name, request.Namespace, r.webhookConfig.ServiceName, deployName, addOwnerRef, r.certConfig, UpdateCABundle, r.logger)
+		return secret, errors.Wrap(err, "failed to reconcile webhook secret")
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.client.Get(ctx, request.NamespacedName, secret); err != nil {
+		return nil, errors.Wrap(err, "failed to get webhook secret")
+	}
+	if r.webhookConfig.CAInjectionSecret == "" {
+		if err := UpdateCABundle(ctx, r.client, certs.CABundle(secret)); err != nil {
+			return nil, errors.Wrap(err, "failed to update webhooks CA bundle")
+		}
+	}
+	return secret, nil
+}
@@ -1,6 +1,7 @@
 package webhook
 
 import (
+	"bytes"
 	"context"
 	"reflect"
 
@@ -11,6 +12,8 @@ import (
 	"github.com/kyma-project/warden/pkg"
 	"github.com/pkg/errors"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -24,13 +27,18 @@ const (
 	MutatingWebhook   WebHookType = "Mutating"
 	ValidatingWebHook WebHookType = "Validating"
 
-	DefaultingWebhookName = "defaulting.webhook.warden.kyma-project.io"
-	ValidationWebhookName = "validation.webhook.warden.kyma-project.io"
+	DefaultingWebhookName         = "defaulting.webhook.warden.kyma-project.io"
+	ValidationWebhookName         = "validation.webhook.warden.kyma-project.io"
+	WorkloadValidationWebhookName = "workloads.validation.webhook.warden.kyma-project.io"
 
-	ValidationWebhookTimeout = 1
-	MutationWebhookTimeout   = 10
+	ValidationWebhookTimeout         = 1
+	MutationWebhookTimeout           = 10
+	WorkloadValidationWebhookTimeout = 10
 
 	PodValidationPath = "/validation/pods"
+
+	// CAInjectFromSecretAnnotation makes the cert-manager cainjector inject the CA of the secret into webhooks
+	CAInjectFromSecretAnnotation = "cert-manager.io/inject-ca-from-secret"
 )
 
 func EnsureWebhookConfigurationFor(ctx context.Context, client ctlrclient.Client, config WebhookConfig, wt WebHookType) error {
@@ -40,6 +48,48 @@ func EnsureWebhookConfigurationFor(ctx context.Context, client ctlrclient.Client
 	return ensureValidatingWebhookConfigFor(ctx, client, config)
 }
 
+// UpdateCABundle sets the CA bundle of all existing webhooks, webhook configurations which don't exist yet are skipped
+func UpdateCABundle(ctx context.Context, client ctlrclient.Client, caBundle []byte) error {
+	mwhc := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	if err := client.Get(ctx, types.NamespacedName{Name: DefaultingWebhookName}, mwhc); ctlrclient.IgnoreNotFound(err) != nil {
+		return errors.Wrapf(err, "failed to get defaulting MutatingWebhookConfiguration: %s", DefaultingWebhookName)
+	} else if err == nil {
+		updated := false
+		for i := range mwhc.Webhooks {
+			updated = setCABundle(&mwhc.Webhooks[i].ClientConfig, caBundle) || updated
+		}
+		if updated {
+			if err := client.Update(ctx, mwhc); err != nil {
+				return errors.Wrap(err, "while updating CA bundle of webhook mutation configuration")
+			}
+		}
+	}
+
+	vwhc := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := client.Get(ctx, types.NamespacedName{Name: ValidationWebhookName}, vwhc); ctlrclient.IgnoreNotFound(err) != nil {
+		return errors.Wrapf(err, "failed to get validation ValidatingWebhookConfiguration: %s", ValidationWebhookName)
+	} else if err == nil {
+		updated := false
+		for i := range vwhc.Webhooks {
+			updated = setCABundle(&vwhc.Webhooks[i].ClientConfig, caBundle) || updated
+		}
+		if updated {
+			if err := client.Update(ctx, vwhc); err != nil {
+				return errors.Wrap(err, "while updating CA bundle of webhook validation configuration")
+			}
+		}
+	}
+	return nil
+}
+
+func setCABundle(clientConfig *admissionregistrationv1.WebhookClientConfig, caBundle []byte) bool {
+	if bytes.Equal(clientConfig.CABundle, caBundle) {
+		return false
+	}
+	clientConfig.CABundle = caBundle
+	return true
+}
+
 func ensureMutatingWebhookConfigFor(ctx context.Context, client ctlrclient.Client, config WebhookConfig) error {
 	mwhc := &admissionregistrationv1.MutatingWebhookConfiguration{}
 	if err := client.Get(ctx, types.NamespacedName{Name: DefaultingWebhookName}, mwhc); err != nil {
@@ -49,9 +99,17 @@ func ensureMutatingWebhookConfigFor(ctx context.Context, client ctlrclient.Clien
 		return errors.Wrapf(err, "failed to get defaulting MutatingWebhookConfiguration: %s", DefaultingWebhookName)
 	}
 	ensuredMwhc := createMutatingWebhookConfiguration(config)
+	if config.CAInjectionSecret != "" {
+		for i := range ensuredMwhc.Webhooks {
+			ensuredMwhc.Webhooks[i].ClientConfig.CABundle = injectedCABundle(mwhc.Webhooks, ensuredMwhc.Webhooks[i].Name,
+				func(webhook admissionregistrationv1.MutatingWebhook) (string, []byte) {
+					return webhook.Name, webhook.ClientConfig.CABundle
+				})
+		}
+	}
 
-	if !reflect.DeepEqual(ensuredMwhc.Webhooks, mwhc.Webhooks) {
-		ensuredMwhc.ObjectMeta = *mwhc.ObjectMeta.DeepCopy()
+	if !reflect.DeepEqual(ensuredMwhc.Webhooks, mwhc.Webhooks) || mwhc.Annotations[CAInjectFromSecretAnnotation] != config.CAInjectionSecret {
+		ensuredMwhc.ObjectMeta = withCAInjectionAnnotation(mwhc.ObjectMeta, config)
 		return errors.Wrap(client.Update(ctx, ensuredMwhc), "while updating webhook mutation configuration")
 	}
 	return nil
@@ -66,17 +124,58 @@ func ensureValidatingWebhookConfigFor(ctx context.Context, client ctlrclient.Cli
 		return errors.Wrapf(err, "failed to get validation ValidatingWebhookConfiguration: %s", ValidationWebhookName)
 	}
 	ensuredVwhc := createValidatingWebhookConfiguration(config)
-	if !reflect.DeepEqual(ensuredVwhc.Webhooks, vwhc.Webhooks) {
-		ensuredVwhc.ObjectMeta = *vwhc.ObjectMeta.DeepCopy()
+	if config.CAInjectionSecret != "" {
+		for i := range ensuredVwhc.Webhooks {
+			ensuredVwhc.Webhooks[i].ClientConfig.CABundle = injectedCABundle(vwhc.Webhooks, ensuredVwhc.Webhooks[i].Name,
+				func(webhook admissionregistrationv1.ValidatingWebhook) (string, []byte) {
+					return webhook.Name, webhook.ClientConfig.CABundle
+				})
+		}
+	}
+
+	if !reflect.DeepEqual(ensuredVwhc.Webhooks, vwhc.Webhooks) || vwhc.Annotations[CAInjectFromSecretAnnotation] != config.CAInjectionSecret {
+		ensuredVwhc.ObjectMeta = withCAInjectionAnnotation(vwhc.ObjectMeta, config)
 		return client.Update(ctx, ensuredVwhc)
 	}
 	return nil
 }
 
+// injectedCABundle returns the CA bundle of the existing webhook, so the CA injected by the cainjector isn't overwritten
+func injectedCABundle[T any](webhooks []T, name string, caBundleOf func(T) (string, []byte)) []byte {
+	for _, webhook := range webhooks {
+		if webhookName, caBundle := caBundleOf(webhook); webhookName == name {
+			return caBundle
+		}
+	}
+	return nil
+}
+
+// withCAInjectionAnnotation sets the cainjector annotation of the CAInjectionSecret, or removes it if the CA isn't injected
+func withCAInjectionAnnotation(meta metav1.ObjectMeta, config WebhookConfig) metav1.ObjectMeta {
+	ensured := *meta.DeepCopy()
+	if config.CAInjectionSecret == "" {
+		delete(ensured.Annotations, CAInjectFromSecretAnnotation)
+		return ensured
+	}
+	if ensured.Annotations == nil {
+		ensured.Annotations = map[string]string{}
+	}
+	ensured.Annotations[CAInjectFromSecretAnnotation] = config.CAInjectionSecret
+	return ensured
+}
+
+func webhookAnnotations(config WebhookConfig) map[string]string {
+	if config.CAInjectionSecret == "" {
+		return nil
+	}
+	return map[string]string{CAInjectFromSecretAnnotation: config.CAInjectionSecret}
+}
+
 func createMutatingWebhookConfiguration(config WebhookConfig) *admissionregistrationv1.MutatingWebhookConfiguration {
 	return &admissionregistrationv1.MutatingWebhookConfiguration{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: DefaultingWebhookName,
+			Name:        DefaultingWebhookName,
+			Annotations: webhookAnnotations(config),
 		},
 		Webhooks: []admissionregistrationv1.MutatingWebhook{
 			getFunctionMutatingWebhookCfg(config),
@@ -85,8 +184,6 @@ func createMutatingWebhookConfiguration(config WebhookConfig) *admissionregistra
 }
 
 func getFunctionMutatingWebhookCfg(config WebhookConfig) admissionregistrationv1.MutatingWebhook {
-	failurePolicy := admissionregistrationv1.Ignore
-	matchPolicy := admissionregistrationv1.Exact
 	reinvocationPolicy := admissionregistrationv1.IfNeededReinvocationPolicy
 	scope := admissionregistrationv1.AllScopes
 	sideEffects := admissionregistrationv1.SideEffectClassNone
@@ -106,8 +203,8 @@ func getFunctionMutatingWebhookCfg(config WebhookConfig) admissionregistrationv1
 				Port:      ptr.To[int32](443),
 			},
 		},
-		FailurePolicy:      &failurePolicy,
-		MatchPolicy:        &matchPolicy,
+		FailurePolicy:      config.Registration.failurePolicy(),
+		MatchPolicy:        matchPolicy(config.Registration.Defaulting, admissionregistrationv1.Exact),
 		ReinvocationPolicy: &reinvocationPolicy,
 		Rules: []admissionregistrationv1.RuleWithOperations{
 			{
@@ -116,7 +213,7 @@ func getFunctionMutatingWebhookCfg(config WebhookConfig) admissionregistrationv1
 						corev1.GroupName,
 					},
 					APIVersions: []string{corev1.SchemeGroupVersion.Version},
-					Resources:   []string{string(corev1.ResourcePods)},
+					Resources:   podResources(),
 					Scope:       &scope,
 				},
 				Operations: []admissionregistrationv1.OperationType{
@@ -125,86 +222,146 @@ func getFunctionMutatingWebhookCfg(config WebhookConfig) admissionregistrationv1
 				},
 			},
 		},
-		SideEffects:    &sideEffects,
-		TimeoutSeconds: ptr.To[int32](MutationWebhookTimeout),
-		NamespaceSelector: &metav1.LabelSelector{
-			MatchExpressions: []metav1.LabelSelectorRequirement{
-				// match system and user values in pkg.NamespaceValidationLabel
-				{
-					Key:      pkg.NamespaceValidationLabel,
-					Operator: metav1.LabelSelectorOpIn,
-					Values: []string{
-						pkg.NamespaceValidationEnabled,
-						pkg.NamespaceValidationSystem,
-						pkg.NamespaceValidationUser,
-					},
-				},
-			},
-		},
+		SideEffects:       &sideEffects,
+		TimeoutSeconds:    config.Registration.handlerTimeoutSeconds(config.Registration.Defaulting, MutationWebhookTimeout),
+		NamespaceSelector: config.Registration.namespaceSelector(),
+		ObjectSelector:    config.Registration.objectSelector(),
 	}
 }
 
 func createValidatingWebhookConfiguration(config WebhookConfig) *admissionregistrationv1.ValidatingWebhookConfiguration {
-	failurePolicy := admissionregistrationv1.Ignore
-	matchPolicy := admissionregistrationv1.Exact
-	scope := admissionregistrationv1.AllScopes
-	sideEffects := admissionregistrationv1.SideEffectClassNone
-
 	return &admissionregistrationv1.ValidatingWebhookConfiguration{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: ValidationWebhookName,
+			Name:        ValidationWebhookName,
+			Annotations: webhookAnnotations(config),
 		},
 		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			getPodValidatingWebhookCfg(config),
+			getWorkloadValidatingWebhookCfg(config),
+		},
+	}
+}
+
+func getPodValidatingWebhookCfg(config WebhookConfig) admissionregistrationv1.ValidatingWebhook {
+	scope := admissionregistrationv1.AllScopes
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+
+	return admissionregistrationv1.ValidatingWebhook{
+		Name: ValidationWebhookName,
+		AdmissionReviewVersions: []string{
+			"v1beta1",
+			"v1",
+		},
+		ClientConfig: admissionregistrationv1.WebhookClientConfig{
+			CABundle: config.CABundel,
+			Service: &admissionregistrationv1.ServiceReference{
+				Namespace: config.ServiceNamespace,
+				Name:      config.ServiceName,
+				Path:      ptr.To[string](PodValidationPath),
+				Port:      ptr.To[int32](443),
+			},
+		},
+		FailurePolicy: config.Registration.failurePolicy(),
+		MatchPolicy:   matchPolicy(config.Registration.Validation, admissionregistrationv1.Exact),
+		Rules: []admissionregistrationv1.RuleWithOperations{
 			{
-				Name: ValidationWebhookName,
-				AdmissionReviewVersions: []string{
-					"v1beta1",
-					"v1",
-				},
-				ClientConfig: admissionregistrationv1.WebhookClientConfig{
-					CABundle: config.CABundel,
-					Service: &admissionregistrationv1.ServiceReference{
-						Namespace: config.ServiceNamespace,
-						Name:      config.ServiceName,
-						Path:      ptr.To[string](PodValidationPath),
-						Port:      ptr.To[int32](443),
+				Rule: admissionregistrationv1.Rule{
+					APIGroups: []string{
+						corev1.GroupName,
 					},
+					APIVersions: []string{corev1.SchemeGroupVersion.Version},
+					Resources:   podResources(),
+					Scope:       &scope,
+				},
+				Operations: []admissionregistrationv1.OperationType{
+					admissionregistrationv1.Create,
+					admissionregistrationv1.Update,
+				}},
+		},
+
+		SideEffects:       &sideEffects,
+		TimeoutSeconds:    timeoutSeconds(config.Registration.Validation.Timeout, ValidationWebhookTimeout),
+		NamespaceSelector: config.Registration.namespaceSelector(),
+		ObjectSelector:    config.Registration.objectSelector(),
+	}
+}
+
+// getWorkloadValidatingWebhookCfg validates pod templates of workloads, so invalid images are reported when the workload is applied
+func getWorkloadValidatingWebhookCfg(config WebhookConfig) admissionregistrationv1.ValidatingWebhook {
+	scope := admissionregistrationv1.NamespacedScope
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	operations := []admissionregistrationv1.OperationType{
+		admissionregistrationv1.Create,
+		admissionregistrationv1.Update,
+	}
+
+	return admissionregistrationv1.ValidatingWebhook{
+		Name: WorkloadValidationWebhookName,
+		AdmissionReviewVersions: []string{
+			"v1beta1",
+			"v1",
+		},
+		ClientConfig: admissionregistrationv1.WebhookClientConfig{
+			CABundle: config.CABundel,
+			Service: &admissionregistrationv1.ServiceReference{
+				Namespace: config.ServiceNamespace,
+				Name:      config.ServiceName,
+				Path:      ptr.To[string](admission.WorkloadValidationPath),
+				Port:      ptr.To[int32](443),
+			},
+		},
+		FailurePolicy: config.Registration.failurePolicy(),
+		MatchPolicy:   matchPolicy(config.Registration.WorkloadValidation, admissionregistrationv1.Equivalent),
+		Rules: []admissionregistrationv1.RuleWithOperations{
+			{
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{appsv1.GroupName},
+					APIVersions: []string{appsv1.SchemeGroupVersion.Version},
+					Resources:   []string{"deployments", "statefulsets", "daemonsets"},
+					Scope:       &scope,
 				},
-				FailurePolicy: &failurePolicy,
-				MatchPolicy:   &matchPolicy,
-				Rules: []admissionregistrationv1.RuleWithOperations{
-					{
-						Rule: admissionregistrationv1.Rule{
-							APIGroups: []string{
-								corev1.GroupName,
-							},
-							APIVersions: []string{corev1.SchemeGroupVersion.Version},
-							Resources:   []string{string(corev1.ResourcePods)},
-							Scope:       &scope,
-						},
-						Operations: []admissionregistrationv1.OperationType{
-							admissionregistrationv1.Create,
-							admissionregistrationv1.Update,
-						}},
+				Operations: operations,
+			},
+			{
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{batchv1.GroupName},
+					APIVersions: []string{batchv1.SchemeGroupVersion.Version},
+					Resources:   []string{"jobs", "cronjobs"},
+					Scope:       &scope,
 				},
+				Operations: operations,
+			},
+		},
+		SideEffects:       &sideEffects,
+		TimeoutSeconds:    config.Registration.handlerTimeoutSeconds(config.Registration.WorkloadValidation, WorkloadValidationWebhookTimeout),
+		NamespaceSelector: config.Registration.namespaceSelector(),
+		ObjectSelector:    config.Registration.objectSelector(),
+	}
+}
 
-				SideEffects:    &sideEffects,
-				TimeoutSeconds: ptr.To[int32](ValidationWebhookTimeout),
-				NamespaceSelector: &metav1.LabelSelector{
-					MatchExpressions: []metav1.LabelSelectorRequirement{
-						// match system and user values in pkg.NamespaceValidationLabel
-						{
-							Key:      pkg.NamespaceValidationLabel,
-							Operator: metav1.LabelSelectorOpIn,
-							Values: []string{
-								pkg.NamespaceValidationEnabled,
-								pkg.NamespaceValidationSystem,
-								pkg.NamespaceValidationUser,
-							},
-						},
-					},
+func validatedNamespacesSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			// match system and user values in pkg.NamespaceValidationLabel
+			{
+				Key:      pkg.NamespaceValidationLabel,
+				Operator: metav1.LabelSelectorOpIn,
+				Values: []string{
+					pkg.NamespaceValidationEnabled,
+					pkg.NamespaceValidationSystem,
+					pkg.NamespaceValidationUser,
+					pkg.NamespaceValidationWarn,
 				},
 			},
 		},
 	}
 }
+
+// podResources returns pods together with the ephemeral containers subresource,
+// so images added by `kubectl debug` are validated as well
+func podResources() []string {
+	return []string{
+		string(corev1.ResourcePods),
+		string(corev1.ResourcePods) + "/" + admission.EphemeralContainersSubResource,
+	}
+}
@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testCAInjectionSecret = "kyma-system/warden-admission-cert"
+
+func TestEnsureWebhookConfigurationFor(t *testing.T) {
+	ctx := context.Background()
+	caBundle := []byte("ca-bundle")
+	injectedCABundle := []byte("injected-ca-bundle")
+
+	t.Run("set CA bundle of webhooks", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().Build()
+		config := fixWebhookConfig(caBundle, "")
+
+		//WHEN
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, config, MutatingWebhook))
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, config, ValidatingWebHook))
+
+		//THEN
+		mwhc, vwhc := getWebhookConfigurations(t, client)
+		require.Equal(t, caBundle, mwhc.Webhooks[0].ClientConfig.CABundle)
+		require.NotContains(t, mwhc.Annotations, CAInjectFromSecretAnnotation)
+		for _, webhook := range vwhc.Webhooks {
+			require.Equal(t, caBundle, webhook.ClientConfig.CABundle)
+		}
+		require.NotContains(t, vwhc.Annotations, CAInjectFromSecretAnnotation)
+	})
+
+	t.Run("keep CA bundle injected by cainjector", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().Build()
+		config := fixWebhookConfig(nil, testCAInjectionSecret)
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, config, MutatingWebhook))
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, config, ValidatingWebHook))
+		require.NoError(t, UpdateCABundle(ctx, client, injectedCABundle))
+
+		//WHEN
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, config, MutatingWebhook))
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, config, ValidatingWebHook))
+
+		//THEN
+		mwhc, vwhc := getWebhookConfigurations(t, client)
+		require.Equal(t, testCAInjectionSecret, mwhc.Annotations[CAInjectFromSecretAnnotation])
+		require.Equal(t, injectedCABundle, mwhc.Webhooks[0].ClientConfig.CABundle)
+		require.Equal(t, testCAInjectionSecret, vwhc.Annotations[CAInjectFromSecretAnnotation])
+		for _, webhook := range vwhc.Webhooks {
+			require.Equal(t, injectedCABundle, webhook.ClientConfig.CABundle)
+		}
+	})
+
+	t.Run("remove cainjector annotation when CA is not injected", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().Build()
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, fixWebhookConfig(nil, testCAInjectionSecret), MutatingWebhook))
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, fixWebhookConfig(nil, testCAInjectionSecret), ValidatingWebHook))
+
+		//WHEN
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, fixWebhookConfig(caBundle, ""), MutatingWebhook))
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, fixWebhookConfig(caBundle, ""), ValidatingWebHook))
+
+		//THEN
+		mwhc, vwhc := getWebhookConfigurations(t, client)
+		require.NotContains(t, mwhc.Annotations, CAInjectFromSecretAnnotation)
+		require.Equal(t, caBundle, mwhc.Webhooks[0].ClientConfig.CABundle)
+		require.NotContains(t, vwhc.Annotations, CAInjectFromSecretAnnotation)
+		require.Equal(t, caBundle, vwhc.Webhooks[0].ClientConfig.CABundle)
+	})
+}
+
+func TestEnsureWebhookConfigurationFor_Registration(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("register webhooks with default registration", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().Build()
+		config := fixWebhookConfig(nil, "")
+
+		//WHEN
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, config, MutatingWebhook))
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, config, ValidatingWebHook))
+
+		//THEN
+		mwhc, vwhc := getWebhookConfigurations(t, client)
+		require.Equal(t, admissionregistrationv1.Ignore, *mwhc.Webhooks[0].FailurePolicy)
+		require.Equal(t, admissionregistrationv1.Exact, *mwhc.Webhooks[0].MatchPolicy)
+		require.Equal(t, int32(MutationWebhookTimeout), *mwhc.Webhooks[0].TimeoutSeconds)
+		require.Equal(t, validatedNamespacesSelector(), mwhc.Webhooks[0].NamespaceSelector)
+		require.Equal(t, int32(ValidationWebhookTimeout), *vwhc.Webhooks[0].TimeoutSeconds)
+		require.Equal(t, admissionregistrationv1.Equivalent, *vwhc.Webhooks[1].MatchPolicy)
+		require.Equal(t, int32(WorkloadValidationWebhookTimeout), *vwhc.Webhooks[1].TimeoutSeconds)
+	})
+
+	t.Run("update webhooks with configured registration", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().Build()
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, fixWebhookConfig(nil, ""), MutatingWebhook))
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, fixWebhookConfig(nil, ""), ValidatingWebHook))
+		excludeKubeSystem := metav1.LabelSelectorRequirement{
+			Key:      "kubernetes.io/metadata.name",
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   []string{"kube-system"},
+		}
+		objectSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "validated"}}
+		config := fixWebhookConfig(nil, "")
+		config.Registration = RegistrationConfig{
+			HandlerTimeout:    2500 * time.Millisecond,
+			FailurePolicy:     admissionregistrationv1.Fail,
+			NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{excludeKubeSystem}},
+			ObjectSelector:    objectSelector,
+			Validation:        WebhookSettings{Timeout: 3 * time.Second, MatchPolicy: admissionregistrationv1.Equivalent},
+		}
+
+		//WHEN
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, config, MutatingWebhook))
+		require.NoError(t, EnsureWebhookConfigurationFor(ctx, client, config, ValidatingWebHook))
+
+		//THEN
+		mwhc, vwhc := getWebhookConfigurations(t, client)
+		expectedNamespaceSelector := validatedNamespacesSelector()
+		expectedNamespaceSelector.MatchExpressions = append(expectedNamespaceSelector.MatchExpressions, excludeKubeSystem)
+
+		require.Equal(t, admissionregistrationv1.Fail, *mwhc.Webhooks[0].FailurePolicy)
+		// the defaulting timeout is derived from the admission timeout
+		require.Equal(t, int32(5), *mwhc.Webhooks[0].TimeoutSeconds)
+		require.Equal(t, expectedNamespaceSelector, mwhc.Webhooks[0].NamespaceSelector)
+		require.Equal(t, objectSelector, mwhc.Webhooks[0].ObjectSelector)
+
+		require.Equal(t, admissionregistrationv1.Fail, *vwhc.Webhooks[0].FailurePolicy)
+		require.Equal(t, admissionregistrationv1.Equivalent, *vwhc.Webhooks[0].MatchPolicy)
+		require.Equal(t, int32(3), *vwhc.Webhooks[0].TimeoutSeconds)
+		require.Equal(t, expectedNamespaceSelector, vwhc.Webhooks[0].NamespaceSelector)
+		require.Equal(t, objectSelector, vwhc.Webhooks[0].ObjectSelector)
+		require.Equal(t, admissionregistrationv1.Fail, *vwhc.Webhooks[1].FailurePolicy)
+		require.Equal(t, int32(5), *vwhc.Webhooks[1].TimeoutSeconds)
+	})
+}
+
+func fixWebhookConfig(caBundle []byte, caInjectionSecret string) WebhookConfig {
+	return WebhookConfig{
+		CABundel:          caBundle,
+		ServiceName:       "warden-admission",
+		ServiceNamespace:  "kyma-system",
+		CAInjectionSecret: caInjectionSecret,
+	}
+}
+
+func getWebhookConfigurations(t *testing.T, client ctrlclient.Client) (*admissionregistrationv1.MutatingWebhookConfiguration, *admissionregistrationv1.ValidatingWebhookConfiguration) {
+	mwhc := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	require.NoError(t, client.Get(context.Background(), types.NamespacedName{Name: DefaultingWebhookName}, mwhc))
+	vwhc := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	require.NoError(t, client.Get(context.Background(), types.NamespacedName{Name: ValidationWebhookName}, vwhc))
+	return mwhc, vwhc
+}
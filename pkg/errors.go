@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 )
 
@@ -39,6 +40,10 @@ func (e NotaryError) Error() string {
 	return builder.String()
 }
 
+func (e NotaryError) Unwrap() error {
+	return e.parent
+}
+
 func (e NotaryError) Is(err error) bool {
 	if customErr, ok := err.(NotaryError); ok {
 		return e.code == customErr.code
@@ -69,3 +74,19 @@ func NewUnknownResultErr(err error) error {
 		parent:  err,
 	}
 }
+
+// ErrImageNotAllowed is the cause of the validation error of the image which can't be verified at all,
+// e.g. because its name is invalid or no trust policy applies to it
+var ErrImageNotAllowed = errors.New("image is not allowed")
+
+func NewImageNotAllowedErr(err error) error {
+	return NewValidationFailedErr(fmt.Errorf("%w: %w", ErrImageNotAllowed, err))
+}
+
+// ErrInvalidCredentials is the cause of the validation error caused by registry credentials of the pod,
+// so the error depends on the pod, not only on the image
+var ErrInvalidCredentials = errors.New("invalid registry credentials")
+
+func NewInvalidCredentialsErr(err error) error {
+	return NewValidationFailedErr(fmt.Errorf("%w: %w", ErrInvalidCredentials, err))
+}
@@ -36,3 +36,17 @@ func TestErrorMsg(t *testing.T) {
 	})
 
 }
+
+func TestImageNotAllowedErr(t *testing.T) {
+	//GIVEN
+	err := NewImageNotAllowedErr(errors.New("image name could not be parsed"))
+
+	//WHEN
+	out := err.Error()
+
+	//THEN
+	require.Equal(t, "notary validation error: image is not allowed: image name could not be parsed", out)
+	require.Equal(t, ValidationError, ErrorCode(err))
+	require.ErrorIs(t, err, ErrImageNotAllowed)
+	require.NotErrorIs(t, NewValidationFailedErr(errors.New("image is not signed")), ErrImageNotAllowed)
+}
@@ -6,10 +6,38 @@ const (
 	NamespaceValidationEnabled           = "enabled"
 	NamespaceValidationSystem            = "system"
 	NamespaceValidationUser              = "user"
+	NamespaceValidationWarn              = "warn" // validates pods like "system", but pods with invalid images are admitted with warnings
 	NamespaceNotaryURLAnnotation         = "namespaces.warden.kyma-project.io/notary-url"
 	NamespaceAllowedRegistriesAnnotation = "namespaces.warden.kyma-project.io/allowed-registries"
 	NamespaceNotaryTimeoutAnnotation     = "namespaces.warden.kyma-project.io/notary-timeout"
 	NamespaceStrictModeAnnotation        = "namespaces.warden.kyma-project.io/strict-mode"
+	NamespaceVerifierAnnotation          = "namespaces.warden.kyma-project.io/verifier"
+	NamespaceCosignPublicKeysAnnotation  = "namespaces.warden.kyma-project.io/cosign-public-keys"
+	NamespaceNotationSecretAnnotation    = "namespaces.warden.kyma-project.io/notation-secret"
+	NamespaceVerificationModeAnnotation  = "namespaces.warden.kyma-project.io/verification-mode"
+)
+
+const (
+	// VerifierNotary verifies image signatures stored in the Notary v1 server
+	VerifierNotary = "notary"
+	// VerifierCosign verifies cosign signatures stored next to the image in the registry
+	VerifierCosign = "cosign"
+	// VerifierNotation verifies notation signatures discovered with the OCI referrers API
+	VerifierNotation = "notation"
+)
+
+const (
+	// VerificationModeAnyOf accepts the image if any of the configured verifiers accepts it
+	VerificationModeAnyOf = "anyOf"
+	// VerificationModeAllOf accepts the image only if all configured verifiers accept it
+	VerificationModeAllOf = "allOf"
+)
+
+const (
+	// EnforcementModeEnforce rejects pods with images which failed the validation
+	EnforcementModeEnforce = "enforce"
+	// EnforcementModeWarn admits pods with images which failed the validation and returns admission warnings
+	EnforcementModeWarn = "warn"
 )
 
 const (